@@ -0,0 +1,397 @@
+package macho
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/blacktop/go-macho/pkg/trie"
+	"github.com/blacktop/go-macho/types"
+)
+
+// DW_FORM_* of the Apple accelerator table atoms
+const (
+	dwFormData2    = 0x05
+	dwFormData4    = 0x06
+	dwFormData8    = 0x07
+	dwFormData1    = 0x0b
+	dwFormFlag     = 0x0c
+	dwFormSdata    = 0x0d
+	dwFormUdata    = 0x0f
+	dwFormRef1     = 0x11
+	dwFormRef2     = 0x12
+	dwFormRef4     = 0x13
+	dwFormRef8     = 0x14
+	dwFormRefUdata = 0x15
+)
+
+// An AppleAccelEntry is a hash data entry of an Apple DWARF accelerator table; a name and the DIE it names.
+type AppleAccelEntry struct {
+	Name         string
+	DIEOffset    dwarf.Offset // offset of the DIE in .debug_info (seek a dwarf.Reader to it)
+	CUOffset     dwarf.Offset // offset of the compile unit (if the table has a DW_ATOM_cu_offset atom)
+	Tag          dwarf.Tag    // tag of the DIE (if the table has a DW_ATOM_die_tag atom)
+	TypeFlags    uint64       // DW_FLAG_type_* (if the table has a DW_ATOM_type_flags atom)
+	QualNameHash uint32       // hash of the qualified name (if the table has a DW_ATOM_qual_name_hash atom)
+}
+
+func (e AppleAccelEntry) String() string {
+	if e.Tag != 0 {
+		return fmt.Sprintf("%#08x  %s (%s)", e.DIEOffset, e.Name, e.Tag)
+	}
+	return fmt.Sprintf("%#08x  %s", e.DIEOffset, e.Name)
+}
+
+// An AppleAccelTable is an Apple DWARF accelerator table; a hash table mapping names to DIEs
+// (i.e. __DWARF,__apple_names, __apple_types, __apple_namespac and __apple_objc).
+type AppleAccelTable struct {
+	Section string
+	types.AppleAccelHeader
+	DIEOffsetBase uint32
+	Atoms         []types.AppleAtom
+	Buckets       []uint32 // index of the first hash of each bucket (or APPLE_HASH_EMPTY_BUCKET)
+	Hashes        []uint32
+	Offsets       []uint32 // section offsets of the hash data of each hash
+
+	dat          []byte // section data
+	str          []byte // .debug_str data
+	bo           binary.ByteOrder
+	minEntrySize uint64 // minimum size of the atoms of a hash data entry
+}
+
+// AppleHash returns the hash of a name used by the Apple DWARF accelerator tables (DJB)
+func AppleHash(name string) uint32 {
+	h := uint32(5381)
+	for i := 0; i < len(name); i++ {
+		h = h*33 + uint32(name[i])
+	}
+	return h
+}
+
+// AppleNames returns the __apple_names accelerator table (functions and variables by name).
+func (f *File) AppleNames() (*AppleAccelTable, error) {
+	return f.appleAccelTable("names")
+}
+
+// AppleTypes returns the __apple_types accelerator table (types by name).
+func (f *File) AppleTypes() (*AppleAccelTable, error) {
+	return f.appleAccelTable("types")
+}
+
+// AppleNamespaces returns the __apple_namespac accelerator table (namespaces by name).
+func (f *File) AppleNamespaces() (*AppleAccelTable, error) {
+	return f.appleAccelTable("namespac")
+}
+
+// AppleObjC returns the __apple_objc accelerator table (ObjC methods by class name).
+func (f *File) AppleObjC() (*AppleAccelTable, error) {
+	return f.appleAccelTable("objc")
+}
+
+func (f *File) appleAccelTable(name string) (*AppleAccelTable, error) {
+	if dsym := f.DSYM(); dsym != nil {
+		return dsym.appleAccelTable(name)
+	}
+
+	var sec, strSec *Section
+	for _, s := range f.Sections {
+		if !(s.Name == "__apple_"+name || s.Name == "__debug_str" || s.Name == "__zdebug_str") {
+			continue
+		}
+		if dwarfSuffix(s) == "str" {
+			strSec = s
+		} else {
+			sec = s
+		}
+	}
+	if sec == nil {
+		return nil, fmt.Errorf("macho does not contain a __apple_%s section", name)
+	}
+	if strSec == nil {
+		return nil, fmt.Errorf("macho does not contain a __debug_str section")
+	}
+
+	dat, err := dwarfSectionData(sec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+	}
+	str, err := dwarfSectionData(strSec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s.%s data: %v", strSec.Seg, strSec.Name, err)
+	}
+
+	t, err := parseAppleAccelTable(dat, str, f.ByteOrder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s.%s: %v", sec.Seg, sec.Name, err)
+	}
+	t.Section = sec.Name
+
+	return t, nil
+}
+
+func parseAppleAccelTable(dat, str []byte, bo binary.ByteOrder) (*AppleAccelTable, error) {
+	t := &AppleAccelTable{dat: dat, str: str, bo: bo}
+
+	r := bytes.NewReader(dat)
+	if err := binary.Read(r, bo, &t.AppleAccelHeader); err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+	if t.Magic != types.APPLE_HASH_MAGIC {
+		return nil, fmt.Errorf("invalid magic %#x", t.Magic)
+	}
+	if t.Version != types.APPLE_HASH_VERSION {
+		return nil, fmt.Errorf("unsupported version %d", t.Version)
+	}
+	if t.HashFunction != types.APPLE_HASH_FUNCTION_DJB {
+		return nil, fmt.Errorf("unsupported hash function %s", t.HashFunction)
+	}
+
+	if t.HeaderDataLength < 8 || uint64(t.HeaderDataLength) > uint64(r.Len()) {
+		return nil, fmt.Errorf("header data length %d overflows the section", t.HeaderDataLength)
+	}
+	hdrDataStart, _ := r.Seek(0, io.SeekCurrent)
+	var atomCount uint32
+	if err := binary.Read(r, bo, &t.DIEOffsetBase); err != nil {
+		return nil, fmt.Errorf("failed to read header data: %v", err)
+	}
+	if err := binary.Read(r, bo, &atomCount); err != nil {
+		return nil, fmt.Errorf("failed to read header data: %v", err)
+	}
+	if uint64(atomCount)*4 > uint64(t.HeaderDataLength)-8 {
+		return nil, fmt.Errorf("atom count %d overflows the header data", atomCount)
+	}
+	t.Atoms = make([]types.AppleAtom, atomCount)
+	if err := binary.Read(r, bo, &t.Atoms); err != nil {
+		return nil, fmt.Errorf("failed to read header data atoms: %v", err)
+	}
+	for _, atom := range t.Atoms {
+		size, err := appleAtomSize(atom.Form)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 { // LEB128
+			size = 1
+		}
+		t.minEntrySize += uint64(size)
+	}
+	if t.minEntrySize == 0 {
+		t.minEntrySize = 1 // bound the entry counts of tables without atoms too
+	}
+
+	if _, err := r.Seek(hdrDataStart+int64(t.HeaderDataLength), io.SeekStart); err != nil {
+		return nil, err
+	}
+	if uint64(t.BucketCount)*4+uint64(t.HashesCount)*8 > uint64(r.Len()) {
+		return nil, fmt.Errorf("%d buckets and %d hashes overflow the section", t.BucketCount, t.HashesCount)
+	}
+	t.Buckets = make([]uint32, t.BucketCount)
+	if err := binary.Read(r, bo, &t.Buckets); err != nil {
+		return nil, fmt.Errorf("failed to read buckets: %v", err)
+	}
+	t.Hashes = make([]uint32, t.HashesCount)
+	if err := binary.Read(r, bo, &t.Hashes); err != nil {
+		return nil, fmt.Errorf("failed to read hashes: %v", err)
+	}
+	t.Offsets = make([]uint32, t.HashesCount)
+	if err := binary.Read(r, bo, &t.Offsets); err != nil {
+		return nil, fmt.Errorf("failed to read hash data offsets: %v", err)
+	}
+
+	return t, nil
+}
+
+// appleAtomSize returns the fixed size of an atom form (or 0 for LEB128 forms)
+func appleAtomSize(form uint16) (int, error) {
+	switch form {
+	case dwFormData1, dwFormRef1, dwFormFlag:
+		return 1, nil
+	case dwFormData2, dwFormRef2:
+		return 2, nil
+	case dwFormData4, dwFormRef4:
+		return 4, nil
+	case dwFormData8, dwFormRef8:
+		return 8, nil
+	case dwFormUdata, dwFormRefUdata, dwFormSdata:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unsupported atom form %#x", form)
+	}
+}
+
+// readAtom reads an atom value at off and returns it and the offset after it
+func (t *AppleAccelTable) readAtom(form uint16, off uint64) (uint64, uint64, error) {
+	size, _ := appleAtomSize(form)
+	if size == 0 {
+		if off >= uint64(len(t.dat)) {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		r := bytes.NewReader(t.dat[off:])
+		if form == dwFormSdata {
+			val, err := trie.ReadSleb128(r)
+			return uint64(val), uint64(len(t.dat)) - uint64(r.Len()), err
+		}
+		val, err := trie.ReadUleb128(r)
+		return val, uint64(len(t.dat)) - uint64(r.Len()), err
+	}
+	if off+uint64(size) > uint64(len(t.dat)) {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	switch size {
+	case 1:
+		return uint64(t.dat[off]), off + 1, nil
+	case 2:
+		return uint64(t.bo.Uint16(t.dat[off:])), off + 2, nil
+	case 4:
+		return uint64(t.bo.Uint32(t.dat[off:])), off + 4, nil
+	default:
+		return t.bo.Uint64(t.dat[off:]), off + 8, nil
+	}
+}
+
+// dieOffset returns the .debug_info offset of a DIE offset atom; DW_FORM_ref* forms are relative to the DIEOffsetBase
+func (t *AppleAccelTable) dieOffset(form uint16, val uint64) uint64 {
+	switch form {
+	case dwFormRef1, dwFormRef2, dwFormRef4, dwFormRef8, dwFormRefUdata:
+		return val + uint64(t.DIEOffsetBase)
+	}
+	return val
+}
+
+func (t *AppleAccelTable) readUint32(off uint64) (uint32, error) {
+	if off+4 > uint64(len(t.dat)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return t.bo.Uint32(t.dat[off:]), nil
+}
+
+func (t *AppleAccelTable) strAt(off uint32) string {
+	if uint64(off) >= uint64(len(t.str)) {
+		return ""
+	}
+	s := t.str[off:]
+	if i := bytes.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return string(s)
+}
+
+// hashData reads the hash data of a hash; the entries of every name with that hash
+// (or only those of the given name if it is not empty)
+func (t *AppleAccelTable) hashData(off uint32, name string) ([]AppleAccelEntry, error) {
+	var entries []AppleAccelEntry
+
+	pos := uint64(off)
+	for {
+		strOff, err := t.readUint32(pos)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read hash data at %#x: %v", pos, err)
+		}
+		if strOff == 0 { // end of the hash data
+			break
+		}
+		count, err := t.readUint32(pos + 4)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read hash data at %#x: %v", pos, err)
+		}
+		pos += 8
+		if uint64(count) > (uint64(len(t.dat))-pos)/t.minEntrySize {
+			return nil, fmt.Errorf("hash data count %d at %#x overflows the section", count, pos-4)
+		}
+
+		entryName := t.strAt(strOff)
+		match := len(name) == 0 || entryName == name
+
+		for i := uint32(0); i < count; i++ {
+			e := AppleAccelEntry{Name: entryName}
+			for _, atom := range t.Atoms {
+				var val uint64
+				if val, pos, err = t.readAtom(atom.Form, pos); err != nil {
+					return nil, fmt.Errorf("failed to read %s of %s: %v", atom.Type, entryName, err)
+				}
+				switch atom.Type {
+				case types.DW_ATOM_die_offset:
+					e.DIEOffset = dwarf.Offset(t.dieOffset(atom.Form, val))
+				case types.DW_ATOM_cu_offset:
+					e.CUOffset = dwarf.Offset(t.dieOffset(atom.Form, val))
+				case types.DW_ATOM_die_tag:
+					e.Tag = dwarf.Tag(val)
+				case types.DW_ATOM_type_flags:
+					e.TypeFlags = val
+				case types.DW_ATOM_qual_name_hash:
+					e.QualNameHash = uint32(val)
+				}
+			}
+			if match {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// Lookup returns the entries of a name (the DIEs with that name).
+func (t *AppleAccelTable) Lookup(name string) ([]AppleAccelEntry, error) {
+	if t.BucketCount == 0 || len(name) == 0 {
+		return nil, nil
+	}
+	hash := AppleHash(name)
+	bucket := hash % t.BucketCount
+	idx := t.Buckets[bucket]
+	if idx == types.APPLE_HASH_EMPTY_BUCKET {
+		return nil, nil
+	}
+
+	var entries []AppleAccelEntry
+	// the hashes of a bucket are contiguous
+	for i := idx; i < t.HashesCount && t.Hashes[i]%t.BucketCount == bucket; i++ {
+		if t.Hashes[i] != hash {
+			continue
+		}
+		es, err := t.hashData(t.Offsets[i], name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, es...)
+	}
+
+	return entries, nil
+}
+
+// LookupDIEs returns the DWARF entries of a name from the DWARF debug information the table indexes.
+func (t *AppleAccelTable) LookupDIEs(d *dwarf.Data, name string) ([]*dwarf.Entry, error) {
+	entries, err := t.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	var dies []*dwarf.Entry
+	r := d.Reader()
+	for _, e := range entries {
+		r.Seek(e.DIEOffset)
+		die, err := r.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DIE at %#x: %v", e.DIEOffset, err)
+		}
+		if die != nil {
+			dies = append(dies, die)
+		}
+	}
+	return dies, nil
+}
+
+// Entries returns all the entries of the table sorted by name.
+func (t *AppleAccelTable) Entries() ([]AppleAccelEntry, error) {
+	var entries []AppleAccelEntry
+	for _, off := range t.Offsets {
+		es, err := t.hashData(off, "")
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, es...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
@@ -0,0 +1,188 @@
+package macho
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// appleAccelTableData returns an accelerator table with a single name (foo) and the entries of its hash data
+func appleAccelTableData(t *testing.T, dieOffsetBase uint32, atoms []types.AppleAtom, count uint32, entries []byte) []byte {
+	t.Helper()
+	hdr := types.AppleAccelHeader{
+		Magic:            types.APPLE_HASH_MAGIC,
+		Version:          types.APPLE_HASH_VERSION,
+		HashFunction:     types.APPLE_HASH_FUNCTION_DJB,
+		BucketCount:      1,
+		HashesCount:      1,
+		HeaderDataLength: uint32(8 + 4*len(atoms)),
+	}
+	hashDataOff := uint32(binary.Size(hdr)) + hdr.HeaderDataLength + 3*4 // after the bucket, hash and offset
+	var buf bytes.Buffer
+	for _, v := range []interface{}{
+		hdr,
+		dieOffsetBase,
+		uint32(len(atoms)),
+		atoms,
+		[]uint32{0},                // bucket
+		[]uint32{AppleHash("foo")}, // hash
+		[]uint32{hashDataOff},
+		[]uint32{1, count}, // name and entry count
+		entries,
+		uint32(0), // end of the hash data
+	} {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+var appleAccelTestStr = []byte("\x00foo\x00")
+
+func TestAppleNames(t *testing.T) {
+	f, err := openObscured("internal/testdata/symbolicate-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	names, err := f.AppleNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := names.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AppleAccelEntry{
+		{Name: "inner", DIEOffset: 0x47}, // the inlined subroutine
+		{Name: "outer", DIEOffset: 0x32},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e != want[i] {
+			t.Errorf("entry %d: got %s, want %s", i, e, want[i])
+		}
+	}
+
+	d, err := f.DWARF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dies, err := names.LookupDIEs(d, "outer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dies) != 1 || dies[0].Tag != dwarf.TagSubprogram || dies[0].Val(dwarf.AttrName) != "outer" {
+		t.Errorf("got %v, want the outer subprogram", dies)
+	}
+	if es, err := names.Lookup("missing"); err != nil || len(es) != 0 {
+		t.Errorf("got %v (%v) for a missing name", es, err)
+	}
+
+	typeNames, err := f.AppleTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(typeNames.Atoms) != 3 || typeNames.HashesCount != 0 {
+		t.Errorf("got %d atoms and %d hashes, want 3 atoms and an empty table", len(typeNames.Atoms), typeNames.HashesCount)
+	}
+}
+
+func TestAppleAccelTableDIEOffsetBase(t *testing.T) {
+	atoms := []types.AppleAtom{
+		{Type: types.DW_ATOM_die_offset, Form: dwFormRef4},
+		{Type: types.DW_ATOM_cu_offset, Form: dwFormRef4},
+		{Type: types.DW_ATOM_die_tag, Form: dwFormData2},
+	}
+	dat := appleAccelTableData(t, 0x100, atoms, 1, []byte{0x20, 0, 0, 0, 0, 0, 0, 0, byte(dwarf.TagVariable), 0})
+	tbl, err := parseAppleAccelTable(dat, appleAccelTestStr, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := tbl.Lookup("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AppleAccelEntry{Name: "foo", DIEOffset: 0x120, CUOffset: 0x100, Tag: dwarf.TagVariable}
+	if len(entries) != 1 || entries[0] != want {
+		t.Errorf("got %v, want %s relative to the DIE offset base", entries, want)
+	}
+
+	// DW_FORM_data* offsets are absolute
+	atoms[0].Form = dwFormData4
+	dat = appleAccelTableData(t, 0x100, atoms[:1], 1, []byte{0x20, 0, 0, 0})
+	if tbl, err = parseAppleAccelTable(dat, appleAccelTestStr, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := tbl.Lookup("foo"); err != nil || len(entries) != 1 || entries[0].DIEOffset != 0x20 {
+		t.Errorf("got %v (%v), want the DIE at 0x20", entries, err)
+	}
+}
+
+func TestAppleAccelTableMalformed(t *testing.T) {
+	dieOffset := []types.AppleAtom{{Type: types.DW_ATOM_die_offset, Form: dwFormData4}}
+
+	tests := []struct {
+		name  string
+		patch func(dat []byte)
+	}{
+		{name: "magic", patch: func(dat []byte) { dat[0] = 0 }},
+		{name: "version", patch: func(dat []byte) { dat[4] = 2 }},
+		{name: "header data length", patch: func(dat []byte) { binary.LittleEndian.PutUint32(dat[16:], 0xfffffff0) }},
+		{name: "short header data length", patch: func(dat []byte) { binary.LittleEndian.PutUint32(dat[16:], 4) }},
+		{name: "atom count", patch: func(dat []byte) { binary.LittleEndian.PutUint32(dat[24:], 0x100) }},
+		{name: "atom form", patch: func(dat []byte) { binary.LittleEndian.PutUint16(dat[30:], 0x1) }},
+		{name: "bucket count", patch: func(dat []byte) { binary.LittleEndian.PutUint32(dat[8:], 0xffffffff) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dat := appleAccelTableData(t, 0, dieOffset, 1, []byte{0x20, 0, 0, 0})
+			tt.patch(dat)
+			if _, err := parseAppleAccelTable(dat, appleAccelTestStr, binary.LittleEndian); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	hashData := []struct {
+		name  string
+		atoms []types.AppleAtom
+		count uint32
+	}{
+		{name: "entry count", atoms: dieOffset, count: 0x10000000},
+		{name: "entry count without atoms", count: 0xffffffff},
+	}
+	for _, tt := range hashData {
+		t.Run(tt.name, func(t *testing.T) {
+			tbl, err := parseAppleAccelTable(appleAccelTableData(t, 0, tt.atoms, tt.count, nil), appleAccelTestStr, binary.LittleEndian)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tbl.Entries(); err == nil {
+				t.Error("expected an error")
+			}
+			if _, err := tbl.Lookup("foo"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	f, err := openPatchedTestFile(t, "internal/testdata/symbolicate-arm64-darwin.obj.base64", func(f *File, data []byte) []byte {
+		copy(data[sectionHeaderOffset(f, f.Section("__DWARF", "__debug_str")):], "__debug_stx")
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.AppleNames(); err == nil {
+		t.Error("expected an error for a missing __debug_str section")
+	}
+}
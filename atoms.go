@@ -0,0 +1,261 @@
+package macho
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// An Atom is the smallest unit of a MH_OBJECT section that the static linker can move or dead strip.
+//
+// When the object has the MH_SUBSECTIONS_VIA_SYMBOLS flag each section is split at its (non alt-entry)
+// symbols, otherwise every section is a single atom. Literal and pointer sections are always split by
+// content (i.e. one atom per C string, literal or pointer) like ld64 does.
+type Atom struct {
+	Segment      string
+	Section      string
+	Addr         uint64 // address of the atom in the object
+	Offset       uint64 // offset of the atom from the start of its section
+	Size         uint64
+	Align        uint32       // log2 alignment of the atom's section
+	AlignModulus uint64       // the atom must be placed at an address where addr % (1 << Align) == AlignModulus
+	Symbols      []Symbol     // symbols that alias the atom's address, followed by any alt entry symbols into it
+	Relocs       []Relocation // relocations whose fixup location is inside the atom
+	NoDeadStrip  bool         // the section has S_ATTR_NO_DEAD_STRIP or one of the symbols has N_NO_DEAD_STRIP
+
+	sec *Section
+}
+
+// Name returns the name of the atom's primary symbol; the first global symbol at the atom's address
+// (or the first symbol if there are no globals), or an empty string for an anonymous atom.
+func (a Atom) Name() string {
+	var name string
+	for _, sym := range a.Symbols {
+		if sym.Value != a.Addr {
+			break
+		}
+		if sym.Type.IsExternalSym() {
+			return sym.Name
+		}
+		if len(name) == 0 {
+			name = sym.Name
+		}
+	}
+	return name
+}
+
+// Data reads and returns the contents of the atom.
+func (a Atom) Data() ([]byte, error) {
+	if a.sec == nil {
+		return nil, fmt.Errorf("atom is not part of a parsed section")
+	}
+	if a.sec.Flags.IsZerofill() || a.sec.Flags.IsThreadLocalZerofill() || a.sec.Flags.IsGbZerofill() {
+		return make([]byte, a.Size), nil
+	}
+	dat := make([]byte, a.Size)
+	if _, err := a.sec.ReadAt(dat, int64(uint64(a.sec.Offset)+a.Offset)); err != nil {
+		return nil, fmt.Errorf("failed to read atom data at %#x: %v", a.Addr, err)
+	}
+	return dat, nil
+}
+
+func (a Atom) String() string {
+	name := a.Name()
+	if len(name) == 0 {
+		name = "<anon>"
+	}
+	var syms []string
+	for _, sym := range a.Symbols {
+		if sym.Name != name {
+			syms = append(syms, sym.Name)
+		}
+	}
+	var aliases string
+	if len(syms) > 0 {
+		aliases = fmt.Sprintf(" (%s)", strings.Join(syms, ", "))
+	}
+	var live string
+	if a.NoDeadStrip {
+		live = " no-dead-strip"
+	}
+	return fmt.Sprintf("%#016x-%#016x  %s.%s\talign=2^%d%%%d relocs=%d%s  %s%s",
+		a.Addr, a.Addr+a.Size, a.Segment, a.Section,
+		a.Align, a.AlignModulus, len(a.Relocs), live,
+		name, aliases)
+}
+
+// Atoms splits the sections of a MH_OBJECT into atoms.
+func (f *File) Atoms() ([]Atom, error) {
+	if f.Type != types.Obj {
+		return nil, fmt.Errorf("macho is not a relocatable object (type=%s)", f.Type)
+	}
+
+	viaSymbols := f.Flags.SubsectionsViaSymbols()
+
+	// section symbols sorted by address
+	secSyms := make([][]Symbol, len(f.Sections))
+	if f.Symtab != nil {
+		for _, sym := range f.Symtab.Syms {
+			if sym.Type.IsDebugSym() || !sym.Type.IsDefinedInSection() {
+				continue
+			}
+			if sym.Sect == 0 || int(sym.Sect) > len(f.Sections) {
+				continue
+			}
+			secSyms[sym.Sect-1] = append(secSyms[sym.Sect-1], sym)
+		}
+	}
+
+	var atoms []Atom
+
+	for i, sec := range f.Sections {
+		if sec.Align >= 64 {
+			return nil, fmt.Errorf("%s.%s alignment 2^%d is out of range", sec.Seg, sec.Name, sec.Align)
+		}
+		syms := secSyms[i]
+		sort.SliceStable(syms, func(i, j int) bool {
+			return syms[i].Value < syms[j].Value
+		})
+
+		bounds, err := f.atomBoundaries(sec, syms, viaSymbols)
+		if err != nil {
+			return nil, err
+		}
+
+		relocs, err := sec.DecodedRelocations()
+		if err != nil {
+			return nil, err
+		}
+
+		secAtoms := make([]Atom, 0, len(bounds))
+		for j, start := range bounds {
+			end := sec.Size
+			if j+1 < len(bounds) {
+				end = bounds[j+1]
+			}
+			addr := sec.Addr + start
+			secAtoms = append(secAtoms, Atom{
+				Segment:      sec.Seg,
+				Section:      sec.Name,
+				Addr:         addr,
+				Offset:       start,
+				Size:         end - start,
+				Align:        sec.Align,
+				AlignModulus: addr % (uint64(1) << sec.Align),
+				NoDeadStrip:  sec.Flags.IsNoDeadStrip(),
+				sec:          sec,
+			})
+		}
+
+		// atomFor returns the index of the atom containing the given section offset
+		atomFor := func(off uint64) int {
+			idx := sort.Search(len(secAtoms), func(i int) bool {
+				return secAtoms[i].Offset > off
+			}) - 1
+			if idx < 0 {
+				return 0
+			}
+			return idx
+		}
+
+		for _, sym := range syms {
+			if len(secAtoms) == 0 {
+				break
+			}
+			off := sym.Value - sec.Addr
+			idx := atomFor(off)
+			secAtoms[idx].Symbols = append(secAtoms[idx].Symbols, sym)
+			if sym.Desc&types.NO_DEAD_STRIP != 0 {
+				secAtoms[idx].NoDeadStrip = true
+			}
+		}
+
+		for _, rel := range relocs {
+			if len(secAtoms) == 0 {
+				break
+			}
+			idx := atomFor(uint64(rel.Offset))
+			secAtoms[idx].Relocs = append(secAtoms[idx].Relocs, rel)
+		}
+
+		atoms = append(atoms, secAtoms...)
+	}
+
+	return atoms, nil
+}
+
+// atomBoundaries returns the sorted, unique section offsets at which the section's atoms start.
+func (f *File) atomBoundaries(sec *Section, syms []Symbol, viaSymbols bool) ([]uint64, error) {
+	if sec.Size == 0 {
+		return nil, nil
+	}
+
+	var bounds []uint64
+
+	switch {
+	case sec.Flags.IsCstringLiterals():
+		dat, err := sec.Data()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+		}
+		start := uint64(0)
+		for start < uint64(len(dat)) {
+			bounds = append(bounds, start)
+			end := start
+			for end < uint64(len(dat)) && dat[end] != 0 {
+				end++
+			}
+			start = end + 1
+		}
+		return bounds, nil
+	case sec.Flags.Is4ByteLiterals():
+		return fixedSizeBoundaries(sec, 4)
+	case sec.Flags.Is8ByteLiterals():
+		return fixedSizeBoundaries(sec, 8)
+	case sec.Flags.Is16ByteLiterals():
+		return fixedSizeBoundaries(sec, 16)
+	case sec.Flags.IsLiteralPointers(), sec.Flags.IsNonLazySymbolPointers(),
+		sec.Flags.IsModInitFuncPointers(), sec.Flags.IsModTermFuncPointers():
+		return fixedSizeBoundaries(sec, f.pointerSize())
+	}
+
+	bounds = append(bounds, 0)
+	if !viaSymbols {
+		return bounds, nil
+	}
+	for _, sym := range syms {
+		if sym.Desc&types.ALT_ENTRY != 0 {
+			continue
+		}
+		if sym.Value < sec.Addr || sym.Value > sec.Addr+sec.Size {
+			return nil, fmt.Errorf("symbol %s (%#x) is outside of its section %s.%s", sym.Name, sym.Value, sec.Seg, sec.Name)
+		}
+		off := sym.Value - sec.Addr
+		if off == sec.Size || off == bounds[len(bounds)-1] {
+			continue
+		}
+		bounds = append(bounds, off)
+	}
+
+	return bounds, nil
+}
+
+// fixedSizeBoundaries splits a section of fixed size literals or pointers; the section's contents are read
+// before anything is allocated for its atoms so a bogus section size can't exhaust memory
+func fixedSizeBoundaries(sec *Section, stride uint64) ([]uint64, error) {
+	dat, err := io.ReadAll(io.NewSectionReader(sec.ReaderAt, int64(sec.Offset), int64(sec.Size)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+	}
+	if uint64(len(dat)) != sec.Size {
+		return nil, fmt.Errorf("%s.%s data is truncated (%#x of %#x bytes)", sec.Seg, sec.Name, len(dat), sec.Size)
+	}
+	var bounds []uint64
+	for off := uint64(0); off < uint64(len(dat)); off += stride {
+		bounds = append(bounds, off)
+	}
+	return bounds, nil
+}
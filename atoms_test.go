@@ -0,0 +1,119 @@
+package macho
+
+import (
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+func TestAtoms(t *testing.T) {
+	f, err := openObscured("internal/testdata/atoms-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	atoms, err := f.Atoms()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		section     string
+		addr, size  uint64
+		name        string
+		symbols     int
+		relocs      int
+		noDeadStrip bool
+		data        string
+	}{
+		{section: "__text", addr: 0x0, size: 0x14, name: "_a", symbols: 3, relocs: 3}, // ltmp0 and the alt entry _a2
+		{section: "__text", addr: 0x14, size: 0x8, name: "_b", symbols: 2, relocs: 1, noDeadStrip: true},
+		{section: "__cstring", addr: 0x1c, size: 6, name: "Lstr", symbols: 2, data: "hello\x00"},
+		{section: "__cstring", addr: 0x22, size: 6, data: "world\x00"},
+		{section: "__literal8", addr: 0x28, size: 8, name: "ltmp2", symbols: 1, data: "\x01\x00\x00\x00\x00\x00\x00\x00"},
+		{section: "__literal8", addr: 0x30, size: 8, data: "\x02\x00\x00\x00\x00\x00\x00\x00"},
+	}
+	if len(atoms) != len(tests) {
+		t.Fatalf("got %d atoms, want %d", len(atoms), len(tests))
+	}
+	for i, tt := range tests {
+		a := atoms[i]
+		if a.Section != tt.section || a.Addr != tt.addr || a.Size != tt.size || a.Name() != tt.name {
+			t.Errorf("atom %d: got %s", i, a)
+			continue
+		}
+		if len(a.Symbols) != tt.symbols || len(a.Relocs) != tt.relocs || a.NoDeadStrip != tt.noDeadStrip {
+			t.Errorf("atom %d: got %d symbols, %d relocations and no-dead-strip=%t, want %d, %d and %t",
+				i, len(a.Symbols), len(a.Relocs), a.NoDeadStrip, tt.symbols, tt.relocs, tt.noDeadStrip)
+		}
+		if a.Offset != a.Addr-f.Section("__TEXT", a.Section).Addr || a.AlignModulus != a.Addr%(1<<a.Align) {
+			t.Errorf("atom %d: got offset %#x and align modulus %d", i, a.Offset, a.AlignModulus)
+		}
+		if len(tt.data) > 0 {
+			if dat, err := a.Data(); err != nil {
+				t.Errorf("atom %d: %v", i, err)
+			} else if string(dat) != tt.data {
+				t.Errorf("atom %d: got data %q, want %q", i, dat, tt.data)
+			}
+		}
+	}
+	if alt := atoms[0].Symbols[len(atoms[0].Symbols)-1]; alt.Name != "_a2" || alt.Desc&types.ALT_ENTRY == 0 {
+		t.Errorf("got %s as the last symbol of _a, want the alt entry _a2", alt.Name)
+	}
+
+	// without MH_SUBSECTIONS_VIA_SYMBOLS a section is a single atom (but literals are still split by content)
+	f.Flags &^= types.SubsectionsViaSymbols
+	atoms, err = f.Atoms()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(atoms) != 5 || atoms[0].Size != 0x1c || len(atoms[0].Symbols) != 5 || len(atoms[0].Relocs) != 4 {
+		t.Errorf("got %d atoms (the first %s), want a single __text atom", len(atoms), atoms[0])
+	}
+}
+
+func TestAtomsMalformed(t *testing.T) {
+	f, err := openObscured("internal/testdata/atoms-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for i, sym := range f.Symtab.Syms {
+		if sym.Name == "_b" {
+			f.Symtab.Syms[i].Value = 0x1000 // outside of __text
+		}
+	}
+	if _, err := f.Atoms(); err == nil {
+		t.Error("expected an error for a symbol outside of its section")
+	}
+
+	for i, sym := range f.Symtab.Syms {
+		if sym.Name == "_b" {
+			f.Symtab.Syms[i].Value = 0x14
+		}
+	}
+	sec := f.Section("__TEXT", "__literal8")
+	sec.Align = 64
+	if _, err := f.Atoms(); err == nil {
+		t.Error("expected an error for an alignment of 2^64")
+	}
+	sec.Align = 3
+	sec.Size = 1 << 62
+	if _, err := f.Atoms(); err == nil {
+		t.Error("expected an error for literals past the end of the file")
+	}
+
+	if _, err := (Atom{}).Data(); err == nil {
+		t.Error("expected an error for an atom that isn't part of a parsed section")
+	}
+
+	exe, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exe.Close()
+	if _, err := exe.Atoms(); err == nil {
+		t.Error("expected an error for an image")
+	}
+}
@@ -282,7 +282,8 @@ type Section struct {
 	// Open() to avoid fighting over the seek offset
 	// with other clients.
 	io.ReaderAt
-	sr *io.SectionReader
+	sr   *io.SectionReader
+	file *File // the file the section belongs to (for symbol and section lookups)
 }
 
 // Data reads and returns the contents of the Mach-O section.
@@ -556,12 +557,17 @@ func (s *SymSeg) String() string {
 type Thread struct {
 	LoadBytes
 	types.Thread
-	Type uint32
-	Data []uint32
+	Type    uint32
+	Data    []uint32
+	Threads []ThreadState
 }
 
 func (t *Thread) String() string {
-	return fmt.Sprintf("Type: %d", t.Type)
+	var states []string
+	for _, state := range t.Threads {
+		states = append(states, state.String())
+	}
+	return fmt.Sprintf("Type: %d, States: %s", t.Type, strings.Join(states, ", "))
 }
 
 /*******************************************************************************
@@ -573,6 +579,7 @@ type UnixThread struct {
 	LoadBytes
 	types.UnixThreadCmd
 	EntryPoint uint64
+	Threads    []ThreadState
 }
 
 func (u *UnixThread) String() string {
@@ -662,7 +669,20 @@ type Prepage struct {
 type Dysymtab struct {
 	LoadBytes
 	types.DysymtabCmd
-	IndirectSyms []uint32 // indices into Symtab.Syms
+	IndirectSyms    []uint32 // indices into Symtab.Syms
+	TableOfContents []types.DylibTableOfContents
+	Modules         []types.DylibModule64 // 32-bit module table entries are widened
+	ExtRefSyms      []types.DylibReference
+	ExtRelocs       []Reloc // external relocation entries (binds of classic, non-dyld-info, images)
+	LocRelocs       []Reloc // local relocation entries (rebases of classic, non-dyld-info, images)
+
+	tablesErr error
+}
+
+// TablesErr returns the first error reading the table of contents, module table, external references
+// or relocation entries; the tables that can't be read are left empty.
+func (d *Dysymtab) TablesErr() error {
+	return d.tablesErr
 }
 
 func (d *Dysymtab) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
@@ -1034,7 +1054,7 @@ type SplitInfo struct {
 	types.SegmentSplitInfoCmd
 	Offset  uint32
 	Size    uint32
-	Version uint8
+	Version uint8 // first byte of the split info; DYLD_CACHE_ADJ_V2_FORMAT for v2, otherwise the first v1 kind
 	Offsets []uint64
 }
 
@@ -1635,17 +1655,21 @@ type BuildVersion struct {
 	Minos       string /* X.Y.Z is encoded in nibbles xxxx.yy.zz */
 	Sdk         string /* X.Y.Z is encoded in nibbles xxxx.yy.zz */
 	NumTools    uint32 /* number of tool entries following this */
-	Tool        string
-	ToolVersion string
+	Tool        string /* first tool */
+	ToolVersion string /* first tool's version */
+	Tools       []types.BuildToolVersion
 }
 
 func (b *BuildVersion) String() string {
 	if b.NumTools > 0 {
-		return fmt.Sprintf("Platform: %s, SDK: %s, Tool: %s (%s)",
+		var tools []string
+		for _, t := range b.Tools {
+			tools = append(tools, fmt.Sprintf("%s (%s)", t.Tool, t.Version))
+		}
+		return fmt.Sprintf("Platform: %s, SDK: %s, Tool: %s",
 			b.Platform,
 			b.Sdk,
-			b.Tool,
-			b.ToolVersion)
+			strings.Join(tools, ", "))
 	}
 	return fmt.Sprintf("Platform: %s, SDK: %s",
 		b.Platform,
@@ -0,0 +1,444 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// Well-known LC_NOTE data owners
+const (
+	NoteAddrableBits    = "addrable bits"
+	NoteMainBinSpec     = "main bin spec"
+	NoteLoadBinary      = "load binary"
+	NoteAllImageInfos   = "all image infos"
+	NoteProcessMetadata = "process metadata"
+)
+
+// Notes returns all the LC_NOTE load commands.
+func (f *File) Notes() []*Note {
+	var notes []*Note
+	for _, l := range f.Loads {
+		if n, ok := l.(*Note); ok {
+			notes = append(notes, n)
+		}
+	}
+	return notes
+}
+
+// GetNote returns the first LC_NOTE load command with the given data owner, or nil if there is none.
+func (f *File) GetNote(owner string) *Note {
+	for _, n := range f.Notes() {
+		if n.DataOwner == owner {
+			return n
+		}
+	}
+	return nil
+}
+
+// GetNoteData returns the payload of a LC_NOTE load command.
+func (f *File) GetNoteData(n *Note) ([]byte, error) {
+	if n.Offset > math.MaxInt64 || n.Size > math.MaxInt64-n.Offset {
+		return nil, fmt.Errorf("invalid %s note data offset=%#x size=%#x", n.DataOwner, n.Offset, n.Size)
+	}
+	// the size comes from the file, so only allocate what can actually be read
+	dat, err := io.ReadAll(io.NewSectionReader(f.sr, int64(n.Offset), int64(n.Size)))
+	if err == nil && uint64(len(dat)) != n.Size {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s note data at offset=%#x: %v", n.DataOwner, n.Offset, err)
+	}
+	return dat, nil
+}
+
+func (f *File) readNote(owner string, data interface{}) error {
+	n := f.GetNote(owner)
+	if n == nil {
+		return fmt.Errorf("macho does not contain a %q %s", owner, types.LC_NOTE)
+	}
+	dat, err := f.GetNoteData(n)
+	if err != nil {
+		return err
+	}
+	if err := binary.Read(bytes.NewReader(dat), f.ByteOrder, data); err != nil {
+		return fmt.Errorf("failed to read %s note: %v", owner, err)
+	}
+	return nil
+}
+
+// AddrableBits is the "addrable bits" note; the number of bits used for addressing (the rest hold PAC/TBI bits)
+type AddrableBits struct {
+	Version uint32
+	LoBits  uint32 // bits used for addressing in low (userland) memory
+	HiBits  uint32 // bits used for addressing in high (kernel) memory
+}
+
+// AddressMask returns the mask that strips the non-addressing bits from a low memory address
+func (a *AddrableBits) AddressMask() uint64 {
+	if a.LoBits == 0 || a.LoBits >= 64 {
+		return math.MaxUint64
+	}
+	return 1<<a.LoBits - 1
+}
+
+// GetAddrableBits returns the "addrable bits" note
+func (f *File) GetAddrableBits() (*AddrableBits, error) {
+	var bits AddrableBits
+
+	n := f.GetNote(NoteAddrableBits)
+	if n == nil {
+		return nil, fmt.Errorf("macho does not contain a %q %s", NoteAddrableBits, types.LC_NOTE)
+	}
+	dat, err := f.GetNoteData(n)
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(dat)
+	if err := binary.Read(r, f.ByteOrder, &bits.Version); err != nil {
+		return nil, fmt.Errorf("failed to read %s note version: %v", NoteAddrableBits, err)
+	}
+	if err := binary.Read(r, f.ByteOrder, &bits.LoBits); err != nil {
+		return nil, fmt.Errorf("failed to read %s note: %v", NoteAddrableBits, err)
+	}
+	if bits.Version >= 4 { // v4 splits into low and high memory, v3 has a single value for both
+		if err := binary.Read(r, f.ByteOrder, &bits.HiBits); err != nil {
+			return nil, fmt.Errorf("failed to read %s note: %v", NoteAddrableBits, err)
+		}
+	} else {
+		bits.HiBits = bits.LoBits
+	}
+	return &bits, nil
+}
+
+// MainBinSpecType is the kind of main binary a "main bin spec" note describes
+type MainBinSpecType uint32
+
+const (
+	MainBinSpecUnspecified MainBinSpecType = 0
+	MainBinSpecKernel      MainBinSpecType = 1
+	MainBinSpecUser        MainBinSpecType = 2
+	MainBinSpecStandalone  MainBinSpecType = 3
+)
+
+func (t MainBinSpecType) String() string {
+	switch t {
+	case MainBinSpecUnspecified:
+		return "unspecified"
+	case MainBinSpecKernel:
+		return "kernel"
+	case MainBinSpecUser:
+		return "user process"
+	case MainBinSpecStandalone:
+		return "standalone"
+	default:
+		return fmt.Sprintf("type(%d)", t)
+	}
+}
+
+// MainBinSpec is the "main bin spec" note; the main binary of the corefile
+type MainBinSpec struct {
+	Version      uint32
+	Type         MainBinSpecType
+	Address      uint64 // math.MaxUint64 if unspecified
+	Slide        uint64 // math.MaxUint64 if unspecified (v2+)
+	UUID         types.UUID
+	Log2PageSize uint32
+	Platform     types.Platform // v2+
+}
+
+// GetMainBinSpec returns the "main bin spec" note
+func (f *File) GetMainBinSpec() (*MainBinSpec, error) {
+	var version uint32
+	if err := f.readNote(NoteMainBinSpec, &version); err != nil {
+		return nil, err
+	}
+	if version < 2 {
+		var v1 struct {
+			Version      uint32
+			Type         MainBinSpecType
+			Address      uint64
+			UUID         types.UUID
+			Log2PageSize uint32
+			_            uint32
+		}
+		if err := f.readNote(NoteMainBinSpec, &v1); err != nil {
+			return nil, err
+		}
+		return &MainBinSpec{
+			Version:      v1.Version,
+			Type:         v1.Type,
+			Address:      v1.Address,
+			Slide:        math.MaxUint64,
+			UUID:         v1.UUID,
+			Log2PageSize: v1.Log2PageSize,
+		}, nil
+	}
+	var spec MainBinSpec
+	if err := f.readNote(NoteMainBinSpec, &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// A LoadBinary is a "load binary" note; a binary loaded in the corefile's address space
+type LoadBinary struct {
+	Version     uint32
+	UUID        types.UUID
+	LoadAddress uint64 // math.MaxUint64 if unavailable
+	Slide       uint64
+	Name        string
+}
+
+// GetLoadBinaries returns all the "load binary" notes
+func (f *File) GetLoadBinaries() ([]LoadBinary, error) {
+	var lbs []LoadBinary
+	for _, n := range f.Notes() {
+		if n.DataOwner != NoteLoadBinary {
+			continue
+		}
+		dat, err := f.GetNoteData(n)
+		if err != nil {
+			return nil, err
+		}
+		var lb LoadBinary
+		r := bytes.NewReader(dat)
+		if err := binary.Read(r, f.ByteOrder, &lb.Version); err != nil {
+			return nil, fmt.Errorf("failed to read %s note: %v", NoteLoadBinary, err)
+		}
+		if err := binary.Read(r, f.ByteOrder, &lb.UUID); err != nil {
+			return nil, fmt.Errorf("failed to read %s note: %v", NoteLoadBinary, err)
+		}
+		if err := binary.Read(r, f.ByteOrder, &lb.LoadAddress); err != nil {
+			return nil, fmt.Errorf("failed to read %s note: %v", NoteLoadBinary, err)
+		}
+		if err := binary.Read(r, f.ByteOrder, &lb.Slide); err != nil {
+			return nil, fmt.Errorf("failed to read %s note: %v", NoteLoadBinary, err)
+		}
+		name := dat[len(dat)-r.Len():]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		lb.Name = string(name)
+		lbs = append(lbs, lb)
+	}
+	return lbs, nil
+}
+
+// An ImageInfo is an image entry of the "all image infos" note
+type ImageInfo struct {
+	Path        string
+	UUID        types.UUID
+	LoadAddress uint64 // math.MaxUint64 if unavailable
+	Segments    []ImageSegment
+}
+
+// An ImageSegment is the load address of an ImageInfo's segment
+type ImageSegment struct {
+	Name string
+	Addr uint64
+}
+
+// GetAllImageInfos returns the images listed in the "all image infos" note
+func (f *File) GetAllImageInfos() ([]ImageInfo, error) {
+	var hdr struct {
+		Version       uint32
+		ImageCount    uint32
+		EntriesOffset uint64
+		EntrySize     uint32
+		_             uint32
+	}
+	if err := f.readNote(NoteAllImageInfos, &hdr); err != nil {
+		return nil, err
+	}
+
+	var entry struct {
+		PathOffset     uint64
+		UUID           types.UUID
+		LoadAddress    uint64
+		SegAddrsOffset uint64
+		SegmentCount   uint32
+		_              uint32
+	}
+	if hdr.EntrySize < uint32(binary.Size(entry)) {
+		return nil, fmt.Errorf("invalid %s note entry size %d", NoteAllImageInfos, hdr.EntrySize)
+	}
+
+	var seg struct {
+		Name [16]byte
+		Addr uint64
+		_    uint64
+	}
+
+	var infos []ImageInfo
+	for i := uint32(0); i < hdr.ImageCount; i++ {
+		off := int64(hdr.EntriesOffset) + int64(i)*int64(hdr.EntrySize)
+		if err := binary.Read(io.NewSectionReader(f.sr, off, int64(hdr.EntrySize)), f.ByteOrder, &entry); err != nil {
+			return nil, fmt.Errorf("failed to read %s note image entry %d: %v", NoteAllImageInfos, i, err)
+		}
+		info := ImageInfo{
+			UUID:        entry.UUID,
+			LoadAddress: entry.LoadAddress,
+		}
+		if entry.PathOffset != math.MaxUint64 {
+			path, err := f.GetCStringAtOffset(int64(entry.PathOffset))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s note image entry %d path: %v", NoteAllImageInfos, i, err)
+			}
+			info.Path = path
+		}
+		sr := io.NewSectionReader(f.sr, int64(entry.SegAddrsOffset), int64(entry.SegmentCount)*int64(binary.Size(seg)))
+		for j := uint32(0); j < entry.SegmentCount; j++ {
+			if err := binary.Read(sr, f.ByteOrder, &seg); err != nil {
+				return nil, fmt.Errorf("failed to read %s note image entry %d segment %d: %v", NoteAllImageInfos, i, j, err)
+			}
+			info.Segments = append(info.Segments, ImageSegment{
+				Name: strings.TrimRight(string(seg.Name[:]), "\x00"),
+				Addr: seg.Addr,
+			})
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// ProcessMetadata is the JSON "process metadata" note
+type ProcessMetadata struct {
+	Threads []struct {
+		ThreadID uint64 `json:"thread_id"`
+	} `json:"threads,omitempty"`
+	Raw json.RawMessage `json:"-"`
+}
+
+// GetProcessMetadata returns the "process metadata" note
+func (f *File) GetProcessMetadata() (*ProcessMetadata, error) {
+	n := f.GetNote(NoteProcessMetadata)
+	if n == nil {
+		return nil, fmt.Errorf("macho does not contain a %q %s", NoteProcessMetadata, types.LC_NOTE)
+	}
+	dat, err := f.GetNoteData(n)
+	if err != nil {
+		return nil, err
+	}
+	dat = bytes.TrimRight(dat, "\x00")
+
+	var md ProcessMetadata
+	if err := json.Unmarshal(dat, &md); err != nil {
+		return nil, fmt.Errorf("failed to parse %s note JSON: %v", NoteProcessMetadata, err)
+	}
+	md.Raw = dat
+	return &md, nil
+}
+
+/*******************************************************************************
+ * MH_CORE
+ *******************************************************************************/
+
+// A MemoryRegion is a range of a corefile's process memory.
+type MemoryRegion struct {
+	Name     string
+	Addr     uint64
+	Size     uint64
+	Offset   uint64 // file offset of the region's bytes
+	FileSize uint64 // bytes present in the file (the rest of the region reads as zeros)
+	Prot     types.VmProtection
+	MaxProt  types.VmProtection
+}
+
+// Contains reports whether addr is in the region
+func (r MemoryRegion) Contains(addr uint64) bool {
+	return r.Addr <= addr && addr < r.Addr+r.Size
+}
+
+func (r MemoryRegion) String() string {
+	return fmt.Sprintf("%#016x-%#016x %s/%s off=%#x filesz=%#x %s", r.Addr, r.Addr+r.Size, r.Prot, r.MaxProt, r.Offset, r.FileSize, r.Name)
+}
+
+// A Core is a MH_CORE file viewed as a process image.
+type Core struct {
+	*File
+	Regions []MemoryRegion
+	Threads [][]ThreadState // the register states of each thread (one LC_THREAD per thread)
+}
+
+// Core returns the MH_CORE file as a process image.
+func (f *File) Core() (*Core, error) {
+	if f.Type != types.Core {
+		return nil, fmt.Errorf("macho is not a core file (type=%s)", f.Type)
+	}
+	c := &Core{File: f}
+	for _, seg := range f.Segments() {
+		if seg.Memsz == 0 {
+			continue
+		}
+		c.Regions = append(c.Regions, MemoryRegion{
+			Name:     seg.Name,
+			Addr:     seg.Addr,
+			Size:     seg.Memsz,
+			Offset:   seg.Offset,
+			FileSize: seg.Filesz,
+			Prot:     seg.Prot,
+			MaxProt:  seg.Maxprot,
+		})
+	}
+	for _, l := range f.Loads {
+		if t, ok := l.(*Thread); ok {
+			c.Threads = append(c.Threads, t.Threads)
+		}
+	}
+	return c, nil
+}
+
+// FindRegion returns the memory region containing addr
+func (c *Core) FindRegion(addr uint64) (MemoryRegion, bool) {
+	for _, r := range c.Regions {
+		if r.Contains(addr) {
+			return r, true
+		}
+	}
+	return MemoryRegion{}, false
+}
+
+// ReadAt reads len(p) bytes of process memory starting at the virtual address vmaddr.
+//
+// NOTE: reads may span adjacent regions; bytes past a region's file size read as zeros.
+func (c *Core) ReadAt(p []byte, vmaddr int64) (int, error) {
+	var n int
+	addr := uint64(vmaddr)
+	for n < len(p) {
+		r, ok := c.FindRegion(addr)
+		if !ok {
+			return n, fmt.Errorf("address %#x is not mapped in the core file", addr)
+		}
+		off := addr - r.Addr
+		chunk := p[n:]
+		if uint64(len(chunk)) > r.Size-off {
+			chunk = chunk[:r.Size-off]
+		}
+		var read int
+		if off < r.FileSize {
+			avail := chunk
+			if uint64(len(avail)) > r.FileSize-off {
+				avail = avail[:r.FileSize-off]
+			}
+			m, err := c.File.ReadAt(avail, int64(r.Offset+off))
+			if err != nil && !(err == io.EOF && m == len(avail)) {
+				return n + m, fmt.Errorf("failed to read core memory at %#x: %v", addr, err)
+			}
+			read = m
+		}
+		for i := read; i < len(chunk); i++ {
+			chunk[i] = 0
+		}
+		n += len(chunk)
+		addr += uint64(len(chunk))
+	}
+	return n, nil
+}
@@ -0,0 +1,143 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// openNoteTestFile opens the test file with its LC_DYLD_INFO_ONLY turned into a LC_NOTE of the owner
+// (and the given size, if non-zero) whose payload is appended to the file
+func openNoteTestFile(t *testing.T, owner string, payload []byte, size uint64) (*File, error) {
+	t.Helper()
+	return openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		off := loadCmdOffset(f, f.DyldInfo())
+		binary.LittleEndian.PutUint32(data[off:], uint32(types.LC_NOTE))
+		copy(data[off+8:off+24], make([]byte, 16))
+		copy(data[off+8:off+24], owner)
+		if size == 0 {
+			size = uint64(len(payload))
+		}
+		binary.LittleEndian.PutUint64(data[off+24:], uint64(len(data)))
+		binary.LittleEndian.PutUint64(data[off+32:], size)
+		return append(data, payload...)
+	})
+}
+
+func TestGetAddrableBits(t *testing.T) {
+	tests := []struct {
+		name   string
+		words  []uint32
+		lo, hi uint32
+	}{
+		{"v2", []uint32{2, 47}, 47, 47},
+		{"v3 single value", []uint32{3, 39, 0}, 39, 39},
+		{"v4 low and high", []uint32{4, 39, 55}, 39, 55},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			binary.Write(&buf, binary.LittleEndian, tt.words)
+			f, err := openNoteTestFile(t, NoteAddrableBits, buf.Bytes(), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			bits, err := f.GetAddrableBits()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bits.LoBits != tt.lo || bits.HiBits != tt.hi {
+				t.Errorf("got lo=%d hi=%d, want lo=%d hi=%d", bits.LoBits, bits.HiBits, tt.lo, tt.hi)
+			}
+			if mask := bits.AddressMask(); mask != 1<<tt.lo-1 {
+				t.Errorf("got address mask %#x, want %#x", mask, uint64(1<<tt.lo-1))
+			}
+		})
+	}
+}
+
+func TestGetAddrableBitsMalformed(t *testing.T) {
+	f, err := openNoteTestFile(t, NoteAddrableBits, []byte{4, 0, 0, 0, 39, 0, 0, 0}, 0) // v4 without the high bits
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.GetAddrableBits(); err == nil {
+		t.Error("expected an error for a truncated v4 note")
+	}
+	f.Close()
+
+	f, err = openNoteTestFile(t, NoteAddrableBits, nil, 1<<62) // payload past the end of the file
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.GetAddrableBits(); err == nil {
+		t.Error("expected an error for note data past the end of the file")
+	}
+	f.Close()
+}
+
+func TestGetLoadBinaries(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	binary.Write(&buf, binary.LittleEndian, types.UUID{1, 2, 3})
+	binary.Write(&buf, binary.LittleEndian, []uint64{0xfffffff007004000, 0x4000})
+	buf.WriteString("kernel\x00")
+	f, err := openNoteTestFile(t, NoteLoadBinary, buf.Bytes(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lbs, err := f.GetLoadBinaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lbs) != 1 || lbs[0].Name != "kernel" || lbs[0].LoadAddress != 0xfffffff007004000 || lbs[0].Slide != 0x4000 || lbs[0].UUID[2] != 3 {
+		t.Errorf("unexpected load binaries %v", lbs)
+	}
+}
+
+func TestCore(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Core(); err == nil {
+		t.Error("expected an error for a non core file")
+	}
+	f.Close()
+
+	f, err = openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		binary.LittleEndian.PutUint32(data[12:], uint32(types.Core))
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	c, err := f.Core()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Regions) != 4 || c.Regions[0].Name != "__PAGEZERO" || c.Regions[0].FileSize != 0 {
+		t.Errorf("got regions %v, want __PAGEZERO, __TEXT, __DATA and __LINKEDIT", c.Regions)
+	}
+	got := make([]byte, 8)
+	if _, err := c.ReadAt(got, 0x100000f60); err != nil {
+		t.Fatal(err)
+	}
+	want := make([]byte, 8)
+	if _, err := f.ReadAt(want, 0xf60); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x at 0x100000f60, want %x", got, want)
+	}
+	if _, err := c.ReadAt(got, 0x200000000); err == nil {
+		t.Error("expected an error for an unmapped address")
+	}
+}
@@ -0,0 +1,388 @@
+package macho
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// A DebugMapSymbol is a symbol of a debug map object; where a symbol of the object file ended up in the linked binary.
+type DebugMapSymbol struct {
+	Name       string
+	Type       uint8  // the STABS type (N_FUN, N_STSYM or N_GSYM)
+	ObjAddr    uint64 // address of the symbol in the object file (if HasObjAddr)
+	HasObjAddr bool
+	BinAddr    uint64 // address of the symbol in the linked binary
+	Size       uint64 // size of the function (0 for data symbols)
+}
+
+// A DebugMapObject is an object file (a N_OSO compilation unit) that was linked into the binary.
+type DebugMapObject struct {
+	Filename   string // path to the object file (or archive(member) for static libraries)
+	Timestamp  time.Time
+	SourceDir  string
+	SourceFile string
+	Symbols    []DebugMapSymbol
+}
+
+// A DebugMap is the STABS debug map of a linked binary; the object files that hold its DWARF (when there is no dSYM)
+// and the addresses of their symbols.
+type DebugMap struct {
+	Triple     string
+	BinaryPath string // path of the binary (not known to the File, so set it before printing if needed)
+	Objects    []DebugMapObject
+
+	cpu types.CPU
+}
+
+// String returns the debug map in the YAML format of `dsymutil -dump-debug-map`.
+func (d DebugMap) String() string {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	sb.WriteString(fmt.Sprintf("triple:          '%s'\n", d.Triple))
+	sb.WriteString(fmt.Sprintf("binary-path:     %s\n", yamlQuote(d.BinaryPath)))
+	if len(d.Objects) > 0 {
+		sb.WriteString("objects:\n")
+	}
+	for _, obj := range d.Objects {
+		sb.WriteString(fmt.Sprintf("  - filename:        %s\n", yamlQuote(obj.Filename)))
+		sb.WriteString(fmt.Sprintf("    timestamp:       %d\n", obj.Timestamp.Unix()))
+		if len(obj.Symbols) > 0 {
+			sb.WriteString("    symbols:\n")
+		}
+		for _, sym := range obj.Symbols {
+			var objAddr string
+			if sym.HasObjAddr {
+				objAddr = fmt.Sprintf(" objAddr: 0x%X,", sym.ObjAddr)
+			}
+			sb.WriteString(fmt.Sprintf("      - { sym: %s,%s binAddr: 0x%X, size: 0x%X }\n", yamlQuote(sym.Name), objAddr, sym.BinAddr, sym.Size))
+		}
+	}
+	sb.WriteString("...\n")
+	return sb.String()
+}
+
+// yamlQuote quotes a YAML scalar the way LLVM's YAML writer does
+func yamlQuote(s string) string {
+	if len(s) == 0 {
+		return "''"
+	}
+	quote := strings.ContainsRune(`-?:\,[]{}#&*!|>'"%@`+"`", rune(s[0])) || s[0] == ' ' || s[len(s)-1] == ' '
+	for _, c := range []byte(s) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '_', c == '-', c == '^', c == '.', c == ',', c == ' ', c == '\t':
+		case c < 0x20 && c != '\n' && c != '\r', c >= 0x7f:
+			return fmt.Sprintf("%q", s)
+		default:
+			quote = true
+		}
+	}
+	if quote {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return s
+}
+
+// debugMapTriple returns the LLVM target triple dsymutil uses for a CPU type
+func debugMapTriple(cpu types.CPU, subtype types.CPUSubtype) string {
+	subtype &^= types.CpuSubtypeFeatureMask
+	var arch string
+	switch cpu {
+	case types.CPU386:
+		arch = "i386"
+	case types.CPUAmd64:
+		arch = "x86_64"
+		if subtype == types.CPUSubtypeX86_64H {
+			arch = "x86_64h"
+		}
+	case types.CPUArm:
+		switch subtype {
+		case types.CPUSubtypeArmV4T:
+			arch = "armv4t"
+		case types.CPUSubtypeArmV5Tej:
+			arch = "armv5e"
+		case types.CPUSubtypeArmXscale:
+			arch = "xscale"
+		case types.CPUSubtypeArmV6:
+			arch = "armv6"
+		case types.CPUSubtypeArmV6M:
+			arch = "thumbv6m"
+		case types.CPUSubtypeArmV7:
+			arch = "thumbv7"
+		case types.CPUSubtypeArmV7Em:
+			arch = "thumbv7em"
+		case types.CPUSubtypeArmV7K:
+			arch = "thumbv7k"
+		case types.CPUSubtypeArmV7M:
+			arch = "thumbv7m"
+		case types.CPUSubtypeArmV7S:
+			arch = "thumbv7s"
+		default:
+			arch = "arm"
+		}
+	case types.CPUArm64:
+		arch = "arm64"
+		if subtype == types.CPUSubtypeArm64E {
+			arch = "arm64e"
+		}
+	case types.CPUArm6432:
+		arch = "arm64_32"
+	case types.CPUPpc:
+		arch = "ppc"
+	case types.CPUPpc64:
+		arch = "ppc64"
+	default:
+		arch = "unknown"
+	}
+	return arch + "-apple-darwin"
+}
+
+// DebugMap groups the STABS entries of the symbol table by compilation unit (like `dsymutil -dump-debug-map`).
+//
+// NOTE: only the symbol table is read; call ResolveObjAddrs to look up the symbols' object addresses in the object files.
+func (f *File) DebugMap() (*DebugMap, error) {
+	if f.Symtab == nil {
+		return nil, fmt.Errorf("macho does not contain a %s load command", types.LC_SYMTAB)
+	}
+
+	dm := &DebugMap{Triple: debugMapTriple(f.CPU, f.SubCPU), cpu: f.CPU}
+
+	// N_GSYM entries don't hold an address, so look it up in the (non-STABS) symbol table
+	binAddrs := make(map[string]uint64)
+	for _, sym := range f.Symtab.Syms {
+		if sym.Type.IsDebugSym() || len(sym.Name) == 0 || sym.Type.IsUndefinedSym() {
+			continue
+		}
+		if _, ok := binAddrs[sym.Name]; !ok || sym.Type.IsExternalSym() {
+			binAddrs[sym.Name] = sym.Value
+		}
+	}
+
+	var obj *DebugMapObject
+	var sourceDir, sourceFile string
+	var funcName string
+	var funcAddr uint64
+
+	for _, sym := range f.Symtab.Syms {
+		if !sym.Type.IsDebugSym() {
+			continue
+		}
+		switch uint8(sym.Type) {
+		case types.N_SO:
+			if len(sym.Name) == 0 { // end of the compilation unit
+				obj = nil
+				sourceDir, sourceFile = "", ""
+				funcName = ""
+			} else if strings.HasSuffix(sym.Name, "/") {
+				sourceDir = sym.Name
+			} else {
+				sourceFile = sym.Name
+			}
+		case types.N_OSO:
+			dm.Objects = append(dm.Objects, DebugMapObject{
+				Filename:   sym.Name,
+				Timestamp:  time.Unix(int64(sym.Value), 0),
+				SourceDir:  sourceDir,
+				SourceFile: sourceFile,
+			})
+			obj = &dm.Objects[len(dm.Objects)-1]
+			funcName = ""
+		case types.N_FUN:
+			if obj == nil {
+				continue
+			}
+			if len(sym.Name) > 0 {
+				funcName = sym.Name
+				funcAddr = sym.Value
+			} else if len(funcName) > 0 { // the end of the function holds its size
+				obj.Symbols = append(obj.Symbols, DebugMapSymbol{
+					Name:    funcName,
+					Type:    types.N_FUN,
+					BinAddr: funcAddr,
+					Size:    sym.Value,
+				})
+				funcName = ""
+			}
+		case types.N_STSYM:
+			if obj == nil {
+				continue
+			}
+			obj.Symbols = append(obj.Symbols, DebugMapSymbol{
+				Name:    sym.Name,
+				Type:    types.N_STSYM,
+				BinAddr: sym.Value,
+			})
+		case types.N_GSYM:
+			if obj == nil {
+				continue
+			}
+			addr, ok := binAddrs[sym.Name]
+			if !ok {
+				continue // dsymutil skips globals that are not in the symbol table
+			}
+			obj.Symbols = append(obj.Symbols, DebugMapSymbol{
+				Name:    sym.Name,
+				Type:    types.N_GSYM,
+				BinAddr: addr,
+			})
+		}
+	}
+
+	return dm, nil
+}
+
+// ResolveObjAddrs opens the object files (and static library members) of the debug map to look up the object
+// addresses of their symbols. Like dsymutil, it skips the objects that were modified after the binary was linked;
+// it resolves every object it can and returns the first error.
+func (d *DebugMap) ResolveObjAddrs() error {
+	var firstErr error
+	for i := range d.Objects {
+		if err := d.Objects[i].ResolveObjAddrs(d.cpu); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// ResolveObjAddrs looks up the object addresses of the symbols in the object file (or the cpu slice of a universal
+// object file). A Filename of the form lib.a(foo.o) refers to a static library member.
+func (o *DebugMapObject) ResolveObjAddrs(cpu types.CPU) error {
+	path, member := o.Filename, ""
+	if strings.HasSuffix(path, ")") {
+		if i := strings.IndexByte(path, '('); i > 0 {
+			path, member = path[:i], path[i+1:len(path)-1]
+		}
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open debug map object: %v", err)
+	}
+	defer fd.Close()
+
+	var r io.ReaderAt = fd
+	var mtime time.Time
+	if len(member) > 0 {
+		if r, mtime, err = archiveMember(fd, member); err != nil {
+			return fmt.Errorf("failed to read %s: %v", o.Filename, err)
+		}
+	} else {
+		fi, err := fd.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to stat debug map object: %v", err)
+		}
+		mtime = fi.ModTime()
+	}
+	// a zero timestamp (i.e. ZERO_AR_DATE) disables the check
+	if o.Timestamp.Unix() != 0 && mtime.Unix() != o.Timestamp.Unix() {
+		return fmt.Errorf("%s: timestamp mismatch between the object file (%d) and the debug map (%d)",
+			o.Filename, mtime.Unix(), o.Timestamp.Unix())
+	}
+
+	obj, err := NewFile(r)
+	if err != nil {
+		fat, ferr := NewFatFile(r)
+		if ferr != nil {
+			return fmt.Errorf("failed to parse %s: %v", o.Filename, err)
+		}
+		for _, arch := range fat.Arches {
+			if arch.CPU == cpu {
+				obj = arch.File
+				break
+			}
+		}
+		if obj == nil {
+			return fmt.Errorf("%s does not contain a %s slice", o.Filename, cpu)
+		}
+	}
+	if obj.Symtab == nil {
+		return fmt.Errorf("%s does not contain a %s load command", o.Filename, types.LC_SYMTAB)
+	}
+
+	objAddrs := make(map[string]uint64)
+	for _, sym := range obj.Symtab.Syms {
+		if sym.Type.IsDebugSym() || len(sym.Name) == 0 {
+			continue
+		}
+		// common symbols (undefined with a size) don't have an address in the object
+		if sym.Type.IsUndefinedSym() {
+			continue
+		}
+		objAddrs[sym.Name] = sym.Value
+	}
+
+	for i, sym := range o.Symbols {
+		if addr, ok := objAddrs[sym.Name]; ok {
+			o.Symbols[i].ObjAddr = addr
+			o.Symbols[i].HasObjAddr = true
+		}
+	}
+
+	return nil
+}
+
+// archiveMember returns the contents and modification time of a static library member
+func archiveMember(r io.ReaderAt, name string) (*io.SectionReader, time.Time, error) {
+	magic := make([]byte, 8)
+	if _, err := r.ReadAt(magic, 0); err != nil || string(magic) != "!<arch>\n" {
+		return nil, time.Time{}, fmt.Errorf("not a static library")
+	}
+
+	// struct ar_hdr {
+	// 	char ar_name[16];
+	// 	char ar_date[12];
+	// 	char ar_uid[6], ar_gid[6];
+	// 	char ar_mode[8];
+	// 	char ar_size[10];
+	// 	char ar_fmag[2];
+	// };
+	hdr := make([]byte, 60)
+	for off := int64(len(magic)); ; {
+		if n, err := r.ReadAt(hdr, off); n == 0 && err == io.EOF {
+			return nil, time.Time{}, fmt.Errorf("member %s not found", name)
+		} else if n < len(hdr) {
+			return nil, time.Time{}, fmt.Errorf("failed to read member header at %#x: %v", off, err)
+		}
+		if string(hdr[58:]) != "`\n" {
+			return nil, time.Time{}, fmt.Errorf("invalid member header at %#x", off)
+		}
+		date, err := strconv.ParseInt(strings.TrimSpace(string(hdr[16:28])), 10, 64)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("invalid member date at %#x: %v", off, err)
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(string(hdr[48:58])), 10, 64)
+		if err != nil || size < 0 {
+			return nil, time.Time{}, fmt.Errorf("invalid member size at %#x", off)
+		}
+
+		dataOff, dataSize := off+int64(len(hdr)), size
+		memberName := strings.TrimRight(string(hdr[:16]), " ")
+		if strings.HasPrefix(memberName, "#1/") { // BSD long names follow the header
+			n, err := strconv.ParseInt(memberName[3:], 10, 64)
+			if err != nil || n < 0 || n > size {
+				return nil, time.Time{}, fmt.Errorf("invalid member name length at %#x", off)
+			}
+			buf := make([]byte, n)
+			if _, err := r.ReadAt(buf, dataOff); err != nil {
+				return nil, time.Time{}, fmt.Errorf("failed to read member name at %#x: %v", dataOff, err)
+			}
+			memberName = strings.TrimRight(string(buf), "\x00")
+			dataOff += n
+			dataSize -= n
+		} else {
+			memberName = strings.TrimSuffix(memberName, "/") // System V
+		}
+
+		if memberName == name {
+			return io.NewSectionReader(r, dataOff, dataSize), time.Unix(date, 0), nil
+		}
+
+		off = dataOff + dataSize + size%2 // members are 2-byte aligned
+	}
+}
@@ -0,0 +1,230 @@
+package macho
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacktop/go-macho/internal/obscuretestdata"
+	"github.com/blacktop/go-macho/types"
+)
+
+const debugMapTimestamp = 1700000000
+
+// debugMapTestFile returns a linked binary whose STABS debug map points at the given object file
+func debugMapTestFile(objects ...string) *File {
+	var syms []Symbol
+	for _, obj := range objects {
+		syms = append(syms,
+			Symbol{Name: "/src/", Type: types.NType(types.N_SO)},
+			Symbol{Name: "tlv.c", Type: types.NType(types.N_SO)},
+			Symbol{Name: obj, Type: types.NType(types.N_OSO), Value: debugMapTimestamp},
+			Symbol{Name: "_f", Type: types.NType(types.N_FUN), Value: 0x100003f00},
+			Symbol{Type: types.NType(types.N_FUN), Value: 0x1c},
+			Symbol{Name: "_a$tlv$init", Type: types.NType(types.N_STSYM), Value: 0x100008000},
+			Symbol{Name: "_a", Type: types.NType(types.N_GSYM)},
+			Symbol{Name: "_stripped", Type: types.NType(types.N_GSYM)}, // not in the symbol table
+			Symbol{Type: types.NType(types.N_SO)},
+		)
+	}
+	syms = append(syms,
+		Symbol{Name: "_f", Type: types.N_SECT | types.N_EXT, Sect: 1, Value: 0x100003f00},
+		Symbol{Name: "_a", Type: types.N_SECT | types.N_EXT, Sect: 2, Value: 0x100008010},
+	)
+	f := &File{Symtab: &Symtab{Syms: syms}}
+	f.CPU = types.CPUArm64
+	return f
+}
+
+// writeDebugMapObject writes the object file of the debug map to dir with the debug map's timestamp
+func writeDebugMapObject(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := obscuretestdata.ReadFile("internal/testdata/tlv-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "tlv.o")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, time.Unix(debugMapTimestamp, 0), time.Unix(debugMapTimestamp, 0)); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// archive returns a static library with a System V named member followed by a BSD long named member
+func archive(t *testing.T, name string, date int64, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("!<arch>\n")
+	fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", "other.o/", 0, 0, 0, 0644, 3)
+	buf.WriteString("abc\n") // padded to 2 bytes
+	longName := make([]byte, 20)
+	copy(longName, name)
+	fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", fmt.Sprintf("#1/%d", len(longName)), date, 0, 0, 0644, len(longName)+len(data))
+	buf.Write(longName)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func checkDebugMapObjAddrs(t *testing.T, obj DebugMapObject, resolved bool) {
+	t.Helper()
+	want := []DebugMapSymbol{
+		{Name: "_f", Type: types.N_FUN, BinAddr: 0x100003f00, Size: 0x1c},
+		{Name: "_a$tlv$init", Type: types.N_STSYM, ObjAddr: 0x1c, BinAddr: 0x100008000},
+		{Name: "_a", Type: types.N_GSYM, ObjAddr: 0x28, BinAddr: 0x100008010},
+	}
+	if len(obj.Symbols) != len(want) {
+		t.Fatalf("got %d symbols, want %d", len(obj.Symbols), len(want))
+	}
+	for i, sym := range obj.Symbols {
+		w := want[i]
+		w.HasObjAddr = resolved
+		if !resolved {
+			w.ObjAddr = 0
+		}
+		if sym != w {
+			t.Errorf("symbol %d: got %+v, want %+v", i, sym, w)
+		}
+	}
+}
+
+func TestDebugMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.o")
+	f := debugMapTestFile(path)
+
+	dm, err := f.DebugMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dm.Triple != "arm64-apple-darwin" || len(dm.Objects) != 1 {
+		t.Fatalf("got %s with %d objects, want an arm64 debug map with 1 object", dm.Triple, len(dm.Objects))
+	}
+	obj := dm.Objects[0]
+	if obj.Filename != path || obj.Timestamp.Unix() != debugMapTimestamp || obj.SourceDir != "/src/" || obj.SourceFile != "tlv.c" {
+		t.Errorf("got object %s (%d) for %s%s", obj.Filename, obj.Timestamp.Unix(), obj.SourceDir, obj.SourceFile)
+	}
+	// the object files are not opened implicitly
+	checkDebugMapObjAddrs(t, obj, false)
+
+	dm.BinaryPath = "/bin/tlv"
+	out := dm.String()
+	for _, line := range []string{
+		"triple:          'arm64-apple-darwin'\n",
+		"binary-path:     '/bin/tlv'\n",
+		"      - { sym: _f, binAddr: 0x100003F00, size: 0x1C }\n",
+		"      - { sym: '_a$tlv$init', binAddr: 0x100008000, size: 0x0 }\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+
+	if _, err := (&File{}).DebugMap(); err == nil {
+		t.Error("expected an error for a file without a symbol table")
+	}
+}
+
+func TestDebugMapResolveObjAddrs(t *testing.T) {
+	dir := t.TempDir()
+	path := writeDebugMapObject(t, dir)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib := filepath.Join(dir, "libtlv.a")
+	if err := os.WriteFile(lib, archive(t, "tlv.o", debugMapTimestamp, data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{path, lib + "(tlv.o)"} {
+		dm, err := debugMapTestFile(name).DebugMap()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dm.ResolveObjAddrs(); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		checkDebugMapObjAddrs(t, dm.Objects[0], true)
+		if out := dm.String(); !strings.Contains(out, "      - { sym: _a, objAddr: 0x28, binAddr: 0x100008010, size: 0x0 }\n") {
+			t.Errorf("%s: missing the object address of _a in:\n%s", name, out)
+		}
+	}
+
+	// the objects that can be resolved are, even if others fail
+	dm, err := debugMapTestFile(filepath.Join(dir, "missing.o"), path).DebugMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.ResolveObjAddrs(); err == nil {
+		t.Error("expected an error for a missing object file")
+	}
+	checkDebugMapObjAddrs(t, dm.Objects[0], false)
+	checkDebugMapObjAddrs(t, dm.Objects[1], true)
+}
+
+func TestDebugMapResolveObjAddrsMalformed(t *testing.T) {
+	dir := t.TempDir()
+	path := writeDebugMapObject(t, dir)
+
+	lib := filepath.Join(dir, "libtlv.a")
+	if err := os.WriteFile(lib, archive(t, "tlv.o", debugMapTimestamp+1, []byte("not a macho")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	obj := DebugMapObject{Filename: path, Timestamp: time.Unix(debugMapTimestamp+1, 0)}
+	if err := obj.ResolveObjAddrs(types.CPUArm64); err == nil || !strings.Contains(err.Error(), "timestamp mismatch") {
+		t.Errorf("got %v, want a timestamp mismatch", err)
+	}
+	// a zero timestamp disables the check
+	obj.Timestamp = time.Unix(0, 0)
+	if err := obj.ResolveObjAddrs(types.CPUArm64); err != nil {
+		t.Error(err)
+	}
+
+	for _, name := range []string{lib + "(missing.o)", path + "(tlv.o)"} {
+		obj := DebugMapObject{Filename: name, Timestamp: time.Unix(0, 0)}
+		if err := obj.ResolveObjAddrs(types.CPUArm64); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	obj = DebugMapObject{Filename: lib + "(tlv.o)", Timestamp: time.Unix(debugMapTimestamp, 0)}
+	if err := obj.ResolveObjAddrs(types.CPUArm64); err == nil || !strings.Contains(err.Error(), "timestamp mismatch") {
+		t.Errorf("got %v, want a timestamp mismatch for the member", err)
+	}
+	obj.Timestamp = time.Unix(debugMapTimestamp+1, 0)
+	if err := obj.ResolveObjAddrs(types.CPUArm64); err == nil {
+		t.Error("expected an error for a member that isn't a Mach-O")
+	}
+
+	valid := archive(t, "tlv.o", 0, []byte("x"))
+	tests := []struct {
+		name  string
+		patch func(ar []byte) []byte
+	}{
+		{name: "magic", patch: func(ar []byte) []byte { return ar[1:] }},
+		{name: "truncated header", patch: func(ar []byte) []byte { return ar[:8+30] }},
+		{name: "header magic", patch: func(ar []byte) []byte { ar[8+58] = 'x'; return ar }},
+		{name: "date", patch: func(ar []byte) []byte { ar[8+16] = 'x'; return ar }},
+		{name: "size", patch: func(ar []byte) []byte { copy(ar[8+48:], "-1"); return ar }},
+		{name: "name length", patch: func(ar []byte) []byte { copy(ar[8+60+4+3:], "99"); return ar }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar := tt.patch(append([]byte(nil), valid...))
+			if _, _, err := archiveMember(bytes.NewReader(ar), "tlv.o"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+	if r, date, err := archiveMember(bytes.NewReader(valid), "other.o"); err != nil || r.Size() != 3 || date.Unix() != 0 {
+		t.Errorf("got %v (%v), want the 3 byte System V member", r, err)
+	}
+}
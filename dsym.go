@@ -0,0 +1,144 @@
+package macho
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// FindDSYMs returns the DWARF files (*.dSYM/Contents/Resources/DWARF/*) of the dSYM bundles
+// in the search directories (or the search paths themselves if they are dSYM bundles).
+//
+// NOTE: search directories that don't exist are skipped
+func FindDSYMs(dirs ...string) ([]string, error) {
+	var paths []string
+
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if path == dir {
+					return err
+				}
+				return nil // skip unreadable entries
+			}
+			if !info.IsDir() || !strings.HasSuffix(strings.TrimSuffix(path, "/"), ".dSYM") {
+				return nil
+			}
+			dwarfDir := filepath.Join(path, "Contents", "Resources", "DWARF")
+			entries, err := os.ReadDir(dwarfDir)
+			if err != nil {
+				return filepath.SkipDir
+			}
+			for _, entry := range entries {
+				if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+				paths = append(paths, filepath.Join(dwarfDir, entry.Name()))
+			}
+			return filepath.SkipDir
+		})
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to search %s for dSYMs: %v", dir, err)
+		}
+	}
+
+	return paths, nil
+}
+
+// openDSYMForUUID opens the (thin or fat) DWARF file at path and returns the image with the given UUID (or nil)
+func openDSYMForUUID(path string, uuid types.UUID) (*File, error) {
+	if m, err := Open(path); err == nil {
+		if u := m.UUID(); u != nil && u.UUID == uuid {
+			return m, nil
+		}
+		m.Close()
+		return nil, nil
+	}
+	ff, err := OpenFat(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, arch := range ff.Arches {
+		if u := arch.UUID(); u != nil && u.UUID == uuid {
+			arch.File.closer = ff // closing the image closes the fat file
+			return arch.File, nil
+		}
+	}
+	ff.Close()
+	return nil, nil
+}
+
+// FindDSYM opens the dSYM DWARF file in the search directories with the UUID of the image
+// (the matching slice of a fat dSYM has the same UUID); the caller must Close it (unless it is paired with PairDSYM).
+func (f *File) FindDSYM(dirs ...string) (*File, error) {
+	u := f.UUID()
+	if u == nil || u.UUID.IsNull() {
+		return nil, fmt.Errorf("macho does not contain a %s load command", types.LC_UUID)
+	}
+	paths, err := FindDSYMs(dirs...)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		m, err := openDSYMForUUID(path, u.UUID)
+		if err != nil || m == nil {
+			continue
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("dSYM with UUID %s not found", u.UUID)
+}
+
+// PairDSYM finds the dSYM of the image in the search directories (see FindDSYM) and pairs it with the image.
+// The paired dSYM's debug information is returned as the image's own by DWARF, Symbolicate and the
+// Apple accelerator table methods; it is closed when the image is closed.
+func (f *File) PairDSYM(dirs ...string) (*File, error) {
+	m, err := f.FindDSYM(dirs...)
+	if err != nil {
+		return nil, err
+	}
+	f.pairDSYM(m)
+	return m, nil
+}
+
+// OpenDSYM pairs the dSYM DWARF file at path with the image (see PairDSYM) if its UUID matches.
+func (f *File) OpenDSYM(path string) (*File, error) {
+	u := f.UUID()
+	if u == nil || u.UUID.IsNull() {
+		return nil, fmt.Errorf("macho does not contain a %s load command", types.LC_UUID)
+	}
+	m, err := openDSYMForUUID(path, u.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dSYM %s: %v", path, err)
+	}
+	if m == nil {
+		return nil, fmt.Errorf("dSYM %s does not match UUID %s", path, u.UUID)
+	}
+	f.pairDSYM(m)
+	return m, nil
+}
+
+func (f *File) pairDSYM(m *File) {
+	f.dsymMu.Lock()
+	prev := f.dsym
+	f.dsym = m
+	// rebuild the Symbolicate index from the dSYM
+	f.dwarfIndex = nil
+	f.dsymMu.Unlock()
+	if prev != nil {
+		prev.Close()
+	}
+}
+
+// DSYM returns the paired dSYM (or nil); see PairDSYM.
+func (f *File) DSYM() *File {
+	f.dsymMu.Lock()
+	defer f.dsymMu.Unlock()
+	return f.dsym
+}
@@ -0,0 +1,205 @@
+package macho
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/blacktop/go-macho/internal/obscuretestdata"
+	"github.com/blacktop/go-macho/types"
+)
+
+// writeDSYM writes a dSYM bundle with the DWARF file data to dir
+func writeDSYM(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	dwarfDir := filepath.Join(dir, name+".dSYM", "Contents", "Resources", "DWARF")
+	if err := os.MkdirAll(dwarfDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dwarfDir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readDSYMTestData returns the gcc-amd64-darwin-exec-debug DWARF file with the UUID of the gcc-amd64-darwin-exec image
+func readDSYMTestData(t *testing.T) []byte {
+	t.Helper()
+	img, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer img.Close()
+	var dsym []byte
+	f, err := openPatchedTestFile(t, "internal/testdata/gcc-amd64-darwin-exec-debug.base64", func(f *File, data []byte) []byte {
+		u := f.UUID()
+		copy(data[loadCmdOffset(f, u)+8:], img.UUID().UUID[:])
+		dsym = data
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return dsym
+}
+
+func TestFindDSYM(t *testing.T) {
+	dir := t.TempDir()
+
+	other, err := obscuretestdata.ReadFile("internal/testdata/gcc-amd64-darwin-exec-debug.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeDSYM(t, dir, "other", other)
+	path := writeDSYM(t, dir, "exec", readDSYMTestData(t))
+
+	paths, err := FindDSYMs(dir, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Errorf("got %v, want 2 DWARF files", paths)
+	}
+
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := f.FindDSYM(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.UUID().UUID != f.UUID().UUID {
+		t.Errorf("got dSYM %s, want %s", m.UUID().UUID, f.UUID().UUID)
+	}
+	// the dSYM is returned open
+	if _, err := m.DWARF(); err != nil {
+		t.Errorf("failed to read the DWARF of the dSYM: %v", err)
+	}
+	m.Close()
+
+	if _, err := f.DWARF(); err == nil {
+		t.Fatal("expected an error for an image without DWARF")
+	}
+	m, err = f.PairDSYM(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.DSYM() != m {
+		t.Error("expected the dSYM to be paired")
+	}
+	if _, err := f.DWARF(); err != nil {
+		t.Errorf("failed to read the DWARF of the paired dSYM: %v", err)
+	}
+
+	if m, err := f.OpenDSYM(path); err != nil || f.DSYM() != m {
+		t.Errorf("failed to pair %s: %v", path, err)
+	}
+}
+
+func TestPairDSYMConcurrentSymbolicate(t *testing.T) {
+	path := writeDSYM(t, t.TempDir(), "exec", readDSYMTestData(t))
+
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	main, err := f.FindSymbolAddress("_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the index is rebuilt from each paired dSYM while other goroutines symbolicate
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				f.Symbolicate(main)
+			}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := f.OpenDSYM(path); err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Wait()
+
+	sym, err := f.Symbolicate(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sym.Function() != "main" {
+		t.Errorf("got %s, want main", sym.Function())
+	}
+}
+
+func TestFindDSYMFat(t *testing.T) {
+	dir := t.TempDir()
+	fat, err := obscuretestdata.ReadFile("internal/testdata/fat-gcc-386-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeDSYM(t, dir, "fat", fat)
+
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// the matching slice is returned
+	m, err := f.PairDSYM(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.CPU != types.CPUAmd64 || m.UUID().UUID != f.UUID().UUID {
+		t.Errorf("got the %s slice with UUID %s", m.CPU, m.UUID().UUID)
+	}
+}
+
+func TestFindDSYMMalformed(t *testing.T) {
+	dir := t.TempDir()
+	other, err := obscuretestdata.ReadFile("internal/testdata/gcc-amd64-darwin-exec-debug.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPath := writeDSYM(t, dir, "other", other)
+	writeDSYM(t, dir, "garbage", []byte("not a macho"))
+
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.FindDSYM(dir); err == nil {
+		t.Error("expected an error for a missing dSYM")
+	}
+	if _, err := f.PairDSYM(dir); err == nil || f.DSYM() != nil {
+		t.Error("expected an error (and no paired dSYM) for a missing dSYM")
+	}
+	if _, err := f.OpenDSYM(otherPath); err == nil {
+		t.Error("expected an error for a dSYM with another UUID")
+	}
+	if _, err := f.OpenDSYM(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing DWARF file")
+	}
+
+	obj, err := openObscured("internal/testdata/clang-amd64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+	if _, err := obj.FindDSYM(dir); err == nil {
+		t.Error("expected an error for an image without a UUID")
+	}
+}
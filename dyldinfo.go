@@ -0,0 +1,462 @@
+package macho
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/blacktop/go-macho/pkg/fixupchains"
+	"github.com/blacktop/go-macho/pkg/trie"
+	"github.com/blacktop/go-macho/types"
+)
+
+// A Rebase is a pointer slot that dyld slides using the LC_DYLD_INFO rebase opcodes.
+type Rebase struct {
+	Segment string
+	Section string
+	Offset  uint64 // offset of the slot from the start of its segment
+	Address uint64 // VM address of the slot
+	Type    types.RebaseType
+	// Threaded is set for the arm64e threaded rebases of BIND_OPCODE_THREADED; the slot holds an encoded target
+	Threaded bool
+}
+
+func (r Rebase) String() string {
+	return fmt.Sprintf("%-7s %-16s %#016x  %s", r.Segment, r.Section, r.Address, r.Type)
+}
+
+// Rebases returns every slot described by the LC_DYLD_INFO rebase opcode stream,
+// followed by the threaded rebases of arm64e binaries built before chained fixups.
+// The threaded rebases are walked by the bind opcodes; an error in the bind stream is
+// reported by Binds and only drops the threaded rebases that follow it.
+func (f *File) Rebases() ([]Rebase, error) {
+	dinfo := f.DyldInfo()
+	if dinfo == nil {
+		return nil, fmt.Errorf("macho does not contain LC_DYLD_INFO or LC_DYLD_INFO_ONLY")
+	}
+
+	rebases := []Rebase{}
+	if dinfo.RebaseSize > 0 {
+		data := make([]byte, dinfo.RebaseSize)
+		if _, err := f.lr.ReadAt(data, int64(dinfo.RebaseOff)); err != nil {
+			return nil, fmt.Errorf("failed to read rebase info at offset=%#x; %v", dinfo.RebaseOff, err)
+		}
+		var err error
+		if rebases, err = f.parseRebases(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	// the threaded rebases are walked by the BIND_SUBOPCODE_THREADED_APPLY bind opcodes
+	_, threaded, _ := f.readBinds(dinfo.BindOff, dinfo.BindSize, regularBind)
+
+	return append(rebases, threaded...), nil
+}
+
+func (f *File) parseRebases(r *bytes.Reader) ([]Rebase, error) {
+	var (
+		rebases []Rebase
+		seg     *Segment
+		segOff  uint64
+		rtype   types.RebaseType
+	)
+
+	segs := f.Segments()
+	ptrSize := f.pointerSize()
+
+	addRebase := func() error {
+		if seg == nil {
+			return fmt.Errorf("rebase opcode used before REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB")
+		}
+		if segOff >= seg.Memsz {
+			return fmt.Errorf("rebase offset %#x is beyond the end of segment %s", segOff, seg.Name)
+		}
+		rb := Rebase{
+			Segment: seg.Name,
+			Offset:  segOff,
+			Address: seg.Addr + segOff,
+			Type:    rtype,
+		}
+		if sec := f.FindSectionForVMAddr(rb.Address); sec != nil {
+			rb.Section = sec.Name
+		}
+		rebases = append(rebases, rb)
+		return nil
+	}
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil { // no REBASE_OPCODE_DONE at the end of the stream
+			break
+		}
+
+		imm := uint64(b & types.REBASE_IMMEDIATE_MASK)
+
+		switch b & types.REBASE_OPCODE_MASK {
+		case types.REBASE_OPCODE_DONE:
+			return rebases, nil
+		case types.REBASE_OPCODE_SET_TYPE_IMM:
+			rtype = types.RebaseType(imm)
+		case types.REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB:
+			if imm >= uint64(len(segs)) {
+				return nil, fmt.Errorf("rebase segment index %d out of range (%d segments)", imm, len(segs))
+			}
+			seg = segs[imm]
+			if segOff, err = trie.ReadUleb128(r); err != nil {
+				return nil, fmt.Errorf("failed to read REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB offset: %v", err)
+			}
+		case types.REBASE_OPCODE_ADD_ADDR_ULEB:
+			off, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read REBASE_OPCODE_ADD_ADDR_ULEB offset: %v", err)
+			}
+			segOff += off
+		case types.REBASE_OPCODE_ADD_ADDR_IMM_SCALED:
+			segOff += imm * ptrSize
+		case types.REBASE_OPCODE_DO_REBASE_IMM_TIMES:
+			for i := uint64(0); i < imm; i++ {
+				if err := addRebase(); err != nil {
+					return nil, err
+				}
+				segOff += ptrSize
+			}
+		case types.REBASE_OPCODE_DO_REBASE_ULEB_TIMES:
+			count, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read REBASE_OPCODE_DO_REBASE_ULEB_TIMES count: %v", err)
+			}
+			for i := uint64(0); i < count; i++ {
+				if err := addRebase(); err != nil {
+					return nil, err
+				}
+				segOff += ptrSize
+			}
+		case types.REBASE_OPCODE_DO_REBASE_ADD_ADDR_ULEB:
+			if err := addRebase(); err != nil {
+				return nil, err
+			}
+			off, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read REBASE_OPCODE_DO_REBASE_ADD_ADDR_ULEB offset: %v", err)
+			}
+			segOff += off + ptrSize
+		case types.REBASE_OPCODE_DO_REBASE_ULEB_TIMES_SKIPPING_ULEB:
+			count, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read REBASE_OPCODE_DO_REBASE_ULEB_TIMES_SKIPPING_ULEB count: %v", err)
+			}
+			skip, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read REBASE_OPCODE_DO_REBASE_ULEB_TIMES_SKIPPING_ULEB skip: %v", err)
+			}
+			for i := uint64(0); i < count; i++ {
+				if err := addRebase(); err != nil {
+					return nil, err
+				}
+				segOff += skip + ptrSize
+			}
+		default:
+			return nil, fmt.Errorf("unknown rebase opcode %#02x", b&types.REBASE_OPCODE_MASK)
+		}
+	}
+
+	return rebases, nil
+}
+
+// A Bind is a pointer slot that dyld binds to an imported symbol using the LC_DYLD_INFO bind opcodes.
+type Bind struct {
+	Name    string
+	Dylib   string
+	Ordinal int
+	Addend  int64
+	Flags   types.BindSymbolFlag
+	Type    types.BindType
+	Segment string
+	Section string
+	Offset  uint64 // offset of the slot from the start of its segment
+	Address uint64 // VM address of the slot
+}
+
+func (b Bind) String() string {
+	var addend string
+	if b.Addend != 0 {
+		addend = fmt.Sprintf(" + %#x", b.Addend)
+	}
+	var flags string
+	if b.Flags != 0 {
+		flags = fmt.Sprintf(" (%s)", b.Flags)
+	}
+	return fmt.Sprintf("%-7s %-16s %#016x  %-10s %-20s %s%s%s", b.Segment, b.Section, b.Address, b.Type, b.Dylib, b.Name, addend, flags)
+}
+
+type bindKind uint8
+
+const (
+	regularBind bindKind = iota
+	weakBind
+	lazyBind
+)
+
+// Binds returns every slot described by the LC_DYLD_INFO bind opcode stream.
+func (f *File) Binds() ([]Bind, error) {
+	dinfo := f.DyldInfo()
+	if dinfo == nil {
+		return nil, fmt.Errorf("macho does not contain LC_DYLD_INFO or LC_DYLD_INFO_ONLY")
+	}
+	binds, _, err := f.readBinds(dinfo.BindOff, dinfo.BindSize, regularBind)
+	return binds, err
+}
+
+// WeakBinds returns every slot described by the LC_DYLD_INFO weak bind opcode stream.
+// Strong definitions that override weak symbols are included with the NonWeakDefinition flag set.
+func (f *File) WeakBinds() ([]Bind, error) {
+	dinfo := f.DyldInfo()
+	if dinfo == nil {
+		return nil, fmt.Errorf("macho does not contain LC_DYLD_INFO or LC_DYLD_INFO_ONLY")
+	}
+	binds, _, err := f.readBinds(dinfo.WeakBindOff, dinfo.WeakBindSize, weakBind)
+	return binds, err
+}
+
+// LazyBinds returns every slot described by the LC_DYLD_INFO lazy bind opcode stream.
+func (f *File) LazyBinds() ([]Bind, error) {
+	dinfo := f.DyldInfo()
+	if dinfo == nil {
+		return nil, fmt.Errorf("macho does not contain LC_DYLD_INFO or LC_DYLD_INFO_ONLY")
+	}
+	binds, _, err := f.readBinds(dinfo.LazyBindOff, dinfo.LazyBindSize, lazyBind)
+	return binds, err
+}
+
+// readBinds parses a bind opcode stream, returning its binds and the threaded rebases it walks.
+// On a malformed stream the threaded rebases walked before the error are still returned.
+func (f *File) readBinds(offset, size uint32, kind bindKind) ([]Bind, []Rebase, error) {
+	if size == 0 {
+		return []Bind{}, nil, nil
+	}
+
+	data := make([]byte, size)
+	if _, err := f.lr.ReadAt(data, int64(offset)); err != nil {
+		return nil, nil, fmt.Errorf("failed to read bind info at offset=%#x; %v", offset, err)
+	}
+
+	return f.parseBinds(bytes.NewReader(data), kind)
+}
+
+func (f *File) parseBinds(r *bytes.Reader, kind bindKind) ([]Bind, []Rebase, error) {
+	var (
+		binds   []Bind
+		rebases []Rebase
+		seg     *Segment
+		segOff  uint64
+		current Bind
+		// arm64e binaries built before chained fixups use BIND_OPCODE_THREADED
+		threaded     bool
+		ordinalTable []Bind
+	)
+
+	segs := f.Segments()
+	ptrSize := f.pointerSize()
+
+	if kind != weakBind {
+		current.Type = types.BIND_TYPE_POINTER
+	}
+
+	addBind := func() error {
+		if threaded {
+			if len(ordinalTable) == cap(ordinalTable) {
+				return fmt.Errorf("threaded bind ordinal table overflows its %d entries", cap(ordinalTable))
+			}
+			ordinalTable = append(ordinalTable, current)
+			return nil
+		}
+		if seg == nil {
+			return fmt.Errorf("bind opcode used before BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB")
+		}
+		if segOff >= seg.Memsz {
+			return fmt.Errorf("bind offset %#x is beyond the end of segment %s", segOff, seg.Name)
+		}
+		bn := current
+		bn.Segment = seg.Name
+		bn.Offset = segOff
+		bn.Address = seg.Addr + segOff
+		if sec := f.FindSectionForVMAddr(bn.Address); sec != nil {
+			bn.Section = sec.Name
+		}
+		binds = append(binds, bn)
+		return nil
+	}
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil { // no BIND_OPCODE_DONE at the end of the stream
+			break
+		}
+
+		imm := b & types.BIND_IMMEDIATE_MASK
+
+		switch b & types.BIND_OPCODE_MASK {
+		case types.BIND_OPCODE_DONE:
+			// lazy bind entries are each terminated with BIND_OPCODE_DONE
+			if kind != lazyBind {
+				return binds, rebases, nil
+			}
+		case types.BIND_OPCODE_SET_DYLIB_ORDINAL_IMM:
+			current.Ordinal = int(imm)
+			current.Dylib = f.LibraryOrdinalName(current.Ordinal)
+		case types.BIND_OPCODE_SET_DYLIB_ORDINAL_ULEB:
+			ord, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, rebases, fmt.Errorf("failed to read BIND_OPCODE_SET_DYLIB_ORDINAL_ULEB ordinal: %v", err)
+			}
+			current.Ordinal = int(ord)
+			current.Dylib = f.LibraryOrdinalName(current.Ordinal)
+		case types.BIND_OPCODE_SET_DYLIB_SPECIAL_IMM:
+			// the special ordinals are negative numbers
+			if imm == 0 {
+				current.Ordinal = 0
+			} else {
+				current.Ordinal = int(int8(types.BIND_OPCODE_MASK | imm))
+			}
+			current.Dylib = f.LibraryOrdinalName(current.Ordinal)
+		case types.BIND_OPCODE_SET_SYMBOL_TRAILING_FLAGS_IMM:
+			current.Flags = types.BindSymbolFlag(imm)
+			var name []byte
+			for {
+				c, err := r.ReadByte()
+				if err != nil {
+					return nil, rebases, fmt.Errorf("failed to read BIND_OPCODE_SET_SYMBOL_TRAILING_FLAGS_IMM symbol name: %v", err)
+				}
+				if c == 0 {
+					break
+				}
+				name = append(name, c)
+			}
+			current.Name = string(name)
+			// a strong definition in the weak bind stream is not followed by a bind opcode
+			if kind == weakBind && current.Flags.NonWeakDefinition() {
+				binds = append(binds, Bind{Name: current.Name, Flags: current.Flags})
+			}
+		case types.BIND_OPCODE_SET_TYPE_IMM:
+			current.Type = types.BindType(imm)
+		case types.BIND_OPCODE_SET_ADDEND_SLEB:
+			if current.Addend, err = trie.ReadSleb128(r); err != nil {
+				return nil, rebases, fmt.Errorf("failed to read BIND_OPCODE_SET_ADDEND_SLEB addend: %v", err)
+			}
+		case types.BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB:
+			if int(imm) >= len(segs) {
+				return nil, rebases, fmt.Errorf("bind segment index %d out of range (%d segments)", imm, len(segs))
+			}
+			seg = segs[imm]
+			if segOff, err = trie.ReadUleb128(r); err != nil {
+				return nil, rebases, fmt.Errorf("failed to read BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB offset: %v", err)
+			}
+		case types.BIND_OPCODE_ADD_ADDR_ULEB:
+			off, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, rebases, fmt.Errorf("failed to read BIND_OPCODE_ADD_ADDR_ULEB offset: %v", err)
+			}
+			segOff += off
+		case types.BIND_OPCODE_DO_BIND:
+			if err := addBind(); err != nil {
+				return nil, rebases, err
+			}
+			if !threaded {
+				segOff += ptrSize
+			}
+		case types.BIND_OPCODE_DO_BIND_ADD_ADDR_ULEB:
+			if err := addBind(); err != nil {
+				return nil, rebases, err
+			}
+			off, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, rebases, fmt.Errorf("failed to read BIND_OPCODE_DO_BIND_ADD_ADDR_ULEB offset: %v", err)
+			}
+			segOff += off + ptrSize
+		case types.BIND_OPCODE_DO_BIND_ADD_ADDR_IMM_SCALED:
+			if err := addBind(); err != nil {
+				return nil, rebases, err
+			}
+			segOff += uint64(imm)*ptrSize + ptrSize
+		case types.BIND_OPCODE_DO_BIND_ULEB_TIMES_SKIPPING_ULEB:
+			count, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, rebases, fmt.Errorf("failed to read BIND_OPCODE_DO_BIND_ULEB_TIMES_SKIPPING_ULEB count: %v", err)
+			}
+			skip, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, rebases, fmt.Errorf("failed to read BIND_OPCODE_DO_BIND_ULEB_TIMES_SKIPPING_ULEB skip: %v", err)
+			}
+			for i := uint64(0); i < count; i++ {
+				if err := addBind(); err != nil {
+					return nil, rebases, err
+				}
+				segOff += skip + ptrSize
+			}
+		case types.BIND_OPCODE_THREADED:
+			switch imm {
+			case types.BIND_SUBOPCODE_THREADED_SET_BIND_ORDINAL_TABLE_SIZE_ULEB:
+				count, err := trie.ReadUleb128(r)
+				if err != nil {
+					return nil, rebases, fmt.Errorf("failed to read BIND_SUBOPCODE_THREADED_SET_BIND_ORDINAL_TABLE_SIZE_ULEB count: %v", err)
+				}
+				if count > uint64(r.Len()) { // every entry takes at least one bind opcode
+					return nil, rebases, fmt.Errorf("threaded bind ordinal table size %d is larger than the bind opcodes", count)
+				}
+				ordinalTable = make([]Bind, 0, count)
+				threaded = true
+			case types.BIND_SUBOPCODE_THREADED_APPLY:
+				if seg == nil {
+					return nil, rebases, fmt.Errorf("bind opcode used before BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB")
+				}
+				// walk the chain of threaded pointers, binding each one that references the ordinal table
+				// and rebasing the others
+				for {
+					if segOff >= seg.Memsz {
+						return nil, rebases, fmt.Errorf("threaded pointer offset %#x is beyond the end of segment %s", segOff, seg.Name)
+					}
+					ptr, err := f.readLeUint64(int64(seg.Offset + segOff))
+					if err != nil {
+						return nil, rebases, fmt.Errorf("failed to read threaded pointer at %s+%#x: %v", seg.Name, segOff, err)
+					}
+					if fixupchains.DcpArm64eIsBind(ptr) {
+						ordinal := int(types.ExtractBits(ptr, 0, 16))
+						if ordinal >= len(ordinalTable) {
+							return nil, rebases, fmt.Errorf("threaded bind ordinal %d out of range (%d entries)", ordinal, len(ordinalTable))
+						}
+						bn := ordinalTable[ordinal]
+						bn.Segment = seg.Name
+						bn.Offset = segOff
+						bn.Address = seg.Addr + segOff
+						if sec := f.FindSectionForVMAddr(bn.Address); sec != nil {
+							bn.Section = sec.Name
+						}
+						binds = append(binds, bn)
+					} else {
+						rb := Rebase{
+							Segment:  seg.Name,
+							Offset:   segOff,
+							Address:  seg.Addr + segOff,
+							Type:     types.REBASE_TYPE_POINTER,
+							Threaded: true,
+						}
+						if sec := f.FindSectionForVMAddr(rb.Address); sec != nil {
+							rb.Section = sec.Name
+						}
+						rebases = append(rebases, rb)
+					}
+					delta := fixupchains.DcpArm64eNext(ptr)
+					if delta == 0 {
+						break
+					}
+					segOff += delta * 8
+				}
+			default:
+				return nil, rebases, fmt.Errorf("unknown threaded bind subopcode %#02x", imm)
+			}
+		default:
+			return nil, rebases, fmt.Errorf("unknown bind opcode %#02x", b&types.BIND_OPCODE_MASK)
+		}
+	}
+
+	return binds, rebases, nil
+}
@@ -0,0 +1,385 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/internal/obscuretestdata"
+	"github.com/blacktop/go-macho/types"
+)
+
+const dyldInfoTestFile = "internal/testdata/clang-amd64-darwin-exec-with-rpath.base64"
+
+func TestRebases(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rebases, err := f.Rebases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rebase{
+		{Segment: "__DATA", Section: "__la_symbol_ptr", Offset: 0x10, Address: 0x100001010, Type: types.REBASE_TYPE_POINTER},
+	}
+	if len(rebases) != len(want) {
+		t.Fatalf("got %d rebases, want %d", len(rebases), len(want))
+	}
+	for i := range want {
+		if rebases[i] != want[i] {
+			t.Errorf("rebase %d: got %+v, want %+v", i, rebases[i], want[i])
+		}
+	}
+}
+
+func TestParseRebases(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		name    string
+		opcodes []byte
+		addrs   []uint64
+		wantErr bool
+	}{
+		{
+			name: "imm times",
+			opcodes: []byte{
+				types.REBASE_OPCODE_SET_TYPE_IMM | types.REBASE_TYPE_POINTER,
+				types.REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x10,
+				types.REBASE_OPCODE_DO_REBASE_IMM_TIMES | 2,
+				types.REBASE_OPCODE_DONE,
+			},
+			addrs: []uint64{0x100001010, 0x100001018},
+		},
+		{
+			name: "uleb times skipping uleb",
+			opcodes: []byte{
+				types.REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x00,
+				types.REBASE_OPCODE_DO_REBASE_ULEB_TIMES_SKIPPING_ULEB, 0x03, 0x08,
+				types.REBASE_OPCODE_DONE,
+			},
+			addrs: []uint64{0x100001000, 0x100001010, 0x100001020},
+		},
+		{
+			name: "add addr",
+			opcodes: []byte{
+				types.REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x00,
+				types.REBASE_OPCODE_ADD_ADDR_IMM_SCALED | 1,
+				types.REBASE_OPCODE_DO_REBASE_ADD_ADDR_ULEB, 0x80, 0x01,
+				types.REBASE_OPCODE_ADD_ADDR_ULEB, 0x08,
+				types.REBASE_OPCODE_DO_REBASE_ULEB_TIMES, 0x01,
+			}, // no REBASE_OPCODE_DONE
+			addrs: []uint64{0x100001008, 0x100001098},
+		},
+		{
+			name:    "rebase before set segment",
+			opcodes: []byte{types.REBASE_OPCODE_DO_REBASE_IMM_TIMES | 1},
+			wantErr: true,
+		},
+		{
+			name:    "segment out of range",
+			opcodes: []byte{types.REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 9, 0x00},
+			wantErr: true,
+		},
+		{
+			name:    "truncated uleb",
+			opcodes: []byte{types.REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x80},
+			wantErr: true,
+		},
+		{
+			name: "offset beyond segment",
+			opcodes: []byte{
+				types.REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x80, 0x80, 0x04,
+				types.REBASE_OPCODE_DO_REBASE_IMM_TIMES | 1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "huge count",
+			opcodes: []byte{
+				types.REBASE_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x00,
+				types.REBASE_OPCODE_DO_REBASE_ULEB_TIMES, 0xff, 0xff, 0xff, 0xff, 0x0f,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown opcode",
+			opcodes: []byte{0x90},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rebases, err := f.parseRebases(bytes.NewReader(tt.opcodes))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d rebases", len(rebases))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rebases) != len(tt.addrs) {
+				t.Fatalf("got %d rebases, want %d", len(rebases), len(tt.addrs))
+			}
+			for i, addr := range tt.addrs {
+				if rebases[i].Address != addr || rebases[i].Segment != "__DATA" {
+					t.Errorf("rebase %d: got %s %#x, want __DATA %#x", i, rebases[i].Segment, rebases[i].Address, addr)
+				}
+			}
+		})
+	}
+}
+
+// openPatchedTestFile opens an obscured test file after letting patch modify (or extend) its bytes
+func openPatchedTestFile(t *testing.T, name string, patch func(f *File, data []byte) []byte) (*File, error) {
+	t.Helper()
+	data, err := obscuretestdata.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig, err := NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = patch(orig, data)
+	return NewFile(bytes.NewReader(data))
+}
+
+// loadCmdOffset returns the file offset of a load command
+func loadCmdOffset(f *File, cmd Load) int {
+	off := types.FileHeaderSize64
+	if f.Magic == types.Magic32 {
+		off = types.FileHeaderSize32
+	}
+	for _, l := range f.Loads {
+		if l == cmd {
+			return off
+		}
+		off += len(l.Raw())
+	}
+	return -1
+}
+
+func TestParseBinds(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	binds, err := f.Binds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(binds) != 1 || binds[0].Name != "dyld_stub_binder" || binds[0].Address != 0x100001000 || binds[0].Dylib != "libSystem.B.dylib" {
+		t.Errorf("unexpected binds %v", binds)
+	}
+
+	tests := []struct {
+		name    string
+		kind    bindKind
+		opcodes []byte
+		addrs   []uint64
+		wantErr bool
+	}{
+		{
+			name: "uleb times skipping uleb",
+			opcodes: []byte{
+				types.BIND_OPCODE_SET_DYLIB_ORDINAL_IMM | 1,
+				types.BIND_OPCODE_SET_SYMBOL_TRAILING_FLAGS_IMM, '_', 'a', 0,
+				types.BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x00,
+				types.BIND_OPCODE_DO_BIND_ULEB_TIMES_SKIPPING_ULEB, 0x02, 0x08,
+				types.BIND_OPCODE_DO_BIND_ADD_ADDR_IMM_SCALED | 1,
+				types.BIND_OPCODE_DONE,
+			},
+			addrs: []uint64{0x100001000, 0x100001010, 0x100001020},
+		},
+		{
+			name: "lazy entries",
+			kind: lazyBind,
+			opcodes: []byte{
+				types.BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x10,
+				types.BIND_OPCODE_SET_DYLIB_SPECIAL_IMM | 0x0e, // flat lookup (-2)
+				types.BIND_OPCODE_SET_SYMBOL_TRAILING_FLAGS_IMM, '_', 'b', 0,
+				types.BIND_OPCODE_DO_BIND,
+				types.BIND_OPCODE_DONE,
+				types.BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x18,
+				types.BIND_OPCODE_DO_BIND,
+				types.BIND_OPCODE_DONE,
+			},
+			addrs: []uint64{0x100001010, 0x100001018},
+		},
+		{
+			name:    "bind before set segment",
+			opcodes: []byte{types.BIND_OPCODE_DO_BIND},
+			wantErr: true,
+		},
+		{
+			name:    "unterminated symbol name",
+			opcodes: []byte{types.BIND_OPCODE_SET_SYMBOL_TRAILING_FLAGS_IMM, '_', 'c'},
+			wantErr: true,
+		},
+		{
+			name:    "truncated addend",
+			opcodes: []byte{types.BIND_OPCODE_SET_ADDEND_SLEB, 0xff},
+			wantErr: true,
+		},
+		{
+			name: "huge count",
+			opcodes: []byte{
+				types.BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x00,
+				types.BIND_OPCODE_DO_BIND_ULEB_TIMES_SKIPPING_ULEB, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x00,
+			},
+			wantErr: true,
+		},
+		{
+			name: "huge threaded ordinal table",
+			opcodes: []byte{
+				types.BIND_OPCODE_THREADED | types.BIND_SUBOPCODE_THREADED_SET_BIND_ORDINAL_TABLE_SIZE_ULEB, 0xff, 0xff, 0xff, 0xff, 0x0f,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown threaded subopcode",
+			opcodes: []byte{types.BIND_OPCODE_THREADED | 0x0f},
+			wantErr: true,
+		},
+		{
+			name:    "unknown opcode",
+			opcodes: []byte{0xe0},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binds, _, err := f.parseBinds(bytes.NewReader(tt.opcodes), tt.kind)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d binds", len(binds))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(binds) != len(tt.addrs) {
+				t.Fatalf("got %d binds, want %d", len(binds), len(tt.addrs))
+			}
+			for i, addr := range tt.addrs {
+				if binds[i].Address != addr {
+					t.Errorf("bind %d: got %#x, want %#x", i, binds[i].Address, addr)
+				}
+			}
+		})
+	}
+}
+
+const (
+	threadedBindSlot   = 0x100001000
+	threadedRebaseSlot = 0x100001008
+)
+
+// openThreadedBindTestFile returns the dyld info test file with a threaded bind followed by a threaded rebase
+// at the start of __DATA, walked by the given bind opcodes
+func openThreadedBindTestFile(t *testing.T, opcodes []byte) (*File, error) {
+	t.Helper()
+	return openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		dinfo := f.DyldInfo()
+		if int(dinfo.BindSize) < len(opcodes) {
+			t.Fatalf("bind opcodes do not fit in %d bytes", dinfo.BindSize)
+		}
+		copy(data[dinfo.BindOff:dinfo.BindOff+dinfo.BindSize], make([]byte, dinfo.BindSize))
+		copy(data[dinfo.BindOff:], opcodes)
+		dataSeg := f.Segment("__DATA")
+		// a bind of ordinal 0 followed (next=1) by an authenticated rebase to __TEXT+0xfa0
+		binary.LittleEndian.PutUint64(data[dataSeg.Offset:], 1<<62|1<<51)
+		binary.LittleEndian.PutUint64(data[dataSeg.Offset+8:], 1<<63|0xfa0)
+		return data
+	})
+}
+
+var threadedBindOpcodes = []byte{
+	types.BIND_OPCODE_THREADED | types.BIND_SUBOPCODE_THREADED_SET_BIND_ORDINAL_TABLE_SIZE_ULEB, 0x01,
+	types.BIND_OPCODE_SET_DYLIB_ORDINAL_IMM | 1,
+	types.BIND_OPCODE_SET_SYMBOL_TRAILING_FLAGS_IMM, '_', 'x', 0,
+	types.BIND_OPCODE_DO_BIND,
+	types.BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | 2, 0x00,
+	types.BIND_OPCODE_THREADED | types.BIND_SUBOPCODE_THREADED_APPLY,
+	types.BIND_OPCODE_DONE,
+}
+
+func TestThreadedBinds(t *testing.T) {
+	f, err := openThreadedBindTestFile(t, threadedBindOpcodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	binds, err := f.Binds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(binds) != 1 || binds[0].Name != "_x" || binds[0].Address != threadedBindSlot {
+		t.Fatalf("unexpected threaded binds %v", binds)
+	}
+
+	rebases, err := f.Rebases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var threaded []Rebase
+	for _, r := range rebases {
+		if r.Threaded {
+			threaded = append(threaded, r)
+		}
+	}
+	if len(threaded) != 1 || threaded[0].Address != threadedRebaseSlot {
+		t.Fatalf("unexpected threaded rebases %v", threaded)
+	}
+
+	ptr, err := f.ReadPointer(threadedRebaseSlot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr.IsBind() || ptr.Target != 0x100000fa0 || ptr.Auth == nil {
+		t.Errorf("unexpected threaded rebase pointer %s", ptr)
+	}
+	if ptr, err = f.ReadPointer(threadedBindSlot); err != nil {
+		t.Fatal(err)
+	} else if !ptr.IsBind() || ptr.Name != "_x" {
+		t.Errorf("unexpected threaded bind pointer %s", ptr)
+	}
+}
+
+func TestThreadedRebasesMalformedBinds(t *testing.T) {
+	// an unknown opcode after the threaded chain
+	opcodes := append([]byte(nil), threadedBindOpcodes...)
+	opcodes[len(opcodes)-1] = 0xe0
+	f, err := openThreadedBindTestFile(t, opcodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.Binds(); err == nil {
+		t.Error("expected an error for an unknown bind opcode")
+	}
+	rebases, err := f.Rebases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rebases) == 0 || !rebases[len(rebases)-1].Threaded || rebases[len(rebases)-1].Address != threadedRebaseSlot {
+		t.Errorf("got %v, want the threaded rebase walked before the error", rebases)
+	}
+}
@@ -0,0 +1,738 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacktop/go-macho/pkg/trie"
+	"github.com/blacktop/go-macho/types"
+)
+
+// A CIE is a DWARF Common Information Entry from the __TEXT,__eh_frame section.
+type CIE struct {
+	Offset                uint64 // section offset of the entry
+	Length                uint64
+	Version               uint8
+	Augmentation          string
+	AugmentationData      []byte
+	CodeAlignmentFactor   uint64
+	DataAlignmentFactor   int64
+	ReturnAddressRegister uint64
+	PointerEncoding       types.EhPointerEncoding // 'R' FDE pointer encoding
+	LSDAEncoding          types.EhPointerEncoding // 'L' LSDA pointer encoding
+	PersonalityEncoding   types.EhPointerEncoding // 'P' personality pointer encoding
+	Personality           uint64                  // address of the personality routine (or of its pointer if the encoding is indirect)
+	PersonalityName       string                  // symbol name of the personality routine (if known)
+	IsSignalFrame         bool                    // 'S'
+	InitialInstructions   []byte
+
+	initialOnce sync.Once
+	initialRow  *CFIRow
+	initialErr  error
+	frame       *EHFrame
+}
+
+// An FDE is a DWARF Frame Description Entry from the __TEXT,__eh_frame section.
+type FDE struct {
+	Offset           uint64 // section offset of the entry
+	Length           uint64
+	CIE              *CIE
+	PCBegin          uint64
+	PCRange          uint64
+	LSDA             uint64 // address of the language specific data area (0 if none)
+	AugmentationData []byte
+	Instructions     []byte
+
+	instrAddr uint64 // address of the instructions (for pcrel DW_CFA_set_loc)
+	rowsOnce  sync.Once
+	rows      []CFIRow
+	rowsErr   error
+}
+
+// Contains reports whether the FDE covers the given address.
+func (fde *FDE) Contains(addr uint64) bool {
+	return fde.PCBegin <= addr && addr < fde.PCBegin+fde.PCRange
+}
+
+func (fde *FDE) String() string {
+	var lsda string
+	if fde.LSDA != 0 {
+		lsda = fmt.Sprintf(" lsda=%#x", fde.LSDA)
+	}
+	return fmt.Sprintf("%08x FDE cie=%08x pc=%#016x...%#016x%s", fde.Offset, fde.CIE.Offset, fde.PCBegin, fde.PCBegin+fde.PCRange, lsda)
+}
+
+// A CFARule is how to compute the Canonical Frame Address.
+type CFARule struct {
+	Register   uint64
+	Offset     int64
+	Expression []byte // if set, the CFA is computed by this DWARF expression instead
+}
+
+func (c CFARule) String() string {
+	if c.Expression != nil {
+		return fmt.Sprintf("CFA=expr(% x)", c.Expression)
+	}
+	if c.Offset == 0 {
+		return fmt.Sprintf("CFA=reg%d", c.Register)
+	}
+	return fmt.Sprintf("CFA=reg%d%+d", c.Register, c.Offset)
+}
+
+// A RegisterRule is how to recover a register's value in the caller's frame.
+type RegisterRule struct {
+	Kind       types.RegisterRuleKind
+	Offset     int64
+	Register   uint64
+	Expression []byte
+}
+
+func (r RegisterRule) String() string {
+	switch r.Kind {
+	case types.RuleOffset:
+		return fmt.Sprintf("[CFA%+d]", r.Offset)
+	case types.RuleValOffset:
+		return fmt.Sprintf("CFA%+d", r.Offset)
+	case types.RuleRegister:
+		return fmt.Sprintf("reg%d", r.Register)
+	case types.RuleExpression:
+		return fmt.Sprintf("[expr(% x)]", r.Expression)
+	case types.RuleValExpression:
+		return fmt.Sprintf("expr(% x)", r.Expression)
+	default:
+		return r.Kind.String()
+	}
+}
+
+// A CFIRow is a row of the call frame information table; the rules for unwinding from the address Loc
+// up to the Loc of the next row (or the end of the FDE).
+type CFIRow struct {
+	Loc       uint64
+	CFA       CFARule
+	Registers map[uint64]RegisterRule
+}
+
+func (r CFIRow) String() string {
+	regs := make([]uint64, 0, len(r.Registers))
+	for reg := range r.Registers {
+		regs = append(regs, reg)
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i] < regs[j] })
+	var rules []string
+	for _, reg := range regs {
+		rules = append(rules, fmt.Sprintf("reg%d=%s", reg, r.Registers[reg]))
+	}
+	if len(rules) == 0 {
+		return fmt.Sprintf("%#x: %s", r.Loc, r.CFA)
+	}
+	return fmt.Sprintf("%#x: %s: %s", r.Loc, r.CFA, strings.Join(rules, ", "))
+}
+
+func (r CFIRow) clone() CFIRow {
+	c := r
+	c.Registers = make(map[uint64]RegisterRule, len(r.Registers))
+	for reg, rule := range r.Registers {
+		c.Registers[reg] = rule
+	}
+	return c
+}
+
+// EHFrame is the parsed __TEXT,__eh_frame section.
+type EHFrame struct {
+	Addr uint64 // address of the section
+	CIEs []*CIE
+	FDEs []*FDE // sorted by PCBegin
+
+	bo       binary.ByteOrder
+	ptrSize  uint64
+	byOffset map[uint64]*FDE
+}
+
+// Lookup returns the FDE covering the given address.
+func (e *EHFrame) Lookup(addr uint64) (*FDE, error) {
+	idx := sort.Search(len(e.FDEs), func(i int) bool {
+		return e.FDEs[i].PCBegin > addr
+	}) - 1
+	if idx < 0 || !e.FDEs[idx].Contains(addr) {
+		return nil, fmt.Errorf("address %#016x not covered by any FDE", addr)
+	}
+	return e.FDEs[idx], nil
+}
+
+// FDEAtOffset returns the FDE at the given section offset (i.e. the offset in a compact unwind DWARF mode encoding).
+func (e *EHFrame) FDEAtOffset(offset uint64) (*FDE, error) {
+	if fde, ok := e.byOffset[offset]; ok {
+		return fde, nil
+	}
+	return nil, fmt.Errorf("no FDE at __eh_frame offset %#x", offset)
+}
+
+// EHFrame parses the __TEXT,__eh_frame section.
+func (f *File) EHFrame() (*EHFrame, error) {
+	sec := f.Section("__TEXT", "__eh_frame")
+	if sec == nil {
+		return nil, fmt.Errorf("macho does not contain a __TEXT,__eh_frame section")
+	}
+	dat, err := sec.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read __TEXT,__eh_frame data: %v", err)
+	}
+
+	eh := &EHFrame{
+		Addr:     sec.Addr,
+		bo:       f.ByteOrder,
+		ptrSize:  f.pointerSize(),
+		byOffset: make(map[uint64]*FDE),
+	}
+
+	// objects store pointers as relocations (i.e. SUBTRACTOR pairs) rather than their final values
+	relocAt := make(map[uint64]Relocation)
+	if f.Type == types.Obj {
+		relocs, err := sec.DecodedRelocations()
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range relocs {
+			relocAt[uint64(rel.Offset)] = rel
+		}
+	}
+
+	// readPointer reads a pointer with the given encoding at the reader's offset
+	readPointer := func(r *bytes.Reader, enc types.EhPointerEncoding) (uint64, string, error) {
+		off := uint64(len(dat) - r.Len())
+		val, err := eh.readEncoded(r, enc)
+		if err != nil {
+			return 0, "", err
+		}
+		var name string
+		if rel, ok := relocAt[off]; ok {
+			val, name = f.ehRelocValue(rel, val)
+		}
+		if enc.Application() == types.DW_EH_PE_pcrel {
+			if len(name) > 0 && val == 0 {
+				return 0, name, nil // unresolved import
+			}
+			val += sec.Addr + off
+		}
+		return val, name, nil
+	}
+
+	cies := make(map[uint64]*CIE)
+
+	r := bytes.NewReader(dat)
+	for r.Len() > 0 {
+		start := uint64(len(dat) - r.Len())
+
+		var length32 uint32
+		if err := binary.Read(r, f.ByteOrder, &length32); err != nil {
+			return nil, fmt.Errorf("failed to read __eh_frame entry length at offset %#x: %v", start, err)
+		}
+		if length32 == 0 { // terminator
+			break
+		}
+		length := uint64(length32)
+		if length32 == 0xffffffff {
+			if err := binary.Read(r, f.ByteOrder, &length); err != nil {
+				return nil, fmt.Errorf("failed to read __eh_frame entry 64-bit length at offset %#x: %v", start, err)
+			}
+		}
+
+		idOffset := uint64(len(dat) - r.Len())
+		if length > uint64(len(dat))-idOffset {
+			return nil, fmt.Errorf("__eh_frame entry at offset %#x extends past the end of the section", start)
+		}
+		end := idOffset + length
+
+		var id uint32
+		if err := binary.Read(r, f.ByteOrder, &id); err != nil {
+			return nil, fmt.Errorf("failed to read __eh_frame entry id at offset %#x: %v", start, err)
+		}
+
+		if id == 0 {
+			cie, err := eh.parseCIE(r, start, length, end, readPointer)
+			if err != nil {
+				return nil, err
+			}
+			if len(cie.PersonalityName) == 0 && cie.Personality != 0 && cie.PersonalityEncoding.IsIndirect() && f.Type != types.Obj {
+				cie.PersonalityName = f.unwindPersonalityName(cie.Personality)
+			}
+			cies[start] = cie
+			eh.CIEs = append(eh.CIEs, cie)
+		} else {
+			// the CIE pointer is the offset back to the CIE from the CIE pointer field
+			cieOffset := idOffset - uint64(id)
+			cie, ok := cies[cieOffset]
+			if !ok {
+				return nil, fmt.Errorf("__eh_frame FDE at offset %#x references unknown CIE at offset %#x", start, cieOffset)
+			}
+			fde := &FDE{
+				Offset: start,
+				Length: length,
+				CIE:    cie,
+			}
+			if fde.PCBegin, _, err = readPointer(r, cie.PointerEncoding); err != nil {
+				return nil, fmt.Errorf("failed to read FDE at offset %#x pc begin: %v", start, err)
+			}
+			// the range is the same size as the start address, but is never relative
+			if fde.PCRange, err = eh.readEncoded(r, cie.PointerEncoding.Format()); err != nil {
+				return nil, fmt.Errorf("failed to read FDE at offset %#x pc range: %v", start, err)
+			}
+			if strings.HasPrefix(cie.Augmentation, "z") {
+				augLen, err := trie.ReadUleb128(r)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read FDE at offset %#x augmentation length: %v", start, err)
+				}
+				augStart := uint64(len(dat) - r.Len())
+				if augStart > end || augLen > end-augStart {
+					return nil, fmt.Errorf("FDE at offset %#x augmentation data extends past the end of the entry", start)
+				}
+				fde.AugmentationData = dat[augStart : augStart+augLen]
+				if cie.LSDAEncoding != types.DW_EH_PE_omit && strings.Contains(cie.Augmentation, "L") {
+					if fde.LSDA, _, err = readPointer(r, cie.LSDAEncoding); err != nil {
+						return nil, fmt.Errorf("failed to read FDE at offset %#x LSDA: %v", start, err)
+					}
+				}
+				if _, err := r.Seek(int64(augStart+augLen), io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+			instrStart := uint64(len(dat) - r.Len())
+			if instrStart > end {
+				return nil, fmt.Errorf("FDE at offset %#x extends past the end of the entry", start)
+			}
+			fde.Instructions = dat[instrStart:end]
+			fde.instrAddr = sec.Addr + instrStart
+			eh.FDEs = append(eh.FDEs, fde)
+			eh.byOffset[start] = fde
+		}
+
+		if _, err := r.Seek(int64(end), io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek to __eh_frame offset %#x: %v", end, err)
+		}
+	}
+
+	sort.SliceStable(eh.FDEs, func(i, j int) bool {
+		return eh.FDEs[i].PCBegin < eh.FDEs[j].PCBegin
+	})
+
+	return eh, nil
+}
+
+func (e *EHFrame) parseCIE(r *bytes.Reader, start, length, end uint64, readPointer func(*bytes.Reader, types.EhPointerEncoding) (uint64, string, error)) (*CIE, error) {
+	var err error
+
+	cie := &CIE{
+		Offset:              start,
+		Length:              length,
+		PointerEncoding:     types.DW_EH_PE_absptr,
+		LSDAEncoding:        types.DW_EH_PE_omit,
+		PersonalityEncoding: types.DW_EH_PE_omit,
+		frame:               e,
+	}
+
+	if cie.Version, err = r.ReadByte(); err != nil {
+		return nil, fmt.Errorf("failed to read CIE at offset %#x version: %v", start, err)
+	}
+	if cie.Version != 1 && cie.Version != 3 {
+		return nil, fmt.Errorf("unsupported CIE version %d at offset %#x", cie.Version, start)
+	}
+	var aug []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CIE at offset %#x augmentation: %v", start, err)
+		}
+		if c == 0 {
+			break
+		}
+		aug = append(aug, c)
+	}
+	cie.Augmentation = string(aug)
+	if len(cie.Augmentation) > 0 && !strings.HasPrefix(cie.Augmentation, "z") {
+		return nil, fmt.Errorf("unsupported CIE augmentation %q at offset %#x", cie.Augmentation, start)
+	}
+	if cie.CodeAlignmentFactor, err = trie.ReadUleb128(r); err != nil {
+		return nil, fmt.Errorf("failed to read CIE at offset %#x code alignment factor: %v", start, err)
+	}
+	if cie.DataAlignmentFactor, err = trie.ReadSleb128(r); err != nil {
+		return nil, fmt.Errorf("failed to read CIE at offset %#x data alignment factor: %v", start, err)
+	}
+	if cie.Version == 1 {
+		ra, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CIE at offset %#x return address register: %v", start, err)
+		}
+		cie.ReturnAddressRegister = uint64(ra)
+	} else if cie.ReturnAddressRegister, err = trie.ReadUleb128(r); err != nil {
+		return nil, fmt.Errorf("failed to read CIE at offset %#x return address register: %v", start, err)
+	}
+
+	if len(cie.Augmentation) > 0 {
+		augLen, err := trie.ReadUleb128(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CIE at offset %#x augmentation length: %v", start, err)
+		}
+		augStart := int64(r.Size()) - int64(r.Len())
+		if uint64(augStart) > end || augLen > end-uint64(augStart) {
+			return nil, fmt.Errorf("CIE at offset %#x augmentation data extends past the end of the entry", start)
+		}
+		cie.AugmentationData = make([]byte, augLen)
+		if _, err := r.ReadAt(cie.AugmentationData, augStart); err != nil {
+			return nil, fmt.Errorf("failed to read CIE at offset %#x augmentation data: %v", start, err)
+		}
+	augmentation:
+		for _, c := range cie.Augmentation[1:] {
+			switch c {
+			case 'L':
+				enc, err := r.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read CIE at offset %#x LSDA encoding: %v", start, err)
+				}
+				cie.LSDAEncoding = types.EhPointerEncoding(enc)
+			case 'P':
+				enc, err := r.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read CIE at offset %#x personality encoding: %v", start, err)
+				}
+				cie.PersonalityEncoding = types.EhPointerEncoding(enc)
+				if cie.Personality, cie.PersonalityName, err = readPointer(r, cie.PersonalityEncoding); err != nil {
+					return nil, fmt.Errorf("failed to read CIE at offset %#x personality: %v", start, err)
+				}
+			case 'R':
+				enc, err := r.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read CIE at offset %#x pointer encoding: %v", start, err)
+				}
+				cie.PointerEncoding = types.EhPointerEncoding(enc)
+			case 'S':
+				cie.IsSignalFrame = true
+			default:
+				// the rest of the augmentation data is skipped using the augmentation length
+				break augmentation
+			}
+		}
+		if _, err := r.Seek(augStart+int64(augLen), io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	instrStart := int64(r.Size()) - int64(r.Len())
+	if uint64(instrStart) > end {
+		return nil, fmt.Errorf("CIE at offset %#x extends past the end of the entry", start)
+	}
+	cie.InitialInstructions = make([]byte, int64(end)-instrStart)
+	if _, err := r.ReadAt(cie.InitialInstructions, instrStart); err != nil {
+		return nil, fmt.Errorf("failed to read CIE at offset %#x initial instructions: %v", start, err)
+	}
+
+	return cie, nil
+}
+
+// readEncoded reads a value in the given encoding's format (the application is left to the caller)
+func (e *EHFrame) readEncoded(r *bytes.Reader, enc types.EhPointerEncoding) (uint64, error) {
+	if enc == types.DW_EH_PE_omit {
+		return 0, nil
+	}
+	var err error
+	switch enc.Format() {
+	case types.DW_EH_PE_absptr:
+		if e.ptrSize == 8 {
+			var v uint64
+			err = binary.Read(r, e.bo, &v)
+			return v, err
+		}
+		var v uint32
+		err = binary.Read(r, e.bo, &v)
+		return uint64(v), err
+	case types.DW_EH_PE_uleb128:
+		return trie.ReadUleb128(r)
+	case types.DW_EH_PE_sleb128:
+		v, err := trie.ReadSleb128(r)
+		return uint64(v), err
+	case types.DW_EH_PE_udata2:
+		var v uint16
+		err = binary.Read(r, e.bo, &v)
+		return uint64(v), err
+	case types.DW_EH_PE_sdata2:
+		var v int16
+		err = binary.Read(r, e.bo, &v)
+		return uint64(v), err
+	case types.DW_EH_PE_udata4:
+		var v uint32
+		err = binary.Read(r, e.bo, &v)
+		return uint64(v), err
+	case types.DW_EH_PE_sdata4:
+		var v int32
+		err = binary.Read(r, e.bo, &v)
+		return uint64(v), err
+	case types.DW_EH_PE_udata8, types.DW_EH_PE_sdata8:
+		var v uint64
+		err = binary.Read(r, e.bo, &v)
+		return v, err
+	default:
+		return 0, fmt.Errorf("unsupported pointer encoding %s", enc)
+	}
+}
+
+// ehRelocValue returns the value the static linker would store for an __eh_frame relocation in an object
+// (along with the symbol name if the target is an imported symbol)
+func (f *File) ehRelocValue(rel Relocation, content uint64) (uint64, string) {
+	symAddr := func(t RelocTarget) (uint64, bool) {
+		if !t.Extern {
+			return t.Addr, true
+		}
+		if sym := f.Symtab.Syms[t.SymIndex]; sym.Type.IsDefinedInSection() || sym.Type.IsAbsoluteSym() {
+			return sym.Value, true
+		}
+		return 0, false
+	}
+	if rel.Subtrahend != nil {
+		if !rel.Target.Extern {
+			return content, "" // the content already holds target - subtrahend
+		}
+		target, ok := symAddr(rel.Target)
+		minus, ok2 := symAddr(*rel.Subtrahend)
+		if !ok || !ok2 {
+			return 0, rel.Target.Symbol
+		}
+		return uint64(int64(target) + rel.Addend - int64(minus)), ""
+	}
+	if !rel.Target.Extern {
+		return content, ""
+	}
+	target, ok := symAddr(rel.Target)
+	if !ok {
+		return 0, rel.Target.Symbol
+	}
+	return uint64(int64(target) + rel.Addend), rel.Target.Symbol
+}
+
+// InitialRow returns the row the CIE's initial instructions produce (the rules every FDE starts with).
+func (cie *CIE) InitialRow() (*CFIRow, error) {
+	cie.initialOnce.Do(func() {
+		row := CFIRow{Registers: make(map[uint64]RegisterRule)}
+		if _, err := cie.frame.execute(cie.InitialInstructions, cie, &row, nil, 0); err != nil {
+			cie.initialErr = fmt.Errorf("failed to execute CIE at offset %#x initial instructions: %v", cie.Offset, err)
+			return
+		}
+		cie.initialRow = &row
+	})
+	return cie.initialRow, cie.initialErr
+}
+
+// Rows evaluates the FDE's call frame instructions into its table of rules; one row per address range.
+//
+// NOTE: the rows are evaluated once and shared by the following calls (and RowForAddr), so they must not be modified.
+func (fde *FDE) Rows() ([]CFIRow, error) {
+	fde.rowsOnce.Do(func() {
+		initial, err := fde.CIE.InitialRow()
+		if err != nil {
+			fde.rowsErr = err
+			return
+		}
+		row := initial.clone()
+		row.Loc = fde.PCBegin
+		rows, err := fde.CIE.frame.execute(fde.Instructions, fde.CIE, &row, initial, fde.instrAddr)
+		if err != nil {
+			fde.rowsErr = fmt.Errorf("failed to execute FDE at offset %#x instructions: %v", fde.Offset, err)
+			return
+		}
+		fde.rows = append(rows, row)
+	})
+	return fde.rows, fde.rowsErr
+}
+
+// RowForAddr returns the rules for unwinding from the given address.
+func (fde *FDE) RowForAddr(addr uint64) (*CFIRow, error) {
+	if !fde.Contains(addr) {
+		return nil, fmt.Errorf("address %#016x not covered by FDE at offset %#x", addr, fde.Offset)
+	}
+	rows, err := fde.Rows()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(rows) - 1; i >= 0; i-- {
+		if rows[i].Loc <= addr {
+			return &rows[i], nil
+		}
+	}
+	return &rows[0], nil
+}
+
+// execute runs call frame instructions against row, returning the rows completed by each advance.
+// initial is the CIE's initial row used by the restore instructions (nil while running the CIE's instructions).
+func (e *EHFrame) execute(instrs []byte, cie *CIE, row *CFIRow, initial *CFIRow, instrAddr uint64) ([]CFIRow, error) {
+	var rows []CFIRow
+	var stack []CFIRow
+
+	r := bytes.NewReader(instrs)
+
+	// the operand readers record the first error, which is checked after each instruction
+	var err error
+	uleb := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = trie.ReadUleb128(r)
+		return v
+	}
+	sleb := func() int64 {
+		if err != nil {
+			return 0
+		}
+		var v int64
+		v, err = trie.ReadSleb128(r)
+		return v
+	}
+	block := func() []byte {
+		n := uleb()
+		if err != nil {
+			return nil
+		}
+		if n > uint64(r.Len()) {
+			err = fmt.Errorf("block length %d exceeds the remaining %d bytes", n, r.Len())
+			return nil
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b
+	}
+	advance := func(delta uint64) {
+		rows = append(rows, row.clone())
+		row.Loc += delta * cie.CodeAlignmentFactor
+	}
+	restore := func(reg uint64) {
+		if initial != nil {
+			if rule, ok := initial.Registers[reg]; ok {
+				row.Registers[reg] = rule
+				return
+			}
+		}
+		delete(row.Registers, reg)
+	}
+
+	for r.Len() > 0 {
+		offset := len(instrs) - r.Len()
+		b, _ := r.ReadByte()
+		op := types.CFAOp(b)
+
+		switch op & types.DW_CFA_high_2_bits_mask {
+		case types.DW_CFA_advance_loc:
+			advance(uint64(op & types.DW_CFA_low_6_bits_mask))
+			continue
+		case types.DW_CFA_offset:
+			rule := RegisterRule{Kind: types.RuleOffset, Offset: int64(uleb()) * cie.DataAlignmentFactor}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read call frame instruction %#x at offset %#x: %v", uint8(op), offset, err)
+			}
+			row.Registers[uint64(op&types.DW_CFA_low_6_bits_mask)] = rule
+			continue
+		case types.DW_CFA_restore:
+			restore(uint64(op & types.DW_CFA_low_6_bits_mask))
+			continue
+		}
+
+		switch op {
+		case types.DW_CFA_nop:
+		case types.DW_CFA_set_loc:
+			off := uint64(len(instrs) - r.Len())
+			loc, err := e.readEncoded(r, cie.PointerEncoding)
+			if err != nil {
+				return nil, err
+			}
+			if cie.PointerEncoding.Application() == types.DW_EH_PE_pcrel {
+				loc += instrAddr + off
+			}
+			rows = append(rows, row.clone())
+			row.Loc = loc
+		case types.DW_CFA_advance_loc1:
+			var delta byte
+			if delta, err = r.ReadByte(); err == nil {
+				advance(uint64(delta))
+			}
+		case types.DW_CFA_advance_loc2:
+			var delta uint16
+			if err := binary.Read(r, e.bo, &delta); err != nil {
+				return nil, err
+			}
+			advance(uint64(delta))
+		case types.DW_CFA_advance_loc4:
+			var delta uint32
+			if err := binary.Read(r, e.bo, &delta); err != nil {
+				return nil, err
+			}
+			advance(uint64(delta))
+		case types.DW_CFA_offset_extended:
+			reg := uleb()
+			row.Registers[reg] = RegisterRule{Kind: types.RuleOffset, Offset: int64(uleb()) * cie.DataAlignmentFactor}
+		case types.DW_CFA_restore_extended:
+			restore(uleb())
+		case types.DW_CFA_undefined:
+			row.Registers[uleb()] = RegisterRule{Kind: types.RuleUndefined}
+		case types.DW_CFA_same_value:
+			row.Registers[uleb()] = RegisterRule{Kind: types.RuleSameValue}
+		case types.DW_CFA_register:
+			reg := uleb()
+			row.Registers[reg] = RegisterRule{Kind: types.RuleRegister, Register: uleb()}
+		case types.DW_CFA_remember_state:
+			stack = append(stack, row.clone())
+		case types.DW_CFA_restore_state:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("DW_CFA_restore_state without DW_CFA_remember_state")
+			}
+			loc := row.Loc
+			*row = stack[len(stack)-1]
+			row.Loc = loc
+			stack = stack[:len(stack)-1]
+		case types.DW_CFA_def_cfa:
+			row.CFA = CFARule{Register: uleb(), Offset: int64(uleb())}
+		case types.DW_CFA_def_cfa_sf:
+			reg := uleb()
+			row.CFA = CFARule{Register: reg, Offset: sleb() * cie.DataAlignmentFactor}
+		case types.DW_CFA_def_cfa_register:
+			row.CFA.Register = uleb()
+			row.CFA.Expression = nil
+		case types.DW_CFA_def_cfa_offset:
+			row.CFA.Offset = int64(uleb())
+		case types.DW_CFA_def_cfa_offset_sf:
+			row.CFA.Offset = sleb() * cie.DataAlignmentFactor
+		case types.DW_CFA_def_cfa_expression:
+			row.CFA = CFARule{Expression: block()}
+		case types.DW_CFA_expression:
+			reg := uleb()
+			row.Registers[reg] = RegisterRule{Kind: types.RuleExpression, Expression: block()}
+		case types.DW_CFA_val_expression:
+			reg := uleb()
+			row.Registers[reg] = RegisterRule{Kind: types.RuleValExpression, Expression: block()}
+		case types.DW_CFA_offset_extended_sf:
+			reg := uleb()
+			row.Registers[reg] = RegisterRule{Kind: types.RuleOffset, Offset: sleb() * cie.DataAlignmentFactor}
+		case types.DW_CFA_val_offset:
+			reg := uleb()
+			row.Registers[reg] = RegisterRule{Kind: types.RuleValOffset, Offset: int64(uleb()) * cie.DataAlignmentFactor}
+		case types.DW_CFA_val_offset_sf:
+			reg := uleb()
+			row.Registers[reg] = RegisterRule{Kind: types.RuleValOffset, Offset: sleb() * cie.DataAlignmentFactor}
+		case types.DW_CFA_GNU_args_size:
+			uleb()
+		case types.DW_CFA_GNU_negative_offset_extended:
+			reg := uleb()
+			row.Registers[reg] = RegisterRule{Kind: types.RuleOffset, Offset: -int64(uleb()) * cie.DataAlignmentFactor}
+		case types.DW_CFA_GNU_window_save:
+			// DW_CFA_AARCH64_negate_ra_state toggles return address signing, which doesn't change any rules
+		default:
+			return nil, fmt.Errorf("unknown call frame instruction %#x", uint8(op))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read call frame instruction %#x at offset %#x: %v", uint8(op), offset, err)
+		}
+	}
+
+	return rows, nil
+}
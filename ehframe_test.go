@@ -0,0 +1,158 @@
+package macho
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestEHFrame(t *testing.T) {
+	f, err := openObscured("internal/testdata/ehframe-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	eh, err := f.EHFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eh.CIEs) != 2 || len(eh.FDEs) != 2 {
+		t.Fatalf("got %d CIEs and %d FDEs, want 2 of each", len(eh.CIEs), len(eh.FDEs))
+	}
+
+	fde, err := eh.Lookup(0x8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fde.PCBegin != 0 || fde.PCRange != 0x14 || fde.LSDA != 0x18 {
+		t.Errorf("got %s, want _f's FDE with its LSDA at 0x18", fde)
+	}
+	if cie := fde.CIE; cie.Augmentation != "zPLR" || cie.PersonalityName != "___gxx_personality_v0" {
+		t.Errorf("got CIE augmentation %q and personality %q", cie.Augmentation, cie.PersonalityName)
+	}
+	if byOff, err := eh.FDEAtOffset(fde.Offset); err != nil || byOff != fde {
+		t.Errorf("got %v (%v) at offset %#x", byOff, err, fde.Offset)
+	}
+
+	rows, err := fde.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"0x0: CFA=reg31",
+		"0x4: CFA=reg31+16",
+		"0x8: CFA=reg29+16: reg29=[CFA-16], reg30=[CFA-8]",
+		"0xc: CFA=reg29+16: reg29=[CFA-16], reg30=[CFA-8]", // restored state
+		"0x10: CFA=reg31: reg29=[CFA-16], reg30=[CFA-8]",
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		if row.String() != want[i] {
+			t.Errorf("row %d: got %s, want %s", i, row, want[i])
+		}
+	}
+
+	row, err := fde.RowForAddr(0xa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row != &rows[2] {
+		t.Errorf("got %s for 0xa, want the cached row %s", row, rows[2])
+	}
+	if _, err := fde.RowForAddr(0x14); err == nil {
+		t.Error("expected an error for an address outside of the FDE")
+	}
+
+	g, err := eh.Lookup(0x14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row, err := g.RowForAddr(0x14); err != nil {
+		t.Fatal(err)
+	} else if s := row.String(); s != "0x14: CFA=reg31+32: reg19=[CFA-24], reg20=undefined, reg21=reg22" {
+		t.Errorf("got %s for _g", s)
+	}
+
+	if _, err := eh.Lookup(0x18); err == nil {
+		t.Error("expected an error for an address outside of any FDE")
+	}
+}
+
+func TestEHFrameMalformed(t *testing.T) {
+	// section offsets of the clang-amd64-darwin.obj __eh_frame fields
+	const (
+		cieVersion = 8
+		cieAugLen  = 15
+		fdeCIEPtr  = 0x1c
+		fdeAugLen  = 0x30
+	)
+	tests := []struct {
+		name  string
+		patch func(eh []byte)
+	}{
+		{name: "entry length", patch: func(eh []byte) { binary.LittleEndian.PutUint32(eh, 0xfffffff0) }},
+		{name: "64-bit entry length", patch: func(eh []byte) {
+			binary.LittleEndian.PutUint32(eh, 0xffffffff)
+			binary.LittleEndian.PutUint64(eh[4:], 0xffffffffffffffff)
+		}},
+		{name: "CIE version", patch: func(eh []byte) { eh[cieVersion] = 2 }},
+		{name: "CIE augmentation length", patch: func(eh []byte) { eh[cieAugLen] = 0x7f }},
+		{name: "FDE augmentation length", patch: func(eh []byte) { eh[fdeAugLen] = 0x7f }},
+		{name: "FDE CIE pointer", patch: func(eh []byte) { binary.LittleEndian.PutUint32(eh[fdeCIEPtr:], 0x100) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := openPatchedTestFile(t, "internal/testdata/clang-amd64-darwin.obj.base64", func(f *File, data []byte) []byte {
+				tt.patch(data[f.Section("__TEXT", "__eh_frame").Offset:])
+				return data
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err := f.EHFrame(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestEHFrameInstructionsMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		instrs []byte
+	}{
+		{name: "truncated uleb", instrs: []byte{0x0e, 0x80, 0x80}},                       // DW_CFA_def_cfa_offset
+		{name: "truncated sleb", instrs: []byte{0x13, 0xff}},                             // DW_CFA_def_cfa_offset_sf
+		{name: "truncated register", instrs: []byte{0x0c}},                               // DW_CFA_def_cfa
+		{name: "truncated offset", instrs: []byte{0x86}},                                 // DW_CFA_offset
+		{name: "truncated advance", instrs: []byte{0x02}},                                // DW_CFA_advance_loc1
+		{name: "block length", instrs: []byte{0x0f, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x00}}, // DW_CFA_def_cfa_expression
+		{name: "restore state", instrs: []byte{0x0b}},                                    // DW_CFA_restore_state
+		{name: "unknown", instrs: []byte{0x3f}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := openObscured("internal/testdata/clang-amd64-darwin.obj.base64")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			eh, err := f.EHFrame()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fde := eh.FDEs[0]
+			fde.Instructions = tt.instrs
+			if _, err := fde.Rows(); err == nil {
+				t.Error("expected an error")
+			}
+			if _, err := fde.RowForAddr(fde.PCBegin); err == nil {
+				t.Error("expected the error to be cached")
+			}
+		})
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"unsafe"
 
 	"github.com/blacktop/go-macho/pkg/codesign"
@@ -42,6 +44,37 @@ type File struct {
 
 	relativeSelectorBase uint64 // objc_opt version 16
 
+	fixups []Fixup
+
+	unwindEntries []UnwindEntry // sorted unwind entries (see GetUnwindEntryForAddr)
+
+	dataInCodeOnce sync.Once
+	dataInCode     []DataInCodeRange // sorted data in code ranges (see GetDataInCode)
+	dataInCodeErr  error
+
+	lohOnce sync.Once
+	lohs    []types.LinkerOptimizationHint // decoded linker optimization hints (see GetLinkerOptimizationHints)
+	lohErr  error
+
+	fixupOnce     sync.Once
+	fixupIndex    map[uint64]int // fixup address to index in fixups (see ReadPointer)
+	fixupIndexErr error
+
+	vmMu  sync.Mutex
+	vmIdx *vmIndex // sorted segments and sections (see FindSegmentForVMAddr)
+
+	functionsMu sync.Mutex // guards FileTOC.functions (see GetFunctions)
+
+	symOnce  sync.Once
+	symIndex *symbolIndex // symtab, exports and function starts index (see SymbolForAddress)
+
+	symtabOnce  sync.Once
+	symtabIndex *symtabIndex // symtab entries by name and by value (see FindSymbolAddress)
+
+	dsymMu     sync.Mutex // guards dsym and dwarfIndex, which are replaced when a dSYM is paired
+	dsym       *File      // paired dSYM (see PairDSYM)
+	dwarfIndex *dwarfIndexOnce
+
 	closer io.Closer
 }
 
@@ -353,6 +386,7 @@ func (f *File) Export(path string, dcf *fixupchains.DyldChainedFixups, baseAddre
 				return fmt.Errorf("failed to remap offset in segment %s: %v", seg.Name, err)
 			}
 			seg.Offset = off
+			f.invalidateVMIndex(nil) // the segment file offsets changed
 
 			if err := seg.Write(&buf, f.ByteOrder); err != nil {
 				return err
@@ -394,36 +428,46 @@ func (f *File) Export(path string, dcf *fixupchains.DyldChainedFixups, baseAddre
 				return err
 			}
 		case types.LC_DYSYMTAB:
-			// tocoffset, err := segMap.Remap(uint64(l.(*Dysymtab).Tocoffset))
-			// if err != nil {
-			// 	return fmt.Errorf("failed to remap Tocoffset in %s: %v", types.LC_DYSYMTAB, err)
-			// }
-			// l.(*Dysymtab).Tocoffset = uint32(tocoffset)
-			// modtaboff, err := segMap.Remap(uint64(l.(*Dysymtab).Modtaboff))
-			// if err != nil {
-			// 	return fmt.Errorf("failed to remap Modtaboff in %s: %v", types.LC_DYSYMTAB, err)
-			// }
-			// l.(*Dysymtab).Modtaboff = uint32(modtaboff)
-			// extrefsymoff, err := segMap.Remap(uint64(l.(*Dysymtab).Extrefsymoff))
-			// if err != nil {
-			// 	return fmt.Errorf("failed to remap Extrefsymoff %s: %v", types.LC_DYSYMTAB, err)
-			// }
-			// l.(*Dysymtab).Extrefsymoff = uint32(extrefsymoff)
+			if l.(*Dysymtab).Ntoc > 0 {
+				tocoffset, err := segMap.Remap(uint64(l.(*Dysymtab).Tocoffset))
+				if err != nil {
+					return fmt.Errorf("failed to remap Tocoffset in %s: %v", types.LC_DYSYMTAB, err)
+				}
+				l.(*Dysymtab).Tocoffset = uint32(tocoffset)
+			}
+			if l.(*Dysymtab).Nmodtab > 0 {
+				modtaboff, err := segMap.Remap(uint64(l.(*Dysymtab).Modtaboff))
+				if err != nil {
+					return fmt.Errorf("failed to remap Modtaboff in %s: %v", types.LC_DYSYMTAB, err)
+				}
+				l.(*Dysymtab).Modtaboff = uint32(modtaboff)
+			}
+			if l.(*Dysymtab).Nextrefsyms > 0 {
+				extrefsymoff, err := segMap.Remap(uint64(l.(*Dysymtab).Extrefsymoff))
+				if err != nil {
+					return fmt.Errorf("failed to remap Extrefsymoff %s: %v", types.LC_DYSYMTAB, err)
+				}
+				l.(*Dysymtab).Extrefsymoff = uint32(extrefsymoff)
+			}
 			indirectsymoff, err := segMap.Remap(uint64(l.(*Dysymtab).Indirectsymoff))
 			if err != nil {
 				return fmt.Errorf("failed to remap Indirectsymoff in %s: %v", types.LC_DYSYMTAB, err)
 			}
 			l.(*Dysymtab).Indirectsymoff = uint32(indirectsymoff)
-			// extreloff, err := segMap.Remap(uint64(l.(*Dysymtab).Extreloff))
-			// if err != nil {
-			// 	return fmt.Errorf("failed to remap Extreloff in %s: %v", types.LC_DYSYMTAB, err)
-			// }
-			// l.(*Dysymtab).Extreloff = uint32(extreloff)
-			// locreloff, err := segMap.Remap(uint64(l.(*Dysymtab).Locreloff))
-			// if err != nil {
-			// 	return fmt.Errorf("failed to remap Locreloff in %s: %v", types.LC_DYSYMTAB, err)
-			// }
-			// l.(*Dysymtab).Locreloff = uint32(locreloff)
+			if l.(*Dysymtab).Nextrel > 0 {
+				extreloff, err := segMap.Remap(uint64(l.(*Dysymtab).Extreloff))
+				if err != nil {
+					return fmt.Errorf("failed to remap Extreloff in %s: %v", types.LC_DYSYMTAB, err)
+				}
+				l.(*Dysymtab).Extreloff = uint32(extreloff)
+			}
+			if l.(*Dysymtab).Nlocrel > 0 {
+				locreloff, err := segMap.Remap(uint64(l.(*Dysymtab).Locreloff))
+				if err != nil {
+					return fmt.Errorf("failed to remap Locreloff in %s: %v", types.LC_DYSYMTAB, err)
+				}
+				l.(*Dysymtab).Locreloff = uint32(locreloff)
+			}
 
 			if err := l.(*Dysymtab).Write(&buf, f.ByteOrder); err != nil {
 				return err
@@ -719,6 +763,15 @@ func (f *File) Close() error {
 		err = f.closer.Close()
 		f.closer = nil
 	}
+	f.dsymMu.Lock()
+	dsym := f.dsym
+	f.dsym = nil
+	f.dsymMu.Unlock()
+	if dsym != nil {
+		if derr := dsym.Close(); err == nil {
+			err = derr
+		}
+	}
 	return err
 }
 
@@ -944,18 +997,20 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			l.Size = led.Size
 			f.Loads[i] = l
 		case types.LC_THREAD:
-			var t types.Thread
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &t); err != nil {
-				return nil, fmt.Errorf("failed to read LC_THREAD: %v", err)
-			}
 			l := new(Thread)
 			l.LoadBytes = cmddat
 			l.LoadCmd = cmd
 			l.Len = siz
-			l.Type = t.Type
-			l.Data = make([]uint32, t.Len-3*uint32(binary.Size(uint32(0)))/uint32(binary.Size(uint32(0))))
-			if err := binary.Read(b, bo, &l.Data); err != nil {
+			threads, err := parseThreadStates(f.CPU, bo, cmddat[8:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read LC_THREAD: %v", err)
+			}
+			l.Threads = threads
+			if len(l.Threads) > 0 {
+				l.Type = uint32(l.Threads[0].Flavor)
+			}
+			l.Data = make([]uint32, (len(cmddat)-8)/4)
+			if err := binary.Read(bytes.NewReader(cmddat[8:]), bo, &l.Data); err != nil {
 				return nil, fmt.Errorf("failed to read Thread data: %v", err)
 			}
 			f.Loads[i] = l
@@ -969,14 +1024,18 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			l.LoadBytes = cmddat
 			l.LoadCmd = cmd
 			l.Len = siz
-			// TODO: handle all flavors
-			if ut.Flavor == 6 {
-				regs := make([]uint64, ut.Count/2)
-				if err := binary.Read(b, bo, &regs); err != nil {
-					return nil, fmt.Errorf("failed to read UnixThread registers: %v", err)
+			l.Flavor = ut.Flavor
+			l.Count = ut.Count
+			threads, err := parseThreadStates(f.CPU, bo, cmddat[8:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read LC_UNIXTHREAD: %v", err)
+			}
+			l.Threads = threads
+			for _, state := range l.Threads {
+				if pc, ok := state.PC(); ok {
+					l.EntryPoint = pc
+					break
 				}
-				// this is to get the program counter register
-				l.EntryPoint = regs[len(regs)-2]
 			}
 			f.Loads[i] = l
 		case types.LC_LOADFVMLIB:
@@ -1069,6 +1128,101 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			st.Len = siz
 			st.DysymtabCmd = hdr
 			st.IndirectSyms = x
+			// the tables are only needed by classic images, so one that can't be read is left empty (see Dysymtab.TablesErr)
+			tableErr := func(err error) {
+				if st.tablesErr == nil {
+					st.tablesErr = err
+				}
+			}
+			if hdr.Ntoc > 0 {
+				sr, err := f.readTable(hdr.Tocoffset, hdr.Ntoc, 8)
+				if err == nil {
+					st.TableOfContents = make([]types.DylibTableOfContents, hdr.Ntoc)
+					err = binary.Read(sr, bo, st.TableOfContents)
+				}
+				if err != nil {
+					st.TableOfContents = nil
+					tableErr(fmt.Errorf("failed to read table of contents at Tocoffset=%#x; %v", hdr.Tocoffset, err))
+				}
+			}
+			if hdr.Nmodtab > 0 {
+				if f.Magic == types.Magic64 {
+					sr, err := f.readTable(hdr.Modtaboff, hdr.Nmodtab, binary.Size(types.DylibModule64{}))
+					if err == nil {
+						st.Modules = make([]types.DylibModule64, hdr.Nmodtab)
+						err = binary.Read(sr, bo, st.Modules)
+					}
+					if err != nil {
+						st.Modules = nil
+						tableErr(fmt.Errorf("failed to read module table at Modtaboff=%#x; %v", hdr.Modtaboff, err))
+					}
+				} else {
+					var mods []types.DylibModule
+					sr, err := f.readTable(hdr.Modtaboff, hdr.Nmodtab, binary.Size(types.DylibModule{}))
+					if err == nil {
+						mods = make([]types.DylibModule, hdr.Nmodtab)
+						err = binary.Read(sr, bo, mods)
+					}
+					if err != nil {
+						mods = nil
+						tableErr(fmt.Errorf("failed to read module table at Modtaboff=%#x; %v", hdr.Modtaboff, err))
+					}
+					for _, m := range mods {
+						st.Modules = append(st.Modules, types.DylibModule64{
+							ModuleName:         m.ModuleName,
+							Iextdefsym:         m.Iextdefsym,
+							Nextdefsym:         m.Nextdefsym,
+							Irefsym:            m.Irefsym,
+							Nrefsym:            m.Nrefsym,
+							Ilocalsym:          m.Ilocalsym,
+							Nlocalsym:          m.Nlocalsym,
+							Iextrel:            m.Iextrel,
+							Nextrel:            m.Nextrel,
+							IinitIterm:         m.IinitIterm,
+							NinitNterm:         m.NinitNterm,
+							ObjcModuleInfoSize: m.ObjcModuleInfoSize,
+							ObjcModuleInfoAddr: uint64(m.ObjcModuleInfoAddr),
+						})
+					}
+				}
+			}
+			if hdr.Nextrefsyms > 0 {
+				var refs []uint32
+				sr, err := f.readTable(hdr.Extrefsymoff, hdr.Nextrefsyms, 4)
+				if err == nil {
+					refs = make([]uint32, hdr.Nextrefsyms)
+					err = binary.Read(sr, bo, refs)
+				}
+				if err != nil {
+					refs = nil
+					tableErr(fmt.Errorf("failed to read external references at Extrefsymoff=%#x; %v", hdr.Extrefsymoff, err))
+				}
+				for _, ref := range refs {
+					if bo == binary.BigEndian {
+						st.ExtRefSyms = append(st.ExtRefSyms, types.DylibReference{SymIndex: ref >> 8, Flags: types.NDescType(ref & 0xff)})
+					} else {
+						st.ExtRefSyms = append(st.ExtRefSyms, types.DylibReference{SymIndex: ref & (1<<24 - 1), Flags: types.NDescType(ref >> 24)})
+					}
+				}
+			}
+			if hdr.Nextrel > 0 {
+				sr, err := f.readTable(hdr.Extreloff, hdr.Nextrel, 8)
+				if err == nil {
+					st.ExtRelocs, err = readRelocs(sr, bo, hdr.Nextrel)
+				}
+				if err != nil {
+					tableErr(fmt.Errorf("failed to read external relocations at Extreloff=%#x; %v", hdr.Extreloff, err))
+				}
+			}
+			if hdr.Nlocrel > 0 {
+				sr, err := f.readTable(hdr.Locreloff, hdr.Nlocrel, 8)
+				if err == nil {
+					st.LocRelocs, err = readRelocs(sr, bo, hdr.Nlocrel)
+				}
+				if err != nil {
+					tableErr(fmt.Errorf("failed to read local relocations at Locreloff=%#x; %v", hdr.Locreloff, err))
+				}
+			}
 			f.Loads[i] = st
 			f.Dysymtab = st
 		case types.LC_LOAD_DYLIB:
@@ -1300,6 +1454,7 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			l.LoadBytes = cmddat
 			l.LoadCmd = cmd
 			l.Len = siz
+			l.UUID = u.UUID
 			l.ID = u.UUID.String()
 			f.Loads[i] = l
 		case types.LC_RPATH:
@@ -1353,29 +1508,14 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			l.Len = siz
 			l.Offset = hdr.Offset
 			l.Size = hdr.Size
-			ldat := make([]byte, l.Size)
-			if _, err := f.lr.ReadAt(ldat, int64(l.Offset)); err != nil {
-				return nil, fmt.Errorf("failed to read SplitInfo data at offset=%#x; %v", int64(hdr.Offset), err)
-			}
-			fsr := bytes.NewReader(ldat)
-			if err := binary.Read(fsr, bo, &l.Version); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO Version: %v", err)
-			}
-			// var offset uint64
-			// for {
-			// 	o, err := trie.ReadUleb128(fsr)
-			// 	if err == io.EOF {
-			// 		break
-			// 	}
-			// 	if err != nil {
-			// 		return nil, err
-			// 	}
-			// 	// if o == 0 {
-			// 	// 	break
-			// 	// }
-			// 	offset += o
-			// 	l.Offsets = append(l.Offsets, offset)
-			// }
+			if l.Size > 0 {
+				// the references themselves are decoded by SplitInfoRefs
+				var version [1]byte
+				if _, err := f.lr.ReadAt(version[:], int64(l.Offset)); err != nil {
+					return nil, fmt.Errorf("failed to read SplitInfo data at offset=%#x; %v", int64(hdr.Offset), err)
+				}
+				l.Version = version[0]
+			}
 			f.Loads[i] = l
 		case types.LC_REEXPORT_DYLIB:
 			var hdr types.ReExportDylibCmd
@@ -1477,6 +1617,7 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			}
 			l := new(VersionMinMacOSX)
 			l.LoadBytes = cmddat
+			l.VersionMinMacOSCmd = verMin
 			l.LoadCmd = cmd
 			l.Len = siz
 			l.Version = verMin.Version.String()
@@ -1490,6 +1631,7 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			}
 			l := new(VersionMiniPhoneOS)
 			l.LoadBytes = cmddat
+			l.VersionMinIPhoneOSCmd = verMin
 			l.LoadCmd = cmd
 			l.Len = siz
 			l.Version = verMin.Version.String()
@@ -1548,8 +1690,6 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			l.LoadBytes = cmddat
 			l.LoadCmd = cmd
 			l.Len = siz
-			// TODO: finish parsing Dice entries
-			// var e DataInCodeEntry
 			l.Offset = led.Offset
 			l.Size = led.Size
 			f.Loads[i] = l
@@ -1633,6 +1773,7 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			}
 			l := new(VersionMinTvOS)
 			l.LoadBytes = cmddat
+			l.VersionMinIPhoneOSCmd = types.VersionMinIPhoneOSCmd(verMin)
 			l.LoadCmd = cmd
 			l.Len = siz
 			l.Version = verMin.Version.String()
@@ -1646,6 +1787,7 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			}
 			l := new(VersionMinWatchOS)
 			l.LoadBytes = cmddat
+			l.VersionMinIPhoneOSCmd = types.VersionMinIPhoneOSCmd(verMin)
 			l.LoadCmd = cmd
 			l.Len = siz
 			l.Version = verMin.Version.String()
@@ -1661,32 +1803,35 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			l.LoadBytes = cmddat
 			l.LoadCmd = cmd
 			l.Len = siz
-			l.DataOwner = string(n.DataOwner[:])
+			l.DataOwner = strings.TrimRight(string(n.DataOwner[:]), "\x00")
 			l.Offset = n.Offset
 			l.Size = n.Size
 			f.Loads[i] = l
 		case types.LC_BUILD_VERSION:
 			var build types.BuildVersionCmd
-			var buildTool types.BuildToolVersion
 			b := bytes.NewReader(cmddat)
 			if err := binary.Read(b, bo, &build); err != nil {
 				return nil, fmt.Errorf("failed to read LC_BUILD_VERSION: %v", err)
 			}
 			l := new(BuildVersion)
 			l.LoadBytes = cmddat
+			l.BuildVersionCmd = build
 			l.LoadCmd = cmd
 			l.Len = siz
 			l.Platform = build.Platform.String()
 			l.Minos = build.Minos.String()
 			l.Sdk = build.Sdk.String()
 			l.NumTools = build.NumTools
-			// TODO: handle more than one tool case
+			if uint64(build.NumTools)*uint64(binary.Size(types.BuildToolVersion{})) > uint64(b.Len()) {
+				return nil, fmt.Errorf("invalid LC_BUILD_VERSION ntools=%d for cmdsize=%d", build.NumTools, siz)
+			}
+			l.Tools = make([]types.BuildToolVersion, build.NumTools)
+			if err := binary.Read(b, bo, &l.Tools); err != nil {
+				return nil, fmt.Errorf("failed to read LC_BUILD_VERSION build tools: %v", err)
+			}
 			if build.NumTools > 0 {
-				if err := binary.Read(b, bo, &buildTool); err != nil {
-					return nil, fmt.Errorf("failed to read LC_BUILD_VERSION buildTool: %v", err)
-				}
-				l.Tool = buildTool.Tool.String()
-				l.ToolVersion = buildTool.Version.String()
+				l.Tool = l.Tools[0].Tool.String()
+				l.ToolVersion = l.Tools[0].Version.String()
 			}
 			f.Loads[i] = l
 		case types.LC_DYLD_EXPORTS_TRIE:
@@ -1794,56 +1939,77 @@ func (f *File) pushSection(sh *Section, r io.ReaderAt) error {
 	f.Sections = append(f.Sections, sh)
 	// sh.sr = io.NewSectionReader(r, int64(sh.Offset), int64(sh.Size))
 	sh.ReaderAt = f.sr
+	sh.file = f
 
 	if sh.Nreloc > 0 {
 		reldat := make([]byte, int(sh.Nreloc)*8)
 		if _, err := r.ReadAt(reldat, int64(sh.Reloff)); err != nil {
 			return fmt.Errorf("failed to read data at Reloff=%#x; %v", int64(sh.Reloff), err)
 		}
-		b := bytes.NewReader(reldat)
+		relocs, err := readRelocs(bytes.NewReader(reldat), f.ByteOrder, sh.Nreloc)
+		if err != nil {
+			return err
+		}
+		sh.Relocs = relocs
+	}
+
+	return nil
+}
 
-		bo := f.ByteOrder
+// readTable reads the count entries of size bytes of a LC_DYSYMTAB table at offset; the table is read
+// before anything is allocated for its entries so a bogus count can't exhaust memory
+func (f *File) readTable(offset, count uint32, size int) (*bytes.Reader, error) {
+	n := int64(count) * int64(size)
+	dat, err := io.ReadAll(io.NewSectionReader(f.lr, int64(offset), n))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(dat)) != n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return bytes.NewReader(dat), nil
+}
 
-		sh.Relocs = make([]Reloc, sh.Nreloc)
-		for i := range sh.Relocs {
-			rel := &sh.Relocs[i]
+// readRelocs reads count relocation_info (or scattered_relocation_info) entries
+func readRelocs(r io.Reader, bo binary.ByteOrder, count uint32) ([]Reloc, error) {
+	relocs := make([]Reloc, count)
+	for i := range relocs {
+		rel := &relocs[i]
 
-			var ri relocInfo
-			if err := binary.Read(b, bo, &ri); err != nil {
-				return fmt.Errorf("failed to read relocInfo; %v", err)
-			}
+		var ri relocInfo
+		if err := binary.Read(r, bo, &ri); err != nil {
+			return nil, fmt.Errorf("failed to read relocInfo; %v", err)
+		}
 
-			if ri.Addr&(1<<31) != 0 { // scattered
-				rel.Addr = ri.Addr & (1<<24 - 1)
-				rel.Type = uint8((ri.Addr >> 24) & (1<<4 - 1))
-				rel.Len = uint8((ri.Addr >> 28) & (1<<2 - 1))
-				rel.Pcrel = ri.Addr&(1<<30) != 0
-				rel.Value = ri.Symnum
-				rel.Scattered = true
-			} else {
-				switch bo {
-				case binary.LittleEndian:
-					rel.Addr = ri.Addr
-					rel.Value = ri.Symnum & (1<<24 - 1)
-					rel.Pcrel = ri.Symnum&(1<<24) != 0
-					rel.Len = uint8((ri.Symnum >> 25) & (1<<2 - 1))
-					rel.Extern = ri.Symnum&(1<<27) != 0
-					rel.Type = uint8((ri.Symnum >> 28) & (1<<4 - 1))
-				case binary.BigEndian:
-					rel.Addr = ri.Addr
-					rel.Value = ri.Symnum >> 8
-					rel.Pcrel = ri.Symnum&(1<<7) != 0
-					rel.Len = uint8((ri.Symnum >> 5) & (1<<2 - 1))
-					rel.Extern = ri.Symnum&(1<<4) != 0
-					rel.Type = uint8(ri.Symnum & (1<<4 - 1))
-				default:
-					panic("unreachable")
-				}
+		if ri.Addr&(1<<31) != 0 { // scattered
+			rel.Addr = ri.Addr & (1<<24 - 1)
+			rel.Type = uint8((ri.Addr >> 24) & (1<<4 - 1))
+			rel.Len = uint8((ri.Addr >> 28) & (1<<2 - 1))
+			rel.Pcrel = ri.Addr&(1<<30) != 0
+			rel.Value = ri.Symnum
+			rel.Scattered = true
+		} else {
+			switch bo {
+			case binary.LittleEndian:
+				rel.Addr = ri.Addr
+				rel.Value = ri.Symnum & (1<<24 - 1)
+				rel.Pcrel = ri.Symnum&(1<<24) != 0
+				rel.Len = uint8((ri.Symnum >> 25) & (1<<2 - 1))
+				rel.Extern = ri.Symnum&(1<<27) != 0
+				rel.Type = uint8((ri.Symnum >> 28) & (1<<4 - 1))
+			case binary.BigEndian:
+				rel.Addr = ri.Addr
+				rel.Value = ri.Symnum >> 8
+				rel.Pcrel = ri.Symnum&(1<<7) != 0
+				rel.Len = uint8((ri.Symnum >> 5) & (1<<2 - 1))
+				rel.Extern = ri.Symnum&(1<<4) != 0
+				rel.Type = uint8(ri.Symnum & (1<<4 - 1))
+			default:
+				panic("unreachable")
 			}
 		}
 	}
-
-	return nil
+	return relocs, nil
 }
 
 func cstring(b []byte) string {
@@ -1872,6 +2038,22 @@ func (f *File) preferredLoadAddress() uint64 {
 	return 0
 }
 
+func (f *File) readUint32(offset int64) (uint32, error) {
+	u32 := make([]byte, 4)
+	if _, err := f.sr.ReadAt(u32, offset); err != nil {
+		return 0, err
+	}
+	return f.ByteOrder.Uint32(u32), nil
+}
+
+func (f *File) readUint64(offset int64) (uint64, error) {
+	u64 := make([]byte, 8)
+	if _, err := f.sr.ReadAt(u64, offset); err != nil {
+		return 0, err
+	}
+	return f.ByteOrder.Uint64(u64), nil
+}
+
 func (f *File) readLeUint32(offset int64) (uint32, error) {
 	u32 := make([]byte, 4)
 	if _, err := f.sr.ReadAt(u32, offset); err != nil {
@@ -1904,20 +2086,16 @@ func (f *File) ReadAt(p []byte, off int64) (n int, err error) {
 
 // GetOffset returns the file offset for a given virtual address
 func (f *File) GetOffset(address uint64) (uint64, error) {
-	for _, seg := range f.Segments() {
-		if seg.Addr <= address && address < seg.Addr+seg.Memsz {
-			return (address - seg.Addr) + seg.Offset, nil
-		}
+	if seg := f.segmentForVMAddr(address); seg != nil {
+		return (address - seg.Addr) + seg.Offset, nil
 	}
 	return 0, fmt.Errorf("address 0x%x not within any segments adress range", address)
 }
 
 // GetVMAddress returns the virtal address for a given file offset
 func (f *File) GetVMAddress(offset uint64) (uint64, error) {
-	for _, seg := range f.Segments() {
-		if seg.Offset <= offset && offset < seg.Offset+seg.Filesz {
-			return (offset - seg.Offset) + seg.Addr, nil
-		}
+	if seg := f.segmentForOffset(offset); seg != nil {
+		return (offset - seg.Offset) + seg.Addr, nil
 	}
 	return 0, fmt.Errorf("offset 0x%x not within any segments file offset range", offset)
 }
@@ -1929,18 +2107,40 @@ func (f *File) GetBaseAddress() uint64 {
 
 func (f *File) convertToVMAddr(value uint64) uint64 {
 	if f.HasFixups() {
-		if fixupchains.DcpArm64eIsRebase(value) {
-			if fixupchains.DcpArm64eIsAuth(value) {
-				dcp := fixupchains.DyldChainedPtrArm64eAuthRebase{Pointer: value}
-				return dcp.Target() + f.preferredLoadAddress()
-			}
-			dcp := fixupchains.DyldChainedPtrArm64eRebase{Pointer: value}
-			return dcp.UnpackTarget()
+		format, err := f.chainedPointerFormat()
+		if err != nil {
+			return value
+		}
+		fx, err := fixupchains.DecodePointer(format, 0, value)
+		if err != nil {
+			return value
+		}
+		if fixup, ok := f.chainedFixup(f.dcf, format, 0, f.preferredLoadAddress(), fx); ok && fixup.Kind == FixupRebase {
+			return fixup.Target
 		}
 	}
 	return value
 }
 
+// chainedPointerFormat returns the pointer format used by the image's dyld chained fixups
+func (f *File) chainedPointerFormat() (fixupchains.DCPtrKind, error) {
+	var err error
+
+	if f.dcf == nil {
+		f.dcf, err = f.DyldChainedFixups()
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse dyld chained fixups: %v", err)
+		}
+	}
+	for _, start := range f.dcf.Starts {
+		if start.PageStarts != nil {
+			return start.PointerFormat, nil
+		}
+	}
+
+	return 0, fmt.Errorf("dyld chained fixups do not contain any segment starts")
+}
+
 // GetBindName returns the import name for a given dyld chained pointer
 func (f *File) GetBindName(pointer uint64) (string, error) {
 	var err error
@@ -2053,22 +2253,12 @@ func (f *File) Section(segment, section string) *Section {
 
 // FindSegmentForVMAddr returns the segment containing a given virtual memory ddress.
 func (f *File) FindSegmentForVMAddr(vmAddr uint64) *Segment {
-	for _, seg := range f.Segments() {
-		if seg.Addr <= vmAddr && vmAddr < seg.Addr+seg.Memsz {
-			return seg
-		}
-	}
-	return nil
+	return f.segmentForVMAddr(vmAddr)
 }
 
 // FindSectionForVMAddr returns the section containing a given virtual memory ddress.
 func (f *File) FindSectionForVMAddr(vmAddr uint64) *Section {
-	for _, sec := range f.Sections {
-		if sec.Addr <= vmAddr && vmAddr < sec.Addr+sec.Size {
-			return sec
-		}
-	}
-	return nil
+	return f.sectionForVMAddr(vmAddr)
 }
 
 // UUID returns the UUID load command, or nil if no UUID exists.
@@ -2101,6 +2291,33 @@ func (f *File) DyldInfo() *DyldInfo {
 	return nil
 }
 
+// EntryPoint returns the VM address of the entry point from either the LC_MAIN
+// command or the program counter of the LC_UNIXTHREAD/LC_THREAD commands.
+func (f *File) EntryPoint() (uint64, error) {
+	for _, l := range f.Loads {
+		if ep, ok := l.(*EntryPoint); ok {
+			return f.GetVMAddress(ep.EntryOffset)
+		}
+	}
+	for _, l := range f.Loads {
+		switch t := l.(type) {
+		case *UnixThread:
+			for _, state := range t.Threads {
+				if pc, ok := state.PC(); ok {
+					return pc, nil
+				}
+			}
+		case *Thread:
+			for _, state := range t.Threads {
+				if pc, ok := state.PC(); ok {
+					return pc, nil
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("macho does not contain LC_MAIN, LC_UNIXTHREAD or LC_THREAD")
+}
+
 // SourceVersion returns the source version load command, or nil if no source version exists.
 func (f *File) SourceVersion() *SourceVersion {
 	for _, l := range f.Loads {
@@ -2121,6 +2338,86 @@ func (f *File) BuildVersion() *BuildVersion {
 	return nil
 }
 
+// BuildVersions returns all the build version load commands (zippered binaries have more than one).
+func (f *File) BuildVersions() []*BuildVersion {
+	var bvs []*BuildVersion
+	for _, l := range f.Loads {
+		if s, ok := l.(*BuildVersion); ok {
+			bvs = append(bvs, s)
+		}
+	}
+	return bvs
+}
+
+// A PlatformVersion is a platform the binary supports and its min OS and SDK versions.
+type PlatformVersion struct {
+	Platform types.Platform
+	Minos    types.Version
+	Sdk      types.Version
+}
+
+func (p PlatformVersion) String() string {
+	return fmt.Sprintf("%s (minos: %s, sdk: %s)", p.Platform, p.Minos, p.Sdk)
+}
+
+// Platforms returns every platform the binary supports from its LC_BUILD_VERSION and LC_VERSION_MIN_* load commands.
+//
+// NOTE: like dyld, LC_VERSION_MIN_* commands in x86 binaries (other than macOS) are treated as simulator platforms.
+func (f *File) Platforms() []PlatformVersion {
+	var pvs []PlatformVersion
+
+	simulator := f.CPU == types.CPU386 || f.CPU == types.CPUAmd64
+
+	for _, l := range f.Loads {
+		switch v := l.(type) {
+		case *BuildVersion:
+			pvs = append(pvs, PlatformVersion{
+				Platform: v.BuildVersionCmd.Platform,
+				Minos:    v.BuildVersionCmd.Minos,
+				Sdk:      v.BuildVersionCmd.Sdk,
+			})
+		case *VersionMinMacOSX:
+			pvs = append(pvs, PlatformVersion{
+				Platform: types.PlatformMacOS,
+				Minos:    v.VersionMinMacOSCmd.Version,
+				Sdk:      v.VersionMinMacOSCmd.Sdk,
+			})
+		case *VersionMiniPhoneOS:
+			pv := PlatformVersion{
+				Platform: types.PlatformIOS,
+				Minos:    v.VersionMinIPhoneOSCmd.Version,
+				Sdk:      v.VersionMinIPhoneOSCmd.Sdk,
+			}
+			if simulator {
+				pv.Platform = types.PlatformIOSSimulator
+			}
+			pvs = append(pvs, pv)
+		case *VersionMinTvOS:
+			pv := PlatformVersion{
+				Platform: types.PlatformTvOS,
+				Minos:    v.VersionMinIPhoneOSCmd.Version,
+				Sdk:      v.VersionMinIPhoneOSCmd.Sdk,
+			}
+			if simulator {
+				pv.Platform = types.PlatformTvOSSimulator
+			}
+			pvs = append(pvs, pv)
+		case *VersionMinWatchOS:
+			pv := PlatformVersion{
+				Platform: types.PlatformWatchOS,
+				Minos:    v.VersionMinIPhoneOSCmd.Version,
+				Sdk:      v.VersionMinIPhoneOSCmd.Sdk,
+			}
+			if simulator {
+				pv.Platform = types.PlatformWatchOSSimulator
+			}
+			pvs = append(pvs, pv)
+		}
+	}
+
+	return pvs
+}
+
 // FileSets returns an array of Fileset entries.
 func (f *File) FileSets() []*FilesetEntry {
 	var fsets []*FilesetEntry
@@ -2160,6 +2457,8 @@ func (f *File) FunctionStarts() *FunctionStarts {
 
 // GetFunctions returns the function array, or nil if none exists.
 func (f *File) GetFunctions(data ...byte) []types.Function {
+	f.functionsMu.Lock()
+	defer f.functionsMu.Unlock()
 
 	if len(f.functions) > 0 {
 		return f.functions
@@ -2226,9 +2525,11 @@ func (f *File) GetFunctions(data ...byte) []types.Function {
 
 // GetFunctionForVMAddr returns the function containing a given virual address
 func (f *File) GetFunctionForVMAddr(addr uint64) (types.Function, error) {
-	for _, f := range f.GetFunctions() {
-		if addr >= f.StartAddr && addr < f.EndAddr {
-			return f, nil
+	// function starts are sorted and don't overlap
+	funcs := f.GetFunctions()
+	if i := sort.Search(len(funcs), func(i int) bool { return funcs[i].StartAddr > addr }) - 1; i >= 0 {
+		if fn := funcs[i]; addr >= fn.StartAddr && addr < fn.EndAddr {
+			return fn, nil
 		}
 	}
 	return types.Function{}, fmt.Errorf("address %#016x not in any function", addr)
@@ -2247,6 +2548,199 @@ func (f *File) GetFunctionData(fn types.Function) ([]byte, error) {
 	return data, nil
 }
 
+// DataInCode returns the data in code load command, or nil if none exists.
+func (f *File) DataInCode() *DataInCode {
+	for _, l := range f.Loads {
+		if s, ok := l.(*DataInCode); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// A DataInCodeRange is a range of data (jump tables, literal pools, etc) embedded in a function's instructions.
+type DataInCodeRange struct {
+	StartAddr uint64
+	EndAddr   uint64
+	Kind      types.DiceKind
+}
+
+func (d DataInCodeRange) String() string {
+	return fmt.Sprintf("%#016x-%#016x %s", d.StartAddr, d.EndAddr, d.Kind)
+}
+
+// GetDataInCode returns the LC_DATA_IN_CODE entries as VM address ranges sorted by address.
+// The entries are read on the first call and the result (or error) is cached.
+func (f *File) GetDataInCode() ([]DataInCodeRange, error) {
+	f.dataInCodeOnce.Do(func() {
+		f.dataInCode, f.dataInCodeErr = f.readDataInCode()
+	})
+	return f.dataInCode, f.dataInCodeErr
+}
+
+func (f *File) readDataInCode() ([]DataInCodeRange, error) {
+	dic := f.DataInCode()
+	if dic == nil || dic.Size == 0 {
+		return nil, nil
+	}
+
+	entrySize := uint32(binary.Size(types.DataInCodeEntry{}))
+	if dic.Size%entrySize != 0 {
+		return nil, fmt.Errorf("data in code size %#x is not a multiple of the %d byte entry size", dic.Size, entrySize)
+	}
+	ldat := make([]byte, dic.Size)
+	if _, err := f.lr.ReadAt(ldat, int64(dic.Offset)); err != nil {
+		return nil, fmt.Errorf("failed to read data in code entries at offset=%#x; %v", dic.Offset, err)
+	}
+	entries := make([]types.DataInCodeEntry, dic.Size/entrySize)
+	if err := binary.Read(bytes.NewReader(ldat), f.ByteOrder, &entries); err != nil {
+		return nil, fmt.Errorf("failed to read data in code entries: %v", err)
+	}
+	dic.Entries = entries
+
+	var ranges []DataInCodeRange
+	for _, e := range entries {
+		// object files store the address of the data, linked images store the file offset
+		addr := uint64(e.Offset)
+		if f.Type != types.Obj {
+			var err error
+			if addr, err = f.GetVMAddress(uint64(e.Offset)); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, DataInCodeRange{
+			StartAddr: addr,
+			EndAddr:   addr + uint64(e.Length),
+			Kind:      e.Kind,
+		})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].StartAddr < ranges[j].StartAddr
+	})
+
+	return ranges, nil
+}
+
+// GetDataInCodeForFunction returns the data in code ranges contained in a given function.
+func (f *File) GetDataInCodeForFunction(fn types.Function) ([]DataInCodeRange, error) {
+	all, err := f.GetDataInCode()
+	if err != nil {
+		return nil, err
+	}
+	var ranges []DataInCodeRange
+	for _, r := range all {
+		if r.StartAddr < fn.EndAddr && r.EndAddr > fn.StartAddr {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges, nil
+}
+
+// IsDataInCode returns the data in code range containing a given virtual address, if the address is data and not instructions.
+// Addresses are reported as instructions if the LC_DATA_IN_CODE entries can't be read (see GetDataInCode).
+func (f *File) IsDataInCode(addr uint64) (DataInCodeRange, bool) {
+	ranges, _ := f.GetDataInCode()
+	idx := sort.Search(len(ranges), func(i int) bool {
+		return ranges[i].EndAddr > addr
+	})
+	if idx < len(ranges) && ranges[idx].StartAddr <= addr {
+		return ranges[idx], true
+	}
+	return DataInCodeRange{}, false
+}
+
+// LinkerOptimizationHint returns the LC_LINKER_OPTIMIZATION_HINT load command, or nil if there is none.
+func (f *File) LinkerOptimizationHint() *LinkerOptimizationHint {
+	for _, l := range f.Loads {
+		if s, ok := l.(*LinkerOptimizationHint); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// GetLinkerOptimizationHints returns the decoded LC_LINKER_OPTIMIZATION_HINT records.
+// The hints are read on the first call and the result (or error) is cached.
+func (f *File) GetLinkerOptimizationHints() ([]types.LinkerOptimizationHint, error) {
+	f.lohOnce.Do(func() {
+		f.lohs, f.lohErr = f.readLinkerOptimizationHints()
+	})
+	return f.lohs, f.lohErr
+}
+
+func (f *File) readLinkerOptimizationHints() ([]types.LinkerOptimizationHint, error) {
+	loh := f.LinkerOptimizationHint()
+	if loh == nil {
+		return nil, fmt.Errorf("macho does not contain a %s load command", types.LC_LINKER_OPTIMIZATION_HINT)
+	}
+	if loh.Size == 0 {
+		return []types.LinkerOptimizationHint{}, nil
+	}
+	ldat := make([]byte, loh.Size)
+	if _, err := f.lr.ReadAt(ldat, int64(loh.Offset)); err != nil {
+		return nil, fmt.Errorf("failed to read linker optimization hints at offset=%#x; %v", loh.Offset, err)
+	}
+	hints, err := parseLinkerOptimizationHints(bytes.NewReader(ldat))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse linker optimization hints: %v", err)
+	}
+	return hints, nil
+}
+
+// GetLinkerOptimizationHintsForAddr returns the hints whose instruction sequence includes addr
+func (f *File) GetLinkerOptimizationHintsForAddr(addr uint64) ([]types.LinkerOptimizationHint, error) {
+	all, err := f.GetLinkerOptimizationHints()
+	if err != nil {
+		return nil, err
+	}
+	var hints []types.LinkerOptimizationHint
+	for _, h := range all {
+		for _, a := range h.Addrs {
+			if a == addr {
+				hints = append(hints, h)
+				break
+			}
+		}
+	}
+	return hints, nil
+}
+
+// parseLinkerOptimizationHints parses a stream of ULEB128 encoded kind, argument count and argument addresses
+// (the stream is padded to pointer alignment with zeros); the argument count of known kinds must match their NumArgs
+func parseLinkerOptimizationHints(r *bytes.Reader) ([]types.LinkerOptimizationHint, error) {
+	var hints []types.LinkerOptimizationHint
+	for r.Len() > 0 {
+		kind, err := trie.ReadUleb128(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read hint kind: %v", err)
+		}
+		if kind == 0 { // padding
+			break
+		}
+		count, err := trie.ReadUleb128(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s hint argument count: %v", types.LOHKind(kind), err)
+		}
+		if count > uint64(r.Len()) {
+			return nil, fmt.Errorf("%s hint argument count %d exceeds remaining data", types.LOHKind(kind), count)
+		}
+		hint := types.LinkerOptimizationHint{Kind: types.LOHKind(kind)}
+		if n := hint.Kind.NumArgs(); n > 0 && count != uint64(n) {
+			return nil, fmt.Errorf("%s hint has %d arguments, expected %d", hint.Kind, count, n)
+		}
+		for i := uint64(0); i < count; i++ {
+			addr, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s hint argument: %v", hint.Kind, err)
+			}
+			hint.Addrs = append(hint.Addrs, addr)
+		}
+		hints = append(hints, hint)
+	}
+	return hints, nil
+}
+
 // CodeSignature returns the code signature, or nil if none exists.
 func (f *File) CodeSignature() *CodeSignature {
 	for _, l := range f.Loads {
@@ -2327,43 +2821,49 @@ func (f *File) DyldChainedFixups() (*fixupchains.DyldChainedFixups, error) {
 	return nil, fmt.Errorf("macho does not contain LC_DYLD_CHAINED_FIXUPS")
 }
 
-// DWARF returns the DWARF debug information for the Mach-O file.
-func (f *File) DWARF() (*dwarf.Data, error) {
-	dwarfSuffix := func(s *Section) string {
-		switch {
-		case strings.HasPrefix(s.Name, "__debug_"):
-			return s.Name[8:]
-		case strings.HasPrefix(s.Name, "__zdebug_"):
-			return s.Name[9:]
-		case strings.HasPrefix(s.Name, "__apple_"):
-			return s.Name[8:]
-		default:
-			return ""
-		}
+// dwarfSuffix returns the DWARF section name of a __debug_* or __zdebug_* section (or "")
+//
+// NOTE: the __apple_* accelerator tables are not DWARF sections (and __apple_types is not .debug_types)
+func dwarfSuffix(s *Section) string {
+	switch {
+	case strings.HasPrefix(s.Name, "__debug_"):
+		return s.Name[8:]
+	case strings.HasPrefix(s.Name, "__zdebug_"):
+		return s.Name[9:]
+	default:
+		return ""
+	}
+}
 
+// dwarfSectionData returns the data of a DWARF section (decompressing ZLIB compressed sections)
+func dwarfSectionData(s *Section) ([]byte, error) {
+	b, err := s.Data()
+	if err != nil && uint64(len(b)) < s.Size {
+		return nil, err
 	}
-	sectionData := func(s *Section) ([]byte, error) {
-		b, err := s.Data()
-		if err != nil && uint64(len(b)) < s.Size {
+
+	if len(b) >= 12 && string(b[:4]) == "ZLIB" {
+		dlen := binary.BigEndian.Uint64(b[4:12])
+		dbuf := make([]byte, dlen)
+		r, err := zlib.NewReader(bytes.NewBuffer(b[12:]))
+		if err != nil {
 			return nil, err
 		}
-
-		if len(b) >= 12 && string(b[:4]) == "ZLIB" {
-			dlen := binary.BigEndian.Uint64(b[4:12])
-			dbuf := make([]byte, dlen)
-			r, err := zlib.NewReader(bytes.NewBuffer(b[12:]))
-			if err != nil {
-				return nil, err
-			}
-			if _, err := io.ReadFull(r, dbuf); err != nil {
-				return nil, err
-			}
-			if err := r.Close(); err != nil {
-				return nil, err
-			}
-			b = dbuf
+		if _, err := io.ReadFull(r, dbuf); err != nil {
+			return nil, err
+		}
+		if err := r.Close(); err != nil {
+			return nil, err
 		}
-		return b, nil
+		b = dbuf
+	}
+	return b, nil
+}
+
+// DWARF returns the DWARF debug information for the Mach-O file (or its paired dSYM; see PairDSYM).
+func (f *File) DWARF() (*dwarf.Data, error) {
+	if dsym := f.DSYM(); dsym != nil {
+		return dsym.DWARF()
 	}
 
 	// There are many other DWARF sections, but these
@@ -2378,7 +2878,7 @@ func (f *File) DWARF() (*dwarf.Data, error) {
 		if _, ok := dat[suffix]; !ok {
 			continue
 		}
-		b, err := sectionData(s)
+		b, err := dwarfSectionData(s)
 		if err != nil {
 			return nil, err
 		}
@@ -2397,7 +2897,7 @@ func (f *File) DWARF() (*dwarf.Data, error) {
 			continue
 		}
 
-		b, err := sectionData(s)
+		b, err := dwarfSectionData(s)
 		if err != nil {
 			return nil, err
 		}
@@ -2471,10 +2971,10 @@ func (f *File) LibraryOrdinalName(libraryOrdinal int) string {
 	dylibs := f.ImportedLibraries()
 
 	if libraryOrdinal > 0 {
-		path := dylibs[libraryOrdinal-1]
 		if libraryOrdinal > len(dylibs) {
 			return "ordinal-too-large"
 		}
+		path := dylibs[libraryOrdinal-1]
 		parts := strings.Split(path, "/")
 		return parts[len(parts)-1]
 	}
@@ -2493,23 +2993,17 @@ func (f *File) LibraryOrdinalName(libraryOrdinal int) string {
 	}
 }
 
+// FindSymbolAddress returns the value of the first symtab symbol with the (case-insensitive) name.
 func (f *File) FindSymbolAddress(symbol string) (uint64, error) {
-	for _, sym := range f.Symtab.Syms {
-		if strings.EqualFold(sym.Name, symbol) {
-			return sym.Value, nil
-		}
+	if addr, ok := f.symtabEntries().byFold[foldName(symbol)]; ok {
+		return addr, nil
 	}
 	return 0, fmt.Errorf("symbol not found in macho symtab")
 }
 
+// FindAddressSymbols returns the symtab symbols with the value addr.
 func (f *File) FindAddressSymbols(addr uint64) ([]Symbol, error) {
-	var syms []Symbol
-	for _, sym := range f.Symtab.Syms {
-		if sym.Value == addr {
-			syms = append(syms, sym)
-		}
-	}
-	if len(syms) > 0 {
+	if syms, ok := f.symtabEntries().byAddr[addr]; ok {
 		return syms, nil
 	}
 	return nil, fmt.Errorf("symbol(s) not found in macho symtab for addr 0x%016x", addr)
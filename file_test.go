@@ -6,6 +6,7 @@ package macho
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"os"
@@ -43,11 +44,11 @@ var fileTests = []fileTest{
 			&Dylib{nil, types.DylibCmd{}, "/usr/lib/libSystem.B.dylib", 0x2, "0x6f0104", "0x10000"},
 		},
 		[]*SectionHeader{
-			{"__text", "__TEXT", 0x1f68, 0x88, 0xf68, 0x2, 0x0, 0x0, 0x80000400, 0, 0, 0},
-			{"__cstring", "__TEXT", 0x1ff0, 0xd, 0xff0, 0x0, 0x0, 0x0, 0x2, 0, 0, 0},
-			{"__data", "__DATA", 0x2000, 0x14, 0x1000, 0x2, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__dyld", "__DATA", 0x2014, 0x1c, 0x1014, 0x2, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__jump_table", "__IMPORT", 0x3000, 0xa, 0x2000, 0x6, 0x0, 0x0, 0x4000008, 0, 0, 0},
+			{"__text", "__TEXT", 0x1f68, 0x88, 0xf68, 0x2, 0x0, 0x0, 0x80000400, 0, 0, 0, 0},
+			{"__cstring", "__TEXT", 0x1ff0, 0xd, 0xff0, 0x0, 0x0, 0x0, 0x2, 0, 0, 0, 0},
+			{"__data", "__DATA", 0x2000, 0x14, 0x1000, 0x2, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__dyld", "__DATA", 0x2014, 0x1c, 0x1014, 0x2, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__jump_table", "__IMPORT", 0x3000, 0xa, 0x2000, 0x6, 0x0, 0x0, 0x4000008, 0, 0, 0, 0},
 		},
 		nil,
 	},
@@ -68,14 +69,14 @@ var fileTests = []fileTest{
 			&Dylib{nil, types.DylibCmd{}, "/usr/lib/libSystem.B.dylib", 0x2, "0x6f0104", "0x10000"},
 		},
 		[]*SectionHeader{
-			{"__text", "__TEXT", 0x100000f14, 0x6d, 0xf14, 0x2, 0x0, 0x0, 0x80000400, 0, 0, 0},
-			{"__symbol_stub1", "__TEXT", 0x100000f81, 0xc, 0xf81, 0x0, 0x0, 0x0, 0x80000408, 0, 0, 0},
-			{"__stub_helper", "__TEXT", 0x100000f90, 0x18, 0xf90, 0x2, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__cstring", "__TEXT", 0x100000fa8, 0xd, 0xfa8, 0x0, 0x0, 0x0, 0x2, 0, 0, 0},
-			{"__eh_frame", "__TEXT", 0x100000fb8, 0x48, 0xfb8, 0x3, 0x0, 0x0, 0x6000000b, 0, 0, 0},
-			{"__data", "__DATA", 0x100001000, 0x1c, 0x1000, 0x3, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__dyld", "__DATA", 0x100001020, 0x38, 0x1020, 0x3, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__la_symbol_ptr", "__DATA", 0x100001058, 0x10, 0x1058, 0x2, 0x0, 0x0, 0x7, 0, 0, 0},
+			{"__text", "__TEXT", 0x100000f14, 0x6d, 0xf14, 0x2, 0x0, 0x0, 0x80000400, 0, 0, 0, 0},
+			{"__symbol_stub1", "__TEXT", 0x100000f81, 0xc, 0xf81, 0x0, 0x0, 0x0, 0x80000408, 0, 0, 0, 0},
+			{"__stub_helper", "__TEXT", 0x100000f90, 0x18, 0xf90, 0x2, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__cstring", "__TEXT", 0x100000fa8, 0xd, 0xfa8, 0x0, 0x0, 0x0, 0x2, 0, 0, 0, 0},
+			{"__eh_frame", "__TEXT", 0x100000fb8, 0x48, 0xfb8, 0x3, 0x0, 0x0, 0x6000000b, 0, 0, 0, 0},
+			{"__data", "__DATA", 0x100001000, 0x1c, 0x1000, 0x3, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__dyld", "__DATA", 0x100001020, 0x38, 0x1020, 0x3, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__la_symbol_ptr", "__DATA", 0x100001058, 0x10, 0x1058, 0x2, 0x0, 0x0, 0x7, 0, 0, 0, 0},
 		},
 		nil,
 	},
@@ -89,21 +90,21 @@ var fileTests = []fileTest{
 			&SegmentHeader{types.LC_SEGMENT_64, 0x278, "__DWARF", 0x100002000, 0x1000, 0x1000, 0x1bc, 0x7, 0x3, 0x7, 0x0, 0},
 		},
 		[]*SectionHeader{
-			{"__text", "__TEXT", 0x100000f14, 0x0, 0x0, 0x2, 0x0, 0x0, 0x80000400, 0, 0, 0},
-			{"__symbol_stub1", "__TEXT", 0x100000f81, 0x0, 0x0, 0x0, 0x0, 0x0, 0x80000408, 0, 0, 0},
-			{"__stub_helper", "__TEXT", 0x100000f90, 0x0, 0x0, 0x2, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__cstring", "__TEXT", 0x100000fa8, 0x0, 0x0, 0x0, 0x0, 0x0, 0x2, 0, 0, 0},
-			{"__eh_frame", "__TEXT", 0x100000fb8, 0x0, 0x0, 0x3, 0x0, 0x0, 0x6000000b, 0, 0, 0},
-			{"__data", "__DATA", 0x100001000, 0x0, 0x0, 0x3, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__dyld", "__DATA", 0x100001020, 0x0, 0x0, 0x3, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__la_symbol_ptr", "__DATA", 0x100001058, 0x0, 0x0, 0x2, 0x0, 0x0, 0x7, 0, 0, 0},
-			{"__debug_abbrev", "__DWARF", 0x100002000, 0x36, 0x1000, 0x0, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__debug_aranges", "__DWARF", 0x100002036, 0x30, 0x1036, 0x0, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__debug_frame", "__DWARF", 0x100002066, 0x40, 0x1066, 0x0, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__debug_info", "__DWARF", 0x1000020a6, 0x54, 0x10a6, 0x0, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__debug_line", "__DWARF", 0x1000020fa, 0x47, 0x10fa, 0x0, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__debug_pubnames", "__DWARF", 0x100002141, 0x1b, 0x1141, 0x0, 0x0, 0x0, 0x0, 0, 0, 0},
-			{"__debug_str", "__DWARF", 0x10000215c, 0x60, 0x115c, 0x0, 0x0, 0x0, 0x0, 0, 0, 0},
+			{"__text", "__TEXT", 0x100000f14, 0x0, 0x0, 0x2, 0x0, 0x0, 0x80000400, 0, 0, 0, 0},
+			{"__symbol_stub1", "__TEXT", 0x100000f81, 0x0, 0x0, 0x0, 0x0, 0x0, 0x80000408, 0, 0, 0, 0},
+			{"__stub_helper", "__TEXT", 0x100000f90, 0x0, 0x0, 0x2, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__cstring", "__TEXT", 0x100000fa8, 0x0, 0x0, 0x0, 0x0, 0x0, 0x2, 0, 0, 0, 0},
+			{"__eh_frame", "__TEXT", 0x100000fb8, 0x0, 0x0, 0x3, 0x0, 0x0, 0x6000000b, 0, 0, 0, 0},
+			{"__data", "__DATA", 0x100001000, 0x0, 0x0, 0x3, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__dyld", "__DATA", 0x100001020, 0x0, 0x0, 0x3, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__la_symbol_ptr", "__DATA", 0x100001058, 0x0, 0x0, 0x2, 0x0, 0x0, 0x7, 0, 0, 0, 0},
+			{"__debug_abbrev", "__DWARF", 0x100002000, 0x36, 0x1000, 0x0, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__debug_aranges", "__DWARF", 0x100002036, 0x30, 0x1036, 0x0, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__debug_frame", "__DWARF", 0x100002066, 0x40, 0x1066, 0x0, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__debug_info", "__DWARF", 0x1000020a6, 0x54, 0x10a6, 0x0, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__debug_line", "__DWARF", 0x1000020fa, 0x47, 0x10fa, 0x0, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__debug_pubnames", "__DWARF", 0x100002141, 0x1b, 0x1141, 0x0, 0x0, 0x0, 0x0, 0, 0, 0, 0},
+			{"__debug_str", "__DWARF", 0x10000215c, 0x60, 0x115c, 0x0, 0x0, 0x0, 0x0, 0, 0, 0, 0},
 		},
 		nil,
 	},
@@ -463,3 +464,237 @@ func TestNewFile(t *testing.T) {
 		t.Errorf("macho.UUID() = %s; want test", got.UUID())
 	}
 }
+
+func TestDataInCode(t *testing.T) {
+	entries := []types.DataInCodeEntry{
+		{Offset: 0xf70, Length: 8, Kind: types.KindJumpTable32},
+		{Offset: 0xf62, Length: 2, Kind: types.KindData},
+	}
+	f, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		off := loadCmdOffset(f, f.DataInCode())
+		binary.LittleEndian.PutUint32(data[off+8:], uint32(len(data)))
+		binary.LittleEndian.PutUint32(data[off+12:], uint32(binary.Size(entries)))
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.LittleEndian, entries)
+		return append(data, buf.Bytes()...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ranges, err := f.GetDataInCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DataInCodeRange{
+		{StartAddr: 0x100000f62, EndAddr: 0x100000f64, Kind: types.KindData},
+		{StartAddr: 0x100000f70, EndAddr: 0x100000f78, Kind: types.KindJumpTable32},
+	}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Fatalf("got data in code %v, want %v", ranges, want)
+	}
+	if r, ok := f.IsDataInCode(0x100000f77); !ok || r != want[1] {
+		t.Errorf("0x100000f77 is not in %s", want[1])
+	}
+	if _, ok := f.IsDataInCode(0x100000f78); ok {
+		t.Error("0x100000f78 is data")
+	}
+	fn := types.Function{StartAddr: 0x100000f60, EndAddr: 0x100000f66}
+	if got, err := f.GetDataInCodeForFunction(fn); err != nil || len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got %v (%v) for %#x-%#x, want %v", got, err, fn.StartAddr, fn.EndAddr, want[:1])
+	}
+	if dic := f.DataInCode(); !reflect.DeepEqual(dic.Entries, entries) {
+		t.Errorf("got entries %v, want %v", dic.Entries, entries)
+	}
+}
+
+func TestDataInCodeMalformed(t *testing.T) {
+	for name, size := range map[string]uint32{
+		"past the end of the file": 0x100000,
+		"partial entry":            12,
+	} {
+		// the entries are only read on request, so the file still opens
+		f, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+			off := loadCmdOffset(f, f.DataInCode())
+			binary.LittleEndian.PutUint32(data[off+12:], size)
+			return data
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := f.GetDataInCode(); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+		if _, err := f.GetDataInCodeForFunction(types.Function{StartAddr: 0x100000f60, EndAddr: 0x100000f66}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if _, ok := f.IsDataInCode(0x100000f60); ok {
+			t.Errorf("%s: 0x100000f60 is data", name)
+		}
+		f.Close()
+	}
+}
+
+func TestParseLinkerOptimizationHints(t *testing.T) {
+	hints, err := parseLinkerOptimizationHints(bytes.NewReader([]byte{
+		byte(types.LOH_ARM64_ADRP_ADD), 0x02, 0xe0, 0x1e, 0xe4, 0x1e, // 0xf60, 0xf64
+		byte(types.LOH_ARM64_ADRP_ADD_LDR), 0x03, 0x10, 0x14, 0x18,
+		0x00, 0x00, // padding
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.LinkerOptimizationHint{
+		{Kind: types.LOH_ARM64_ADRP_ADD, Addrs: []uint64{0xf60, 0xf64}},
+		{Kind: types.LOH_ARM64_ADRP_ADD_LDR, Addrs: []uint64{0x10, 0x14, 0x18}},
+	}
+	if !reflect.DeepEqual(hints, want) {
+		t.Errorf("got hints %v, want %v", hints, want)
+	}
+
+	for name, data := range map[string][]byte{
+		"argument count mismatch": {byte(types.LOH_ARM64_ADRP_ADD), 0x03, 0x10, 0x14, 0x18},
+		"truncated argument":      {byte(types.LOH_ARM64_ADRP_ADD), 0x02, 0x10, 0x80},
+		"huge argument count":     {byte(types.LOH_ARM64_ADRP_ADD), 0xff, 0xff, 0xff, 0xff, 0x0f},
+	} {
+		if _, err := parseLinkerOptimizationHints(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLinkerOptimizationHints(t *testing.T) {
+	for name, loh := range map[string][]byte{
+		"valid":     {byte(types.LOH_ARM64_ADRP_ADD), 0x02, 0xe0, 0x1e, 0xe4, 0x1e, 0x00, 0x00},
+		"malformed": {byte(types.LOH_ARM64_ADRP_ADD), 0x01, 0xe0, 0x1e, 0x00, 0x00, 0x00, 0x00},
+		"truncated": {byte(types.LOH_ARM64_ADRP_ADD), 0x02},
+	} {
+		size := uint32(len(loh))
+		if name == "truncated" {
+			size = 0x100000 // past the end of the file
+		}
+		// the hints are only decoded on request, so the file opens either way
+		f, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+			// turn LC_DATA_IN_CODE (also a linkedit_data_command) into LC_LINKER_OPTIMIZATION_HINT
+			off := loadCmdOffset(f, f.DataInCode())
+			binary.LittleEndian.PutUint32(data[off:], uint32(types.LC_LINKER_OPTIMIZATION_HINT))
+			binary.LittleEndian.PutUint32(data[off+8:], uint32(len(data)))
+			binary.LittleEndian.PutUint32(data[off+12:], size)
+			return append(data, loh...)
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if f.LinkerOptimizationHint() == nil {
+			t.Fatalf("%s: no LC_LINKER_OPTIMIZATION_HINT", name)
+		}
+		hints, err := f.GetLinkerOptimizationHintsForAddr(0xf64)
+		if name == "valid" && (err != nil || len(hints) != 1) {
+			t.Errorf("%s: got hints %v (%v)", name, hints, err)
+		}
+		if name != "valid" && err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		f.Close()
+	}
+
+	exe, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exe.Close()
+	if _, err := exe.GetLinkerOptimizationHints(); err == nil {
+		t.Error("expected an error for an image without LC_LINKER_OPTIMIZATION_HINT")
+	}
+}
+
+func TestBuildVersions(t *testing.T) {
+	tools := []types.BuildToolVersion{
+		{Tool: types.ToolClang, Version: 0x44c0021},
+		{Tool: types.ToolSwift, Version: 0x50100},
+		{Tool: types.ToolLD, Version: 0x2080000},
+	}
+	f, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		// a zippered binary; LC_DYLD_INFO_ONLY (48 bytes) becomes a Mac Catalyst LC_BUILD_VERSION with 3 tools
+		// and LC_UUID (24 bytes) a macOS LC_BUILD_VERSION without tools
+		off := loadCmdOffset(f, f.DyldInfo())
+		binary.LittleEndian.PutUint32(data[off:], uint32(types.LC_BUILD_VERSION))
+		binary.LittleEndian.PutUint32(data[off+8:], uint32(types.PlatformMacCatalyst))
+		binary.LittleEndian.PutUint32(data[off+12:], 0xd0100) // minos 13.1
+		binary.LittleEndian.PutUint32(data[off+16:], 0xd0100) // sdk 13.1
+		binary.LittleEndian.PutUint32(data[off+20:], uint32(len(tools)))
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.LittleEndian, tools)
+		copy(data[off+24:], buf.Bytes())
+
+		off = loadCmdOffset(f, f.UUID())
+		binary.LittleEndian.PutUint32(data[off:], uint32(types.LC_BUILD_VERSION))
+		binary.LittleEndian.PutUint32(data[off+8:], uint32(types.PlatformMacOS))
+		binary.LittleEndian.PutUint32(data[off+12:], 0xa0f00) // minos 10.15
+		binary.LittleEndian.PutUint32(data[off+16:], 0xb0000) // sdk 11.0
+		binary.LittleEndian.PutUint32(data[off+20:], 0)
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	bvs := f.BuildVersions()
+	if len(bvs) != 2 {
+		t.Fatalf("got %d build versions, want 2", len(bvs))
+	}
+	if !reflect.DeepEqual(bvs[0].Tools, tools) || bvs[0].Tool != types.ToolClang.String() {
+		t.Errorf("got tools %v, want %v", bvs[0].Tools, tools)
+	}
+	if len(bvs[1].Tools) != 0 || len(bvs[1].Tool) != 0 {
+		t.Errorf("got tools %v, want none", bvs[1].Tools)
+	}
+
+	var platforms []types.Platform
+	for _, pv := range f.Platforms() {
+		platforms = append(platforms, pv.Platform)
+	}
+	want := []types.Platform{types.PlatformMacCatalyst, types.PlatformMacOS, types.PlatformMacOS} // and LC_VERSION_MIN_MACOSX
+	if !reflect.DeepEqual(platforms, want) {
+		t.Errorf("got platforms %v, want %v", platforms, want)
+	}
+	if minos := f.Platforms()[1].Minos; minos.Major() != 10 || minos.Minor() != 15 || minos.Patch() != 0 {
+		t.Errorf("got macOS minos %s, want 10.15", minos)
+	}
+}
+
+func TestBuildVersionsMalformed(t *testing.T) {
+	_, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		// a LC_BUILD_VERSION whose tools extend past the end of the command
+		off := loadCmdOffset(f, f.UUID())
+		binary.LittleEndian.PutUint32(data[off:], uint32(types.LC_BUILD_VERSION))
+		binary.LittleEndian.PutUint32(data[off+20:], 1)
+		return data
+	})
+	if err == nil {
+		t.Error("expected an error for tools past the end of LC_BUILD_VERSION")
+	}
+}
+
+func TestPlatformsSimulator(t *testing.T) {
+	f, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		for _, l := range f.Loads {
+			if _, ok := l.(*VersionMinMacOSX); ok {
+				binary.LittleEndian.PutUint32(data[loadCmdOffset(f, l):], uint32(types.LC_VERSION_MIN_IPHONEOS))
+			}
+		}
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if pvs := f.Platforms(); len(pvs) != 1 || pvs[0].Platform != types.PlatformIOSSimulator {
+		t.Errorf("got platforms %v, want an iOS simulator", pvs)
+	}
+}
@@ -0,0 +1,517 @@
+package macho
+
+import (
+	"fmt"
+
+	"github.com/blacktop/go-macho/pkg/fixupchains"
+	"github.com/blacktop/go-macho/types"
+)
+
+// FixupKind is the kind of work dyld performs on a fixup slot.
+type FixupKind uint8
+
+const (
+	FixupRebase FixupKind = iota
+	FixupBind
+)
+
+func (k FixupKind) String() string {
+	switch k {
+	case FixupRebase:
+		return "rebase"
+	case FixupBind:
+		return "bind"
+	default:
+		return fmt.Sprintf("FixupKind(%d)", k)
+	}
+}
+
+// PAC is the arm64e pointer authentication info of an authenticated fixup.
+type PAC struct {
+	Key       uint64
+	Diversity uint64
+	AddrDiv   bool
+}
+
+func (p PAC) String() string {
+	return fmt.Sprintf("key: %s, addrDiv: %t, diversity: %#04x", fixupchains.KeyName(p.Key), p.AddrDiv, p.Diversity)
+}
+
+// A Fixup is a rebase or bind of a pointer slot, decoded from either
+// LC_DYLD_CHAINED_FIXUPS or the legacy LC_DYLD_INFO opcode streams.
+type Fixup struct {
+	Kind    FixupKind
+	Segment string
+	Section string
+	Offset  uint64 // file offset of the slot
+	Address uint64 // VM address of the slot
+	Target  uint64 // VM address a rebase points to
+	Name    string // symbol name a bind resolves to
+	Dylib   string // library a bind resolves from
+	Ordinal int
+	Addend  int64
+	Weak    bool
+	Auth    *PAC // non-nil for arm64e authenticated pointers
+}
+
+func (f Fixup) String() string {
+	var auth string
+	if f.Auth != nil {
+		auth = fmt.Sprintf(" (%s)", f.Auth)
+	}
+	if f.Kind == FixupBind {
+		var addend string
+		if f.Addend != 0 {
+			addend = fmt.Sprintf(" + %#x", f.Addend)
+		}
+		return fmt.Sprintf("%#016x: %-6s %s/%s%s%s", f.Address, f.Kind, f.Dylib, f.Name, addend, auth)
+	}
+	return fmt.Sprintf("%#016x: %-6s %#016x%s", f.Address, f.Kind, f.Target, auth)
+}
+
+// Fixups returns every rebase and bind dyld performs when loading the image,
+// whether they are encoded as chained fixups or as dyld info opcodes.
+func (f *File) Fixups() ([]Fixup, error) {
+	if f.fixups != nil {
+		return f.fixups, nil
+	}
+
+	var err error
+	if f.HasFixups() {
+		f.fixups, err = f.chainedFixups()
+	} else if f.DyldInfo() != nil {
+		f.fixups, err = f.dyldInfoFixups()
+	} else if f.hasClassicFixups() {
+		f.fixups, err = f.classicFixups()
+	} else {
+		return nil, fmt.Errorf("macho does not contain LC_DYLD_CHAINED_FIXUPS, LC_DYLD_INFO, LC_DYLD_INFO_ONLY or LC_DYSYMTAB")
+	}
+	if err != nil {
+		f.fixups = nil
+		return nil, err
+	}
+	if f.fixups == nil {
+		f.fixups = []Fixup{} // cache images without fixups too
+	}
+
+	return f.fixups, nil
+}
+
+// hasClassicFixups reports whether the image's fixups are the LC_DYSYMTAB relocations and indirect symbols
+func (f *File) hasClassicFixups() bool {
+	return f.Dysymtab != nil && f.Type != types.Obj
+}
+
+func (f *File) chainedFixups() ([]Fixup, error) {
+	var err error
+	var fixups []Fixup
+
+	if f.dcf == nil {
+		f.dcf, err = f.DyldChainedFixups()
+		if err != nil {
+			return nil, err
+		}
+	}
+	dcf := f.dcf
+
+	segs := f.Segments()
+	base := f.preferredLoadAddress()
+
+	for idx, start := range dcf.Starts {
+		if start.PageStarts == nil || idx >= len(segs) {
+			continue
+		}
+		seg := segs[idx]
+		for _, fx := range start.Fixups {
+			fixup, ok := f.chainedFixup(dcf, start.PointerFormat, start.MaxValidPointer, base, fx)
+			if !ok {
+				continue
+			}
+			fixup.Segment = seg.Name
+			fixup.Offset = fx.Offset()
+			fixup.Address = seg.Addr + (fixup.Offset - seg.Offset)
+			if sec := f.FindSectionForVMAddr(fixup.Address); sec != nil {
+				fixup.Section = sec.Name
+			}
+			fixups = append(fixups, fixup)
+		}
+	}
+
+	return fixups, nil
+}
+
+// chainedFixup converts a decoded chained pointer into a Fixup, resolving rebase targets to VM addresses.
+func (f *File) chainedFixup(dcf *fixupchains.DyldChainedFixups, format fixupchains.DCPtrKind, maxValid uint32, base uint64, fx fixupchains.Fixup) (Fixup, bool) {
+	var fixup Fixup
+
+	// only arm64e and generic 64 rebases in these formats hold a vmaddr, the rest hold an offset from the image base
+	targetIsVMAddr := format == fixupchains.DYLD_CHAINED_PTR_ARM64E ||
+		format == fixupchains.DYLD_CHAINED_PTR_ARM64E_FIRMWARE ||
+		format == fixupchains.DYLD_CHAINED_PTR_64
+
+	switch p := fx.(type) {
+	case fixupchains.DyldChainedPtrArm64eRebase:
+		fixup.Target = p.Target()
+		if !targetIsVMAddr {
+			fixup.Target += base
+		}
+		fixup.Target |= p.High8() << 56
+	case fixupchains.DyldChainedPtrArm64eRebase24:
+		fixup.Target = base + p.Target() | p.High8()<<56
+	case fixupchains.DyldChainedPtrArm64eAuthRebase:
+		fixup.Target = base + p.Target()
+		fixup.Auth = &PAC{Key: p.Key(), Diversity: p.Diversity(), AddrDiv: p.AddrDiv() == 1}
+	case fixupchains.DyldChainedPtrArm64eAuthRebase24:
+		fixup.Target = base + p.Target()
+		fixup.Auth = &PAC{Key: p.Key(), Diversity: p.Diversity(), AddrDiv: p.AddrDiv() == 1}
+	case fixupchains.DyldChainedPtr64Rebase:
+		fixup.Target = p.High8()<<56 | p.Target()
+	case fixupchains.DyldChainedPtr64RebaseOffset:
+		fixup.Target = p.High8()<<56 | (base + p.Target())
+	case fixupchains.DyldChainedPtr64KernelCacheRebase:
+		fixup.Target = base + p.Target()
+		if p.IsAuth() == 1 {
+			fixup.Auth = &PAC{Key: p.Key(), Diversity: p.Diversity(), AddrDiv: p.AddrDiv() == 1}
+		}
+	case fixupchains.DyldChainedPtr32Rebase:
+		if maxValid != 0 && p.Target() > uint64(maxValid) {
+			return fixup, false // a non-pointer value co-opted into the chain
+		}
+		fixup.Target = p.Target()
+	case fixupchains.DyldChainedPtr32CacheRebase:
+		fixup.Target = base + p.Target()
+	case fixupchains.DyldChainedPtr32FirmwareRebase:
+		fixup.Target = p.Target()
+	case fixupchains.DyldChainedPtrArm64eBind:
+		fixup = f.chainedBind(dcf, p.Ordinal(), p.SignExtendedAddend())
+	case fixupchains.DyldChainedPtrArm64eBind24:
+		fixup = f.chainedBind(dcf, p.Ordinal(), p.SignExtendedAddend())
+	case fixupchains.DyldChainedPtrArm64eAuthBind:
+		fixup = f.chainedBind(dcf, p.Ordinal(), 0)
+		fixup.Auth = &PAC{Key: p.Key(), Diversity: p.Diversity(), AddrDiv: p.AddrDiv() == 1}
+	case fixupchains.DyldChainedPtrArm64eAuthBind24:
+		fixup = f.chainedBind(dcf, p.Ordinal(), 0)
+		fixup.Auth = &PAC{Key: p.Key(), Diversity: p.Diversity(), AddrDiv: p.AddrDiv() == 1}
+	case fixupchains.DyldChainedPtr64Bind:
+		fixup = f.chainedBind(dcf, p.Ordinal(), int64(p.Addend()))
+	case fixupchains.DyldChainedPtr32Bind:
+		fixup = f.chainedBind(dcf, p.Ordinal(), int64(p.Addend()))
+	default:
+		return fixup, false
+	}
+
+	return fixup, true
+}
+
+func (f *File) chainedBind(dcf *fixupchains.DyldChainedFixups, ordinal uint64, addend int64) Fixup {
+	fixup := Fixup{Kind: FixupBind, Addend: addend}
+	if ordinal < uint64(len(dcf.Imports)) {
+		imp := dcf.Imports[ordinal]
+		fixup.Name = imp.Name
+		fixup.Ordinal = imp.LibOrdinal()
+		fixup.Dylib = f.LibraryOrdinalName(fixup.Ordinal)
+		fixup.Weak = imp.WeakImport()
+		fixup.Addend += int64(imp.Import.Addend())
+	}
+	return fixup
+}
+
+func (f *File) dyldInfoFixups() ([]Fixup, error) {
+	var fixups []Fixup
+
+	rebases, err := f.Rebases()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rebases: %v", err)
+	}
+	for _, r := range rebases {
+		seg := f.Segment(r.Segment)
+		fixup := Fixup{
+			Kind:    FixupRebase,
+			Segment: r.Segment,
+			Section: r.Section,
+			Offset:  seg.Offset + r.Offset,
+			Address: r.Address,
+		}
+		// the unslid target is stored in the slot itself (encoded like an arm64e chained pointer if threaded)
+		if r.Threaded {
+			raw, err := f.readUint64(int64(fixup.Offset))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read threaded rebase at offset %#x: %v", fixup.Offset, err)
+			}
+			fx, err := fixupchains.DecodePointer(fixupchains.DYLD_CHAINED_PTR_ARM64E, fixup.Offset, raw)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode threaded rebase at offset %#x: %v", fixup.Offset, err)
+			}
+			decoded, _ := f.chainedFixup(nil, fixupchains.DYLD_CHAINED_PTR_ARM64E, 0, f.preferredLoadAddress(), fx)
+			fixup.Target = decoded.Target
+			fixup.Auth = decoded.Auth
+		} else if f.is64bit() && r.Type == types.REBASE_TYPE_POINTER {
+			if fixup.Target, err = f.readUint64(int64(fixup.Offset)); err != nil {
+				return nil, fmt.Errorf("failed to read rebase target at offset %#x: %v", fixup.Offset, err)
+			}
+		} else {
+			t32, err := f.readUint32(int64(fixup.Offset))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read rebase target at offset %#x: %v", fixup.Offset, err)
+			}
+			fixup.Target = uint64(t32)
+		}
+		fixups = append(fixups, fixup)
+	}
+
+	for _, bindsFn := range []func() ([]Bind, error){f.Binds, f.WeakBinds, f.LazyBinds} {
+		binds, err := bindsFn()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get binds: %v", err)
+		}
+		for _, b := range binds {
+			if b.Flags.NonWeakDefinition() {
+				continue // strong definitions do not have a slot
+			}
+			fixups = append(fixups, Fixup{
+				Kind:    FixupBind,
+				Segment: b.Segment,
+				Section: b.Section,
+				Offset:  f.Segment(b.Segment).Offset + b.Offset,
+				Address: b.Address,
+				Name:    b.Name,
+				Dylib:   b.Dylib,
+				Ordinal: b.Ordinal,
+				Addend:  b.Addend,
+				Weak:    b.Flags.WeakImport(),
+			})
+		}
+	}
+
+	return fixups, nil
+}
+
+// relocBase returns the address the r_address of the LC_DYSYMTAB relocations is relative to; like dyld,
+// the first writable segment for x86_64 and split segment images (but never for kexts), and the first
+// segment otherwise (arm64 included)
+func (f *File) relocBase() uint64 {
+	firstWritable := (f.CPU == types.CPUAmd64 || f.Flags.SplitSegs()) && f.Type != types.KextBundle
+	var base uint64
+	for i, seg := range f.Segments() {
+		if i == 0 {
+			base = seg.Addr
+			if !firstWritable {
+				break
+			}
+		}
+		if seg.Prot.Write() {
+			return seg.Addr
+		}
+	}
+	return base
+}
+
+// classicFixups returns the fixups of an image linked without LC_DYLD_INFO or LC_DYLD_CHAINED_FIXUPS;
+// local relocations are rebases, external relocations and the indirect symbol pointer sections are binds
+func (f *File) classicFixups() ([]Fixup, error) {
+	var fixups []Fixup
+
+	if err := f.Dysymtab.TablesErr(); err != nil {
+		return nil, err
+	}
+
+	relocBase := f.relocBase()
+
+	slot := func(addr uint64, size uint8) (Fixup, error) {
+		fixup := Fixup{Address: addr}
+		off, err := f.GetOffset(addr)
+		if err != nil {
+			return fixup, err
+		}
+		fixup.Offset = off
+		if sec := f.FindSectionForVMAddr(addr); sec != nil {
+			fixup.Segment = sec.Seg
+			fixup.Section = sec.Name
+		}
+		if size == 3 {
+			fixup.Target, err = f.readUint64(int64(off))
+		} else {
+			var t32 uint32
+			t32, err = f.readUint32(int64(off))
+			fixup.Target = uint64(t32)
+		}
+		if err != nil {
+			return fixup, fmt.Errorf("failed to read slot at offset %#x: %v", off, err)
+		}
+		return fixup, nil
+	}
+
+	bind := func(fixup *Fixup, symIdx uint32) error {
+		if f.Symtab == nil || int(symIdx) >= len(f.Symtab.Syms) {
+			return fmt.Errorf("symbol index %d out of range", symIdx)
+		}
+		sym := f.Symtab.Syms[symIdx]
+		fixup.Kind = FixupBind
+		fixup.Name = sym.Name
+		fixup.Weak = sym.Desc&types.WEAK_REF != 0
+		switch ord := sym.Desc.GetLibraryOrdinal(); {
+		case !f.Flags.TwoLevel() || ord == types.DYNAMIC_LOOKUP_ORDINAL:
+			fixup.Ordinal = types.BIND_SPECIAL_DYLIB_FLAT_LOOKUP
+		case ord == types.EXECUTABLE_ORDINAL:
+			fixup.Ordinal = types.BIND_SPECIAL_DYLIB_MAIN_EXECUTABLE
+		default:
+			fixup.Ordinal = int(ord)
+		}
+		fixup.Dylib = f.LibraryOrdinalName(fixup.Ordinal)
+		return nil
+	}
+
+	for _, rel := range f.Dysymtab.LocRelocs {
+		fixup, err := slot(relocBase+uint64(rel.Addr), rel.Len)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local relocation at %#x: %v", relocBase+uint64(rel.Addr), err)
+		}
+		fixup.Kind = FixupRebase
+		fixups = append(fixups, fixup)
+	}
+
+	for _, rel := range f.Dysymtab.ExtRelocs {
+		if rel.Scattered || !rel.Extern {
+			continue
+		}
+		fixup, err := slot(relocBase+uint64(rel.Addr), rel.Len)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read external relocation at %#x: %v", relocBase+uint64(rel.Addr), err)
+		}
+		// the addend is stored in the slot itself
+		fixup.Addend = int64(fixup.Target)
+		fixup.Target = 0
+		if err := bind(&fixup, rel.Value); err != nil {
+			return nil, fmt.Errorf("failed to bind external relocation at %#x: %v", fixup.Address, err)
+		}
+		fixups = append(fixups, fixup)
+	}
+
+	isyms, err := f.IndirectSymbols()
+	if err != nil {
+		return nil, err
+	}
+	for _, isym := range isyms {
+		if isym.Local() || isym.Absolute() {
+			continue
+		}
+		if sec := f.Section(isym.Segment, isym.Section); sec == nil || sec.Flags.IsSymbolStubs() {
+			continue
+		}
+		size := uint8(2)
+		if f.is64bit() {
+			size = 3
+		}
+		fixup, err := slot(isym.Address, size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read symbol pointer at %#x: %v", isym.Address, err)
+		}
+		fixup.Target = 0
+		if err := bind(&fixup, isym.SymIndex); err != nil {
+			return nil, fmt.Errorf("failed to bind symbol pointer at %#x: %v", fixup.Address, err)
+		}
+		fixups = append(fixups, fixup)
+	}
+
+	return fixups, nil
+}
+
+// A Pointer is the resolved value of a pointer sized slot.
+// Slots without a fixup are returned as a rebase to the value stored in the slot.
+type Pointer struct {
+	Fixup
+	Raw uint64 // value stored in the slot on disk
+}
+
+// IsBind reports whether the slot is bound to an imported symbol.
+func (p Pointer) IsBind() bool {
+	return p.Kind == FixupBind
+}
+
+// ReadPointer reads the pointer slot at a given virtual address and applies its fixup,
+// returning either the rebased target address or the import the slot is bound to.
+// An error is returned for every slot if the image's fixups are malformed.
+func (f *File) ReadPointer(vmaddr uint64) (*Pointer, error) {
+	off, err := f.vma.GetOffset(vmaddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offset for vmaddr %#x: %v", vmaddr, err)
+	}
+	return f.readPointer(vmaddr, off)
+}
+
+// ReadPointerAtOffset reads the pointer slot at a given file offset and applies its fixup,
+// returning either the rebased target address or the import the slot is bound to.
+func (f *File) ReadPointerAtOffset(offset uint64) (*Pointer, error) {
+	vmaddr, err := f.vma.GetVMAddress(offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vmaddr for offset %#x: %v", offset, err)
+	}
+	return f.readPointer(vmaddr, offset)
+}
+
+func (f *File) readPointer(vmaddr, offset uint64) (*Pointer, error) {
+	var ptr Pointer
+
+	if f.is64bit() {
+		raw, err := f.readUint64(int64(offset))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pointer at offset %#x: %v", offset, err)
+		}
+		ptr.Raw = raw
+	} else {
+		raw, err := f.readUint32(int64(offset))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pointer at offset %#x: %v", offset, err)
+		}
+		ptr.Raw = uint64(raw)
+	}
+
+	f.fixupOnce.Do(f.buildFixupIndex)
+	if f.fixupIndexErr != nil {
+		return nil, f.fixupIndexErr
+	}
+
+	if idx, ok := f.fixupIndex[vmaddr]; ok {
+		ptr.Fixup = f.fixups[idx]
+		return &ptr, nil
+	}
+
+	ptr.Fixup = Fixup{
+		Kind:    FixupRebase,
+		Offset:  offset,
+		Address: vmaddr,
+		Target:  ptr.Raw,
+	}
+	if len(f.fixupIndex) == 0 {
+		// no fixup info (i.e. a dyld_shared_cache image), so let the converter resolve the value
+		ptr.Target = f.vma.Convert(ptr.Raw)
+	}
+	if seg := f.FindSegmentForVMAddr(vmaddr); seg != nil {
+		ptr.Segment = seg.Name
+	}
+	if sec := f.FindSectionForVMAddr(vmaddr); sec != nil {
+		ptr.Section = sec.Name
+	}
+
+	return &ptr, nil
+}
+
+// buildFixupIndex indexes the fixups by slot address; images without any fixup info are left without an index
+func (f *File) buildFixupIndex() {
+	if !f.HasFixups() && f.DyldInfo() == nil && !f.hasClassicFixups() {
+		return
+	}
+	fixups, err := f.Fixups()
+	if err != nil {
+		f.fixupIndexErr = fmt.Errorf("failed to read fixups: %v", err)
+		return
+	}
+	f.fixupIndex = make(map[uint64]int, len(fixups))
+	for idx, fixup := range fixups {
+		// lazy pointers are both rebased and bound, the bind is what they resolve to
+		if prev, ok := f.fixupIndex[fixup.Address]; ok && fixups[prev].Kind == FixupBind {
+			continue
+		}
+		f.fixupIndex[fixup.Address] = idx
+	}
+}
@@ -0,0 +1,187 @@
+package macho
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+func TestFixupsWithoutFixupsAreCached(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-386-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fixups, err := f.Fixups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixups) != 0 {
+		t.Fatalf("got %d fixups, want none", len(fixups))
+	}
+	if f.fixups == nil {
+		t.Error("an image without fixups is parsed again on every call")
+	}
+}
+
+func TestReadPointer(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		addr   uint64
+		bind   string
+		target uint64
+	}{
+		{addr: 0x100001000, bind: "dyld_stub_binder"},
+		{addr: 0x100001008, target: 0},
+		{addr: 0x100001010, bind: "_printf"}, // a lazy pointer is both rebased and bound
+		{addr: 0x100000f90, target: 0x41000000711d8d4c},
+	}
+	for _, tt := range tests {
+		ptr, err := f.ReadPointer(tt.addr)
+		if err != nil {
+			t.Fatalf("%#x: %v", tt.addr, err)
+		}
+		if len(tt.bind) > 0 {
+			if !ptr.IsBind() || ptr.Name != tt.bind || ptr.Dylib != "libSystem.B.dylib" {
+				t.Errorf("%#x: got %s, want a bind to %s", tt.addr, ptr, tt.bind)
+			}
+		} else if ptr.IsBind() || ptr.Target != tt.target {
+			t.Errorf("%#x: got %s, want a rebase to %#x", tt.addr, ptr, tt.target)
+		}
+	}
+
+	if ptr, err := f.ReadPointerAtOffset(0x1010); err != nil {
+		t.Fatal(err)
+	} else if ptr.Address != 0x100001010 || ptr.Name != "_printf" {
+		t.Errorf("got %s at offset 0x1010, want a bind to _printf", ptr)
+	}
+
+	if _, err := f.ReadPointer(0x200000000); err == nil {
+		t.Error("expected an error for an unmapped address")
+	}
+}
+
+func TestReadPointerMalformedFixups(t *testing.T) {
+	f, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		data[f.DyldInfo().BindOff] = 0xe0 // unknown bind opcode
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// the error is cached rather than falling back to the raw pointer values
+	for i := 0; i < 2; i++ {
+		if _, err := f.ReadPointer(0x100001008); err == nil {
+			t.Error("expected an error for a malformed bind stream")
+		}
+	}
+}
+
+func TestRelocBase(t *testing.T) {
+	const (
+		amd64 = "internal/testdata/gcc-amd64-darwin-exec.base64"
+		i386  = "internal/testdata/gcc-386-darwin-exec.base64"
+	)
+	tests := []struct {
+		name  string
+		file  string
+		patch func(data []byte)
+		write bool // the first writable segment rather than the first segment
+	}{
+		{name: "x86_64", file: amd64, write: true},
+		{name: "x86_64 kext", file: amd64, patch: func(data []byte) {
+			binary.LittleEndian.PutUint32(data[12:], uint32(types.KextBundle))
+		}},
+		{name: "arm64", file: amd64, patch: func(data []byte) {
+			binary.LittleEndian.PutUint32(data[4:], uint32(types.CPUArm64))
+		}},
+		{name: "i386", file: i386},
+		{name: "i386 split segs", file: i386, write: true, patch: func(data []byte) {
+			binary.LittleEndian.PutUint32(data[24:], binary.LittleEndian.Uint32(data[24:])|uint32(types.SplitSegs))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := openPatchedTestFile(t, tt.file, func(_ *File, data []byte) []byte {
+				if tt.patch != nil {
+					tt.patch(data)
+				}
+				return data
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			want := f.Segments()[0].Addr
+			if tt.write {
+				for _, seg := range f.Segments() {
+					if seg.Prot.Write() {
+						want = seg.Addr
+						break
+					}
+				}
+			}
+			if got := f.relocBase(); got != want {
+				t.Errorf("got relocation base %#x, want %#x", got, want)
+			}
+		})
+	}
+}
+
+func TestClassicFixups(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fixups, err := f.Fixups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var binds []uint64
+	for _, fx := range fixups {
+		if fx.Kind == FixupBind {
+			binds = append(binds, fx.Address)
+		}
+	}
+	if len(binds) != 2 || binds[0] != 0x100001058 || binds[1] != 0x100001060 {
+		t.Errorf("got binds at %#x, want 0x100001058 and 0x100001060", binds)
+	}
+}
+
+func TestDysymtabTablesMalformed(t *testing.T) {
+	// the counts of the LC_DYSYMTAB tables
+	for _, off := range []int{36, 44, 52, 68, 76} {
+		// the file still opens with the table left empty
+		f, err := openPatchedTestFile(t, "internal/testdata/gcc-amd64-darwin-exec.base64", func(f *File, data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[loadCmdOffset(f, f.Dysymtab)+off:], 0xffffffff)
+			return data
+		})
+		if err != nil {
+			t.Fatalf("LC_DYSYMTAB+%d: %v", off, err)
+		}
+		st := f.Dysymtab
+		if st.TablesErr() == nil {
+			t.Errorf("expected an error for a bogus count at LC_DYSYMTAB+%d", off)
+		}
+		if st.TableOfContents != nil || st.Modules != nil || st.ExtRefSyms != nil || st.ExtRelocs != nil || st.LocRelocs != nil {
+			t.Errorf("LC_DYSYMTAB+%d: the bad table is not empty", off)
+		}
+		// the classic binds and rebases are the relocations
+		if _, err := f.Fixups(); err == nil {
+			t.Errorf("LC_DYSYMTAB+%d: expected a fixups error", off)
+		}
+		f.Close()
+	}
+}
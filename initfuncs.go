@@ -0,0 +1,190 @@
+package macho
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// An InitFunc is a module initializer or terminator; a function dyld runs before main (or at exit).
+type InitFunc struct {
+	Segment string
+	Section string
+	Slot    uint64 // address of the pointer (or 32-bit offset) to the function
+	Address uint64 // address of the function (0 if it is an unresolved import)
+	Name    string // symbol name of the function (if known)
+	Import  bool   // the slot is bound to an imported function
+}
+
+func (i InitFunc) String() string {
+	name := i.Name
+	if len(name) == 0 {
+		name = "?"
+	}
+	if i.Import {
+		return fmt.Sprintf("%#016x  %s.%s\t-> %s (import)", i.Slot, i.Segment, i.Section, name)
+	}
+	return fmt.Sprintf("%#016x  %s.%s\t-> %#016x %s", i.Slot, i.Segment, i.Section, i.Address, name)
+}
+
+// InitFunctions returns the module initializers from the S_MOD_INIT_FUNC_POINTERS (i.e. __mod_init_func)
+// and S_INIT_FUNC_OFFSETS (i.e. __init_offsets) sections in the order dyld runs them.
+func (f *File) InitFunctions() ([]InitFunc, error) {
+	var funcs []InitFunc
+	for _, sec := range f.Sections {
+		switch {
+		case sec.Flags.IsModInitFuncPointers():
+			fns, err := f.initFuncPointers(sec)
+			if err != nil {
+				return nil, err
+			}
+			funcs = append(funcs, fns...)
+		case sec.Flags.IsInitFuncOffsets():
+			fns, err := f.initFuncOffsets(sec)
+			if err != nil {
+				return nil, err
+			}
+			funcs = append(funcs, fns...)
+		}
+	}
+	return funcs, nil
+}
+
+// TermFunctions returns the module terminators from the S_MOD_TERM_FUNC_POINTERS (i.e. __mod_term_func) sections.
+func (f *File) TermFunctions() ([]InitFunc, error) {
+	var funcs []InitFunc
+	for _, sec := range f.Sections {
+		if sec.Flags.IsModTermFuncPointers() {
+			fns, err := f.initFuncPointers(sec)
+			if err != nil {
+				return nil, err
+			}
+			funcs = append(funcs, fns...)
+		}
+	}
+	return funcs, nil
+}
+
+// initFuncPointers resolves a section of function pointers through the image's fixups (or an object's relocations)
+func (f *File) initFuncPointers(sec *Section) ([]InitFunc, error) {
+	var funcs []InitFunc
+
+	ptrSize := f.pointerSize()
+
+	if f.Type == types.Obj {
+		relocs, err := sec.DecodedRelocations()
+		if err != nil {
+			return nil, err
+		}
+		relocAt := make(map[uint64]Relocation)
+		for _, rel := range relocs {
+			relocAt[uint64(rel.Offset)] = rel
+		}
+		dat, err := sec.Data()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+		}
+		for off := uint64(0); off+ptrSize <= uint64(len(dat)); off += ptrSize {
+			fn := InitFunc{
+				Segment: sec.Seg,
+				Section: sec.Name,
+				Slot:    sec.Addr + off,
+			}
+			if ptrSize == 8 {
+				fn.Address = f.ByteOrder.Uint64(dat[off:])
+			} else {
+				fn.Address = uint64(f.ByteOrder.Uint32(dat[off:]))
+			}
+			if rel, ok := relocAt[off]; ok && rel.Target.Extern {
+				sym := f.Symtab.Syms[rel.Target.SymIndex]
+				fn.Name = sym.Name
+				if sym.Type.IsDefinedInSection() {
+					fn.Address = uint64(int64(sym.Value) + rel.Addend)
+				} else {
+					fn.Address = 0
+					fn.Import = true
+				}
+			} else {
+				fn.Name = f.symbolNameForAddr(fn.Address)
+			}
+			funcs = append(funcs, fn)
+		}
+		return funcs, nil
+	}
+
+	for off := uint64(0); off+ptrSize <= sec.Size; off += ptrSize {
+		ptr, err := f.ReadPointer(sec.Addr + off)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s pointer at %#x: %v", sec.Seg, sec.Name, sec.Addr+off, err)
+		}
+		fn := InitFunc{
+			Segment: sec.Seg,
+			Section: sec.Name,
+			Slot:    sec.Addr + off,
+		}
+		if ptr.IsBind() {
+			fn.Name = ptr.Name
+			fn.Import = true
+		} else {
+			fn.Address = ptr.Target
+			fn.Name = f.symbolNameForAddr(fn.Address)
+		}
+		funcs = append(funcs, fn)
+	}
+
+	return funcs, nil
+}
+
+// initFuncOffsets resolves a section of 32-bit initializer offsets from the mach header
+func (f *File) initFuncOffsets(sec *Section) ([]InitFunc, error) {
+	dat, err := sec.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+	}
+
+	offsets := make([]uint32, len(dat)/binary.Size(uint32(0)))
+	for i := range offsets {
+		offsets[i] = f.ByteOrder.Uint32(dat[i*4:])
+	}
+
+	var funcs []InitFunc
+
+	base := f.GetBaseAddress()
+	for i, off := range offsets {
+		fn := InitFunc{
+			Segment: sec.Seg,
+			Section: sec.Name,
+			Slot:    sec.Addr + uint64(i*4),
+			Address: base + uint64(off),
+		}
+		fn.Name = f.symbolNameForAddr(fn.Address)
+		funcs = append(funcs, fn)
+	}
+
+	return funcs, nil
+}
+
+// symbolNameForAddr returns the name of the symbol at the given address, preferring external symbols
+func (f *File) symbolNameForAddr(addr uint64) string {
+	if f.Symtab == nil {
+		return ""
+	}
+	syms, err := f.FindAddressSymbols(addr)
+	if err != nil {
+		return ""
+	}
+	var name string
+	for _, sym := range syms {
+		if sym.Type.IsDebugSym() {
+			continue
+		}
+		if sym.Type.IsExternalSym() {
+			return sym.Name
+		}
+		if len(name) == 0 {
+			name = sym.Name
+		}
+	}
+	return name
+}
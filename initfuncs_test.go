@@ -0,0 +1,142 @@
+package macho
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// sectionHeaderOffset returns the file offset of a 64-bit section's header
+func sectionHeaderOffset(f *File, sec *Section) int {
+	for _, l := range f.Loads {
+		seg, ok := l.(*Segment)
+		if !ok {
+			continue
+		}
+		for j := uint32(0); j < seg.Nsect; j++ {
+			if f.Sections[seg.Firstsect+j] == sec {
+				return loadCmdOffset(f, seg) + binary.Size(types.Segment64{}) + int(j)*binary.Size(types.Section64{})
+			}
+		}
+	}
+	return -1
+}
+
+// setSectionType patches the type of a 64-bit section
+func setSectionType(f *File, data []byte, sec *Section, typ types.SectionFlag) {
+	flags := data[sectionHeaderOffset(f, sec)+64:]
+	binary.LittleEndian.PutUint32(flags, binary.LittleEndian.Uint32(flags)&^uint32(types.SectionType)|uint32(typ))
+}
+
+func TestInitFunctionsObject(t *testing.T) {
+	// llvm-mc can't emit S_INIT_FUNC_OFFSETS sections
+	f, err := openPatchedTestFile(t, "internal/testdata/initfuncs-arm64-darwin.obj.base64", func(f *File, data []byte) []byte {
+		setSectionType(f, data, f.Section("__TEXT", "__init_offsets"), types.InitFuncOffsets)
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	inits, err := f.InitFunctions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []InitFunc{
+		{Segment: "__DATA", Section: "__mod_init_func", Slot: 0x10, Address: 0x0, Name: "_ctor"},
+		{Segment: "__DATA", Section: "__mod_init_func", Slot: 0x18, Address: 0x4, Name: "_ctor2"},
+		{Segment: "__DATA", Section: "__mod_init_func", Slot: 0x20, Name: "_extctor", Import: true},
+		{Segment: "__TEXT", Section: "__init_offsets", Slot: 0x30, Address: 0x4, Name: "_ctor2"},
+		{Segment: "__TEXT", Section: "__init_offsets", Slot: 0x34, Address: 0x8, Name: "_dtor"},
+	}
+	if len(inits) != len(want) {
+		t.Fatalf("got %d initializers, want %d", len(inits), len(want))
+	}
+	for i, fn := range inits {
+		if fn != want[i] {
+			t.Errorf("initializer %d: got %s, want %s", i, fn, want[i])
+		}
+	}
+
+	terms, err := f.TermFunctions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(terms) != 1 || terms[0].Address != 0x8 || terms[0].Name != "_dtor" {
+		t.Errorf("got terminators %v, want _dtor", terms)
+	}
+}
+
+func TestInitFunctionsImage(t *testing.T) {
+	// the non-lazy pointers as initializers and the lazy pointers as terminators
+	f, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		setSectionType(f, data, f.Section("__DATA", "__nl_symbol_ptr"), types.ModInitFuncPointers)
+		setSectionType(f, data, f.Section("__DATA", "__la_symbol_ptr"), types.ModTermFuncPointers)
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	inits, err := f.InitFunctions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inits) != 2 || !inits[0].Import || inits[0].Name != "dyld_stub_binder" || inits[1].Import || inits[1].Slot != 0x100001008 {
+		t.Errorf("got initializers %v, want dyld_stub_binder and an unbound pointer", inits)
+	}
+	terms, err := f.TermFunctions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(terms) != 1 || !terms[0].Import || terms[0].Name != "_printf" {
+		t.Errorf("got terminators %v, want _printf", terms)
+	}
+
+	// __init_offsets are relative to the mach header
+	f, err = openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		sec := f.Section("__TEXT", "__cstring")
+		setSectionType(f, data, sec, types.InitFuncOffsets)
+		binary.LittleEndian.PutUint32(data[sec.Offset:], 0xf60)
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	inits, err = f.InitFunctions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inits) == 0 || inits[0].Address != 0x100000f60 || inits[0].Name != "_main" {
+		t.Errorf("got initializers %v, want _main first", inits)
+	}
+}
+
+func TestInitFunctionsMalformed(t *testing.T) {
+	f, err := openPatchedTestFile(t, "internal/testdata/initfuncs-arm64-darwin.obj.base64", func(f *File, data []byte) []byte {
+		// move the __mod_init_func contents past the end of the file
+		binary.LittleEndian.PutUint32(data[sectionHeaderOffset(f, f.Section("__DATA", "__mod_init_func"))+48:], 0x100000)
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.InitFunctions(); err == nil {
+		t.Error("expected an error for an unreadable __mod_init_func section")
+	}
+
+	f, err = openObscured("internal/testdata/initfuncs-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Section("__DATA", "__mod_term_func").Relocs[0].Value = 0xffffff // symbol index
+	if _, err := f.TermFunctions(); err == nil {
+		t.Error("expected an error for a relocation to an unknown symbol")
+	}
+}
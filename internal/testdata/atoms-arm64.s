@@ -0,0 +1,25 @@
+/* llvm-mc -triple arm64-apple-macos -filetype=obj atoms-arm64.s -o atoms-arm64-darwin.obj */
+	.section __TEXT,__text,regular,pure_instructions
+	.globl _a
+	.p2align 2
+_a:
+	bl _b
+	adrp x0, Lstr@PAGE
+	add x0, x0, Lstr@PAGEOFF
+	ret
+	.alt_entry _a2
+_a2:
+	ret
+	.no_dead_strip _b
+_b:
+ltmp_b_alias:
+	bl _ext
+	ret
+	.section __TEXT,__cstring,cstring_literals
+Lstr:
+	.asciz "hello"
+	.asciz "world"
+	.literal8
+	.quad 1
+	.quad 2
+	.subsections_via_symbols
@@ -0,0 +1,34 @@
+/* llvm-mc -triple arm64-apple-macos -filetype=obj ehframe-arm64.s -o ehframe-arm64-darwin.obj */
+	.section __TEXT,__text,regular,pure_instructions
+	.globl _f
+	.p2align 2
+_f:
+	.cfi_startproc
+	.cfi_personality 155, ___gxx_personality_v0
+	.cfi_lsda 16, Lexc
+	stp x29, x30, [sp, #-16]!
+	.cfi_def_cfa_offset 16
+	mov x29, sp
+	.cfi_def_cfa w29, 16
+	.cfi_offset w30, -8
+	.cfi_offset w29, -16
+	.cfi_remember_state
+	sub sp, sp, #32
+	.cfi_escape 0x2e, 0x20
+	.cfi_restore_state
+	ldp x29, x30, [sp], #16
+	.cfi_def_cfa sp, 0
+	ret
+	.cfi_endproc
+	.globl _g
+_g:
+	.cfi_startproc
+	.cfi_def_cfa_offset 32
+	.cfi_offset w19, -24
+	.cfi_undefined w20
+	.cfi_register w21, w22
+	ret
+	.cfi_endproc
+	.section __TEXT,__gcc_except_tab
+Lexc:
+	.long 0
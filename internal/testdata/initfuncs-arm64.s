@@ -0,0 +1,22 @@
+/* llvm-mc -triple arm64-apple-macos -filetype=obj initfuncs-arm64.s -o initfuncs-arm64-darwin.obj */
+/* the tests patch __init_offsets to S_INIT_FUNC_OFFSETS */
+	.section __TEXT,__text,regular,pure_instructions
+	.p2align 2
+_ctor:
+	ret
+	.globl _ctor2
+_ctor2:
+	ret
+_dtor:
+	ret
+	.section __DATA,__mod_init_func,mod_init_funcs
+	.p2align 3
+	.quad _ctor
+	.quad _ctor2
+	.quad _extctor
+	.section __DATA,__mod_term_func,mod_term_funcs
+	.p2align 3
+	.quad _dtor
+	.section __TEXT,__init_offsets,regular
+	.long 4
+	.long 8
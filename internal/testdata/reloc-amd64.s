@@ -0,0 +1,15 @@
+/* llvm-mc -triple x86_64-apple-macos -filetype=obj reloc-amd64.s -o reloc-amd64-darwin.obj */
+.text
+.globl _f
+_f:
+  movq _g+8(%rip), %rax
+  movb $1, _g(%rip)
+  call _ext
+  leaq Lfoo(%rip), %rax
+  ret
+Lfoo: .byte 0
+.data
+_g: .quad 0,0
+_p: .quad _ext+8
+_d: .quad _g - _p + 4
+_l: .quad Lfoo
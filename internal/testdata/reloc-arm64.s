@@ -0,0 +1,18 @@
+/* llvm-mc -triple arm64-apple-macos -filetype=obj reloc-arm64.s -o reloc-arm64-darwin.obj */
+.text
+.globl _f
+_f:
+  adrp x0, _g@PAGE+16
+  add x0, x0, _g@PAGEOFF+16
+  adrp x1, _ext@GOTPAGE
+  ldr x1, [x1, _ext@GOTPAGEOFF]
+  bl _ext2
+  b Lloc
+Lloc:
+  ret
+.data
+_g: .quad 0, 0, 0
+_p: .quad _ext+8
+_d: .quad _g - _p
+_e: .long _ext - .
+.subsections_via_symbols
@@ -0,0 +1,29 @@
+; llc -mtriple=arm64-apple-macos -filetype=obj symbolicate-arm64.ll -o symbolicate-arm64-darwin.obj
+target datalayout = "e-m:o-i64:64-i128:128-n32:64-S128"
+target triple = "arm64-apple-macosx11.0.0"
+
+@g = global i32 0, align 4
+
+define void @outer() !dbg !10 {
+entry:
+  store volatile i32 1, i32* @g, align 4, !dbg !13
+  store volatile i32 2, i32* @g, align 4, !dbg !14
+  store volatile i32 3, i32* @g, align 4, !dbg !16
+  ret void, !dbg !17
+}
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!3, !4}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_C99, file: !1, producer: "test", isOptimized: true, runtimeVersion: 0, emissionKind: FullDebug)
+!1 = !DIFile(filename: "sym.c", directory: "/src")
+!3 = !{i32 7, !"Dwarf Version", i32 4}
+!4 = !{i32 2, !"Debug Info Version", i32 3}
+!5 = !DISubroutineType(types: !{null})
+!10 = distinct !DISubprogram(name: "outer", scope: !1, file: !1, line: 10, type: !5, scopeLine: 10, spFlags: DISPFlagDefinition | DISPFlagOptimized, unit: !0)
+!11 = distinct !DISubprogram(name: "inner", scope: !1, file: !1, line: 2, type: !5, scopeLine: 2, spFlags: DISPFlagDefinition | DISPFlagOptimized, unit: !0)
+!13 = !DILocation(line: 10, column: 5, scope: !10)
+!14 = !DILocation(line: 3, column: 7, scope: !11, inlinedAt: !15)
+!15 = distinct !DILocation(line: 11, column: 3, scope: !10)
+!16 = !DILocation(line: 12, column: 5, scope: !10)
+!17 = !DILocation(line: 13, column: 1, scope: !10)
@@ -0,0 +1,20 @@
+/* llvm-mc -triple x86_64-apple-macos -filetype=obj tlv-amd64.s -o tlv-amd64-darwin.obj */
+	.text
+	.globl _f
+_f:
+	movq _a@TLVP(%rip), %rdi
+	callq *(%rdi)
+	movq _ext@TLVP(%rip), %rdi
+	callq *(%rdi)
+	ret
+	.section __DATA,__thread_data,thread_local_regular
+_a$tlv$init:
+	.quad 42
+	.section __DATA,__thread_vars,thread_local_variables
+	.p2align 3
+	.globl _a
+_a:
+	.quad __tlv_bootstrap
+	.quad 0
+	.quad _a$tlv$init
+.subsections_via_symbols
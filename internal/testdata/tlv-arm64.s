@@ -0,0 +1,30 @@
+/* llvm-mc -triple arm64-apple-macos -filetype=obj tlv-arm64.s -o tlv-arm64-darwin.obj */
+	.section __TEXT,__text,regular,pure_instructions
+	.globl _f
+	.p2align 2
+_f:
+	adrp x0, _a@TLVPPAGE
+	ldr x0, [x0, _a@TLVPPAGEOFF]
+	ldr x8, [x0]
+	blr x8
+	adrp x0, _b@TLVPPAGE
+	ldr x0, [x0, _b@TLVPPAGEOFF]
+	ret
+	.section __DATA,__thread_data,thread_local_regular
+	.p2align 2
+_a$tlv$init:
+	.long 42
+	.quad 7
+	.section __DATA,__thread_vars,thread_local_variables
+	.globl _a
+_a:
+	.quad __tlv_bootstrap
+	.quad 0
+	.quad _a$tlv$init
+	.globl _b
+_b:
+	.quad __tlv_bootstrap
+	.quad 0
+	.quad _b$tlv$init
+.tbss _b$tlv$init, 16, 3
+.subsections_via_symbols
@@ -0,0 +1,28 @@
+/* llvm-mc -triple arm64-apple-macos -filetype=obj unwind-arm64.s -o unwind-arm64-darwin.obj */
+	.section __TEXT,__text,regular,pure_instructions
+	.globl _f
+	.p2align 2
+_f:
+	.cfi_startproc
+	.cfi_personality 155, ___gxx_personality_v0
+	.cfi_lsda 16, Lexc
+	stp x29, x30, [sp, #-16]!
+	mov x29, sp
+	.cfi_def_cfa w29, 16
+	.cfi_offset w30, -8
+	.cfi_offset w29, -16
+	bl _g
+	ldp x29, x30, [sp], #16
+	ret
+	.cfi_endproc
+	.globl _g
+	.p2align 2
+_g:
+	.cfi_startproc
+	ret
+	.cfi_endproc
+	.section __TEXT,__gcc_except_tab
+	.p2align 2
+Lexc:
+	.long 0
+.subsections_via_symbols
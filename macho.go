@@ -12,6 +12,14 @@
 
 package macho
 
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/blacktop/go-macho/types"
+)
+
 // Regs386 is the Mach-O 386 register structure.
 type Regs386 struct {
 	AX    uint32
@@ -116,3 +124,174 @@ type RegsARM64 struct {
 	CPSR uint32
 	PAD  uint32
 }
+
+// RegsPPC is the Mach-O PowerPC register structure.
+type RegsPPC struct {
+	SRR0   uint32 // program counter
+	SRR1   uint32 // machine state register
+	R      [32]uint32
+	CR     uint32
+	XER    uint32
+	LR     uint32
+	CTR    uint32
+	MQ     uint32
+	VRSAVE uint32
+}
+
+// RegsPPC64 is the Mach-O PowerPC 64 register structure.
+type RegsPPC64 struct {
+	SRR0   uint64 // program counter
+	SRR1   uint64 // machine state register
+	R      [32]uint64
+	CR     uint32
+	XER    uint64
+	LR     uint64
+	CTR    uint64
+	VRSAVE uint32
+}
+
+// A ThreadState is a single flavor of register state in a LC_THREAD or LC_UNIXTHREAD command.
+// General purpose register flavors are decoded into Regs as a *Regs386, *RegsAMD64, *RegsARM,
+// *RegsARM64, *RegsPPC or *RegsPPC64; all other flavors (float, exception, debug, vector)
+// are left as raw Data tagged with their Kind, as are unsupported or truncated general purpose states.
+type ThreadState struct {
+	CPU    types.CPU
+	Flavor types.ThreadFlavor
+	Kind   types.ThreadStateKind
+	Count  uint32 // size of Data in uint32 words
+	Data   []byte
+	Regs   interface{}
+}
+
+func (t ThreadState) String() string {
+	return fmt.Sprintf("%s (%s, count %d)", t.Flavor.Name(t.CPU), t.Kind, t.Count)
+}
+
+// PC returns the program counter of a general purpose register state.
+func (t ThreadState) PC() (uint64, bool) {
+	switch regs := t.Regs.(type) {
+	case *Regs386:
+		return uint64(regs.IP), true
+	case *RegsAMD64:
+		return regs.IP, true
+	case *RegsARM:
+		return uint64(regs.PC), true
+	case *RegsARM64:
+		return regs.PC, true
+	case *RegsPPC:
+		return uint64(regs.SRR0), true
+	case *RegsPPC64:
+		return regs.SRR0, true
+	}
+	return 0, false
+}
+
+// SP returns the stack pointer of a general purpose register state.
+func (t ThreadState) SP() (uint64, bool) {
+	switch regs := t.Regs.(type) {
+	case *Regs386:
+		return uint64(regs.SP), true
+	case *RegsAMD64:
+		return regs.SP, true
+	case *RegsARM:
+		return uint64(regs.SP), true
+	case *RegsARM64:
+		return regs.SP, true
+	case *RegsPPC:
+		return uint64(regs.R[1]), true
+	case *RegsPPC64:
+		return regs.R[1], true
+	}
+	return 0, false
+}
+
+// parseThreadStates parses the flavor/count/state entries that follow a thread command's header
+func parseThreadStates(cpu types.CPU, bo binary.ByteOrder, data []byte) ([]ThreadState, error) {
+	var states []ThreadState
+
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		var hdr struct {
+			Flavor types.ThreadFlavor
+			Count  uint32
+		}
+		if err := binary.Read(r, bo, &hdr); err != nil {
+			return nil, fmt.Errorf("failed to read thread state header: %v", err)
+		}
+		if hdr.Count > uint32(r.Len())/4 {
+			return nil, fmt.Errorf("thread state %s count %d extends past end of command", hdr.Flavor.Name(cpu), hdr.Count)
+		}
+		state := ThreadState{
+			CPU:    cpu,
+			Flavor: hdr.Flavor,
+			Kind:   hdr.Flavor.Kind(cpu),
+			Count:  hdr.Count,
+			Data:   make([]byte, hdr.Count*4),
+		}
+		if _, err := r.Read(state.Data); err != nil {
+			return nil, fmt.Errorf("failed to read thread state %s: %v", hdr.Flavor.Name(cpu), err)
+		}
+		if state.Kind == types.ThreadStateGeneral {
+			// unsupported flavors and states smaller than their register struct keep only the raw Data
+			if regs, err := decodeThreadRegs(cpu, bo, hdr.Flavor, state.Data); err == nil {
+				state.Regs = regs
+			}
+		}
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// decodeThreadRegs decodes a general purpose register state into its typed register struct
+func decodeThreadRegs(cpu types.CPU, bo binary.ByteOrder, flavor types.ThreadFlavor, data []byte) (interface{}, error) {
+	var regs interface{}
+
+	switch cpu {
+	case types.CPU386, types.CPUAmd64:
+		switch flavor {
+		case types.X86_THREAD_STATE32:
+			regs = new(Regs386)
+		case types.X86_THREAD_STATE64, types.X86_THREAD_FULL_STATE64:
+			regs = new(RegsAMD64)
+		case types.X86_THREAD_STATE: // x86_state_hdr followed by a 32 or 64-bit state
+			if len(data) < 8 {
+				return nil, fmt.Errorf("x86_THREAD_STATE is too small")
+			}
+			return decodeThreadRegs(cpu, bo, types.ThreadFlavor(bo.Uint32(data)), data[8:])
+		}
+	case types.CPUArm, types.CPUArm64, types.CPUArm6432:
+		switch flavor {
+		case types.ARM_THREAD_STATE:
+			if cpu == types.CPUArm {
+				regs = new(RegsARM)
+				break
+			}
+			// arm_unified_thread_state: arm_state_hdr followed by a 32 or 64-bit state
+			if len(data) < 8 {
+				return nil, fmt.Errorf("ARM_THREAD_STATE is too small")
+			}
+			return decodeThreadRegs(cpu, bo, types.ThreadFlavor(bo.Uint32(data)), data[8:])
+		case types.ARM_THREAD_STATE32:
+			regs = new(RegsARM)
+		case types.ARM_THREAD_STATE64:
+			regs = new(RegsARM64)
+		}
+	case types.CPUPpc, types.CPUPpc64:
+		switch flavor {
+		case types.PPC_THREAD_STATE:
+			regs = new(RegsPPC)
+		case types.PPC_THREAD_STATE64:
+			regs = new(RegsPPC64)
+		}
+	}
+
+	if regs == nil {
+		return nil, fmt.Errorf("unsupported thread flavor %s for CPU %s", flavor.Name(cpu), cpu)
+	}
+	if err := binary.Read(bytes.NewReader(data), bo, regs); err != nil {
+		return nil, err
+	}
+
+	return regs, nil
+}
@@ -0,0 +1,111 @@
+package macho
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// threadStateData encodes a flavor/count/state entry of a thread command
+func threadStateData(flavor types.ThreadFlavor, words ...uint32) []byte {
+	data := make([]byte, 8+4*len(words))
+	binary.LittleEndian.PutUint32(data, uint32(flavor))
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(words)))
+	for i, w := range words {
+		binary.LittleEndian.PutUint32(data[8+4*i:], w)
+	}
+	return data
+}
+
+func TestParseThreadStates(t *testing.T) {
+	amd64 := make([]uint32, 42)                  // x86_thread_state64_t
+	amd64[2*16], amd64[2*16+1] = 0x00000f60, 0x1 // rip
+	amd64[2*7] = 0x7ff000                        // rsp
+	data := threadStateData(types.X86_THREAD_STATE64, amd64...)
+	data = append(data, threadStateData(types.X86_EXCEPTION_STATE64, 1, 2, 3, 4)...)
+
+	states, err := parseThreadStates(types.CPUAmd64, binary.LittleEndian, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("got %d thread states, want 2", len(states))
+	}
+	if pc, ok := states[0].PC(); !ok || pc != 0x100000f60 {
+		t.Errorf("got pc %#x (%t), want 0x100000f60", pc, ok)
+	}
+	if sp, ok := states[0].SP(); !ok || sp != 0x7ff000 {
+		t.Errorf("got sp %#x (%t), want 0x7ff000", sp, ok)
+	}
+	if states[1].Regs != nil || states[1].Kind == types.ThreadStateGeneral || len(states[1].Data) != 16 {
+		t.Errorf("unexpected exception state %s", states[1])
+	}
+}
+
+func TestParseThreadStatesDegrades(t *testing.T) {
+	tests := []struct {
+		name string
+		cpu  types.CPU
+		data []byte
+	}{
+		{
+			name: "unsupported unified flavor",
+			cpu:  types.CPUArm64,
+			data: threadStateData(types.ARM_THREAD_STATE, 99, 2, 0, 0),
+		},
+		{
+			name: "count smaller than the registers",
+			cpu:  types.CPUAmd64,
+			data: threadStateData(types.X86_THREAD_STATE64, 1, 2, 3, 4),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states, err := parseThreadStates(tt.cpu, binary.LittleEndian, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(states) != 1 || states[0].Regs != nil || len(states[0].Data) != len(tt.data)-8 {
+				t.Errorf("got %v, want the raw state without registers", states)
+			}
+			if _, ok := states[0].PC(); ok {
+				t.Error("got a pc for an undecoded state")
+			}
+		})
+	}
+}
+
+func TestParseThreadStatesMalformed(t *testing.T) {
+	data := threadStateData(types.X86_THREAD_STATE64, 1, 2)
+	binary.LittleEndian.PutUint32(data[4:], 42) // count past the end of the command
+	if _, err := parseThreadStates(types.CPUAmd64, binary.LittleEndian, data); err == nil {
+		t.Error("expected an error for a count past the end of the command")
+	}
+	if _, err := parseThreadStates(types.CPUAmd64, binary.LittleEndian, data[:6]); err == nil {
+		t.Error("expected an error for a truncated flavor/count header")
+	}
+}
+
+func TestEntryPoint(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		want uint64
+	}{
+		{dyldInfoTestFile, 0x100000f60},                                 // LC_MAIN
+		{"internal/testdata/gcc-amd64-darwin-exec.base64", 0x100000f14}, // LC_UNIXTHREAD (start)
+	} {
+		f, err := openObscured(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := f.EntryPoint()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != tt.want {
+			t.Errorf("%s: got entry point %#x, want %#x", tt.file, entry, tt.want)
+		}
+	}
+}
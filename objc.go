@@ -287,15 +287,16 @@ func (f *File) GetObjCClass(vmaddr uint64) (*objc.Class, error) {
 	superClass := &objc.Class{Name: "<ROOT>"}
 	if classPtr.SuperclassVMAddr > 0 {
 		if !info.Flags.IsRoot() {
-			superClass, err = f.GetObjCClass(f.vma.Convert(classPtr.SuperclassVMAddr))
+			ptr, err := f.ReadPointer(vmaddr + sizeOfInt64) // superclass
 			if err != nil {
-				if f.HasFixups() {
-					bindName, err := f.GetBindName(classPtr.SuperclassVMAddr)
-					if err == nil {
-						superClass = &objc.Class{Name: strings.TrimPrefix(bindName, "_OBJC_CLASS_$_")}
-					} else {
-						return nil, fmt.Errorf("failed to read super class objc_class_t at vmaddr: 0x%x; %v", vmaddr, err)
-					}
+				return nil, fmt.Errorf("failed to read super class pointer at vmaddr: 0x%x; %v", vmaddr, err)
+			}
+			if ptr.IsBind() {
+				superClass = &objc.Class{Name: strings.TrimPrefix(ptr.Name, "_OBJC_CLASS_$_")}
+			} else {
+				superClass, err = f.GetObjCClass(ptr.Target)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read super class objc_class_t at vmaddr: 0x%x; %v", vmaddr, err)
 				}
 			}
 		}
@@ -305,15 +306,17 @@ func (f *File) GetObjCClass(vmaddr uint64) (*objc.Class, error) {
 	var cMethods []objc.Method
 	if classPtr.IsaVMAddr > 0 {
 		if !info.Flags.IsMeta() {
-			isaClass, err = f.GetObjCClass(f.vma.Convert(classPtr.IsaVMAddr))
+			ptr, err := f.ReadPointer(vmaddr) // isa
 			if err != nil {
-				bindName, err := f.GetBindName(classPtr.IsaVMAddr)
-				if err == nil {
-					isaClass = &objc.Class{Name: strings.TrimPrefix(bindName, "_OBJC_CLASS_$_")}
-				} else {
-					return nil, fmt.Errorf("failed to read super class objc_class_t at vmaddr: 0x%x; %v", vmaddr, err)
-				}
+				return nil, fmt.Errorf("failed to read isa pointer at vmaddr: 0x%x; %v", vmaddr, err)
+			}
+			if ptr.IsBind() {
+				isaClass = &objc.Class{Name: strings.TrimPrefix(ptr.Name, "_OBJC_CLASS_$_")}
 			} else {
+				isaClass, err = f.GetObjCClass(ptr.Target)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read isa objc_class_t at vmaddr: 0x%x; %v", vmaddr, err)
+				}
 				if isaClass.ReadOnlyData.Flags.IsMeta() {
 					cMethods = isaClass.InstanceMethods
 				}
@@ -933,7 +936,6 @@ func (f *File) GetObjCProperties(vmAddr uint64) ([]objc.Property, error) {
 }
 
 func (f *File) GetObjCClassReferences() (map[uint64]*objc.Class, error) {
-	var classPtrs []uint64
 	clsRefs := make(map[uint64]*objc.Class)
 
 	for _, s := range f.Segments() {
@@ -943,26 +945,20 @@ func (f *File) GetObjCClassReferences() (map[uint64]*objc.Class, error) {
 					return nil, fmt.Errorf("%s.%s section has size 0", sec.Seg, sec.Name)
 				}
 
-				dat, err := sec.Data()
-				if err != nil {
-					return nil, fmt.Errorf("failed to read __objc_classrefs: %v", err)
-				}
-
-				classPtrs = make([]uint64, sec.Size/8)
-				if err := binary.Read(bytes.NewReader(dat), f.ByteOrder, &classPtrs); err != nil {
-					return nil, fmt.Errorf("failed to read class ref pointers: %v", err)
-				}
-
-				for idx, ptr := range classPtrs {
-					if cls, err := f.GetObjCClass(f.vma.Convert(ptr)); err != nil {
-						if bindName, err := f.GetBindName(ptr); err == nil {
-							clsRefs[sec.Addr+uint64(idx*sizeOfInt64)] = &objc.Class{Name: strings.TrimPrefix(bindName, "_OBJC_CLASS_$_")}
-						} else {
-							return nil, fmt.Errorf("failed to read objc_class_t at classref ptr: %#x; %v", ptr, err)
-						}
-					} else {
-						clsRefs[sec.Addr+uint64(idx*sizeOfInt64)] = cls
+				for idx := uint64(0); idx < sec.Size/sizeOfInt64; idx++ {
+					ptr, err := f.ReadPointer(sec.Addr + idx*sizeOfInt64)
+					if err != nil {
+						return nil, fmt.Errorf("failed to read class ref pointer: %v", err)
+					}
+					if ptr.IsBind() {
+						clsRefs[ptr.Address] = &objc.Class{Name: strings.TrimPrefix(ptr.Name, "_OBJC_CLASS_$_")}
+						continue
+					}
+					cls, err := f.GetObjCClass(ptr.Target)
+					if err != nil {
+						return nil, fmt.Errorf("failed to read objc_class_t at classref ptr: %#x; %v", ptr.Raw, err)
 					}
+					clsRefs[ptr.Address] = cls
 				}
 				return clsRefs, nil
 			}
@@ -972,7 +968,6 @@ func (f *File) GetObjCClassReferences() (map[uint64]*objc.Class, error) {
 }
 
 func (f *File) GetObjCSuperReferences() (map[uint64]*objc.Class, error) {
-	var classPtrs []uint64
 	clsRefs := make(map[uint64]*objc.Class)
 
 	for _, s := range f.Segments() {
@@ -982,26 +977,20 @@ func (f *File) GetObjCSuperReferences() (map[uint64]*objc.Class, error) {
 					return nil, fmt.Errorf("%s.%s section has size 0", sec.Seg, sec.Name)
 				}
 
-				dat, err := sec.Data()
-				if err != nil {
-					return nil, fmt.Errorf("failed to read __objc_superrefs: %v", err)
-				}
-
-				classPtrs = make([]uint64, sec.Size/8)
-				if err := binary.Read(bytes.NewReader(dat), f.ByteOrder, &classPtrs); err != nil {
-					return nil, fmt.Errorf("failed to read super ref pointers: %v", err)
-				}
-
-				for idx, ptr := range classPtrs {
-					if cls, err := f.GetObjCClass(f.vma.Convert(ptr)); err != nil {
-						if bindName, err := f.GetBindName(ptr); err == nil {
-							clsRefs[sec.Addr+uint64(idx*sizeOfInt64)] = &objc.Class{Name: strings.TrimPrefix(bindName, "_OBJC_CLASS_$_")}
-						} else {
-							return nil, fmt.Errorf("failed to read objc_class_t at superref ptr: %#x; %v", ptr, err)
-						}
-					} else {
-						clsRefs[sec.Addr+uint64(idx*sizeOfInt64)] = cls
+				for idx := uint64(0); idx < sec.Size/sizeOfInt64; idx++ {
+					ptr, err := f.ReadPointer(sec.Addr + idx*sizeOfInt64)
+					if err != nil {
+						return nil, fmt.Errorf("failed to read super ref pointer: %v", err)
+					}
+					if ptr.IsBind() {
+						clsRefs[ptr.Address] = &objc.Class{Name: strings.TrimPrefix(ptr.Name, "_OBJC_CLASS_$_")}
+						continue
+					}
+					cls, err := f.GetObjCClass(ptr.Target)
+					if err != nil {
+						return nil, fmt.Errorf("failed to read objc_class_t at superref ptr: %#x; %v", ptr.Raw, err)
 					}
+					clsRefs[ptr.Address] = cls
 				}
 				return clsRefs, nil
 			}
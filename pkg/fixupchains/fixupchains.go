@@ -81,6 +81,9 @@ func (dcf *DyldChainedFixups) ParseStarts() error {
 	if err := binary.Read(dcf.r, dcf.bo, &segCount); err != nil {
 		return err
 	}
+	if uint64(segCount)*4 > uint64(dcf.r.Len()) {
+		return fmt.Errorf("chained starts segment count %d is larger than the fixups data", segCount)
+	}
 
 	dcf.Starts = make([]DyldChainedStarts, segCount)
 	segInfoOffsets := make([]uint32, segCount)
@@ -107,11 +110,20 @@ func (dcf *DyldChainedFixups) ParseStarts() error {
 	return nil
 }
 
+// importName returns the name of the import a bind ordinal references
+func (dcf *DyldChainedFixups) importName(ordinal uint64) (string, error) {
+	if ordinal >= uint64(len(dcf.Imports)) {
+		return "", fmt.Errorf("bind ordinal %d out of range (%d imports)", ordinal, len(dcf.Imports))
+	}
+	return dcf.Imports[ordinal].Name, nil
+}
+
 func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, offsetInPage DCPtrStart) error {
 
 	var dcPtr uint32
 	var dcPtr64 uint64
 	var next uint64
+	var err error
 
 	chainEnd := false
 	segOffset := dcf.Starts[segIdx].DyldChainedStartsInSegment.SegmentOffset
@@ -130,7 +142,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 			}
 			if Generic32IsBind(dcPtr) {
 				bind := DyldChainedPtr32Bind{Pointer: dcPtr, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			} else {
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtr32Rebase{
@@ -172,7 +186,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 			}
 			if Generic64IsBind(dcPtr64) {
 				bind := DyldChainedPtr64Bind{Pointer: dcPtr64, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			} else {
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtr64Rebase{
@@ -188,10 +204,18 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 			if err := binary.Read(dcf.sr, dcf.bo, &dcPtr64); err != nil {
 				return err
 			}
-			dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtr64RebaseOffset{
-				Pointer: dcPtr64,
-				Fixup:   fixupLocation,
-			})
+			if Generic64IsBind(dcPtr64) {
+				bind := DyldChainedPtr64Bind{Pointer: dcPtr64, Fixup: fixupLocation}
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
+				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
+			} else {
+				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtr64RebaseOffset{
+					Pointer: dcPtr64,
+					Fixup:   fixupLocation,
+				})
+			}
 			if Generic64Next(dcPtr64) == 0 {
 				chainEnd = true
 			}
@@ -231,7 +255,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				})
 			} else if DcpArm64eIsBind(dcPtr64) && !DcpArm64eIsAuth(dcPtr64) {
 				bind := DyldChainedPtrArm64eBind{Pointer: dcPtr64, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			} else if !DcpArm64eIsBind(dcPtr64) && DcpArm64eIsAuth(dcPtr64) {
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eAuthRebase{
@@ -240,7 +266,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				})
 			} else {
 				bind := DyldChainedPtrArm64eAuthBind{Pointer: dcPtr64, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			}
 			if DcpArm64eNext(dcPtr64) == 0 {
@@ -258,7 +286,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				})
 			} else if DcpArm64eIsBind(dcPtr64) && !DcpArm64eIsAuth(dcPtr64) {
 				bind := DyldChainedPtrArm64eBind{Pointer: dcPtr64, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			} else if !DcpArm64eIsBind(dcPtr64) && DcpArm64eIsAuth(dcPtr64) {
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eAuthRebase{
@@ -267,7 +297,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				})
 			} else {
 				bind := DyldChainedPtrArm64eAuthBind{Pointer: dcPtr64, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			}
 			if DcpArm64eNext(dcPtr64) == 0 {
@@ -287,7 +319,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				})
 			} else if DcpArm64eIsBind(dcPtr64) && !DcpArm64eIsAuth(dcPtr64) {
 				bind := DyldChainedPtrArm64eBind{Pointer: dcPtr64, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			} else if !DcpArm64eIsBind(dcPtr64) && DcpArm64eIsAuth(dcPtr64) {
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eAuthRebase{
@@ -296,7 +330,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				})
 			} else {
 				bind := DyldChainedPtrArm64eAuthBind{Pointer: dcPtr64, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			}
 			if DcpArm64eNext(dcPtr64) == 0 {
@@ -314,7 +350,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				})
 			} else if DcpArm64eIsBind(dcPtr64) && DcpArm64eIsAuth(dcPtr64) {
 				bind := DyldChainedPtrArm64eAuthBind24{Pointer: dcPtr64, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			} else if !DcpArm64eIsBind(dcPtr64) && DcpArm64eIsAuth(dcPtr64) {
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eAuthRebase24{
@@ -323,7 +361,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				})
 			} else if DcpArm64eIsBind(dcPtr64) && !DcpArm64eIsAuth(dcPtr64) {
 				bind := DyldChainedPtrArm64eBind24{Pointer: dcPtr64, Fixup: fixupLocation}
-				bind.Import = dcf.Imports[bind.Ordinal()].Name
+				if bind.Import, err = dcf.importName(bind.Ordinal()); err != nil {
+					return err
+				}
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			}
 			if DcpArm64eNext(dcPtr64) == 0 {
@@ -386,3 +426,54 @@ func (dcf *DyldChainedFixups) parseImports() error {
 
 	return nil
 }
+
+// DecodePointer decodes a raw chained pointer of the given format (bind import names are left empty)
+func DecodePointer(pointerFormat DCPtrKind, fixupLocation, ptr uint64) (Fixup, error) {
+	switch pointerFormat {
+	case DYLD_CHAINED_PTR_32:
+		if Generic32IsBind(uint32(ptr)) {
+			return DyldChainedPtr32Bind{Pointer: uint32(ptr), Fixup: fixupLocation}, nil
+		}
+		return DyldChainedPtr32Rebase{Pointer: uint32(ptr), Fixup: fixupLocation}, nil
+	case DYLD_CHAINED_PTR_32_CACHE:
+		return DyldChainedPtr32CacheRebase{Pointer: uint32(ptr), Fixup: fixupLocation}, nil
+	case DYLD_CHAINED_PTR_32_FIRMWARE:
+		return DyldChainedPtr32FirmwareRebase{Pointer: uint32(ptr), Fixup: fixupLocation}, nil
+	case DYLD_CHAINED_PTR_64:
+		if Generic64IsBind(ptr) {
+			return DyldChainedPtr64Bind{Pointer: ptr, Fixup: fixupLocation}, nil
+		}
+		return DyldChainedPtr64Rebase{Pointer: ptr, Fixup: fixupLocation}, nil
+	case DYLD_CHAINED_PTR_64_OFFSET:
+		if Generic64IsBind(ptr) {
+			return DyldChainedPtr64Bind{Pointer: ptr, Fixup: fixupLocation}, nil
+		}
+		return DyldChainedPtr64RebaseOffset{Pointer: ptr, Fixup: fixupLocation}, nil
+	case DYLD_CHAINED_PTR_64_KERNEL_CACHE, DYLD_CHAINED_PTR_X86_64_KERNEL_CACHE:
+		return DyldChainedPtr64KernelCacheRebase{Pointer: ptr, Fixup: fixupLocation}, nil
+	case DYLD_CHAINED_PTR_ARM64E, DYLD_CHAINED_PTR_ARM64E_KERNEL, DYLD_CHAINED_PTR_ARM64E_USERLAND, DYLD_CHAINED_PTR_ARM64E_FIRMWARE:
+		if DcpArm64eIsBind(ptr) {
+			if DcpArm64eIsAuth(ptr) {
+				return DyldChainedPtrArm64eAuthBind{Pointer: ptr, Fixup: fixupLocation}, nil
+			}
+			return DyldChainedPtrArm64eBind{Pointer: ptr, Fixup: fixupLocation}, nil
+		}
+		if DcpArm64eIsAuth(ptr) {
+			return DyldChainedPtrArm64eAuthRebase{Pointer: ptr, Fixup: fixupLocation}, nil
+		}
+		return DyldChainedPtrArm64eRebase{Pointer: ptr, Fixup: fixupLocation}, nil
+	case DYLD_CHAINED_PTR_ARM64E_USERLAND24:
+		if DcpArm64eIsBind(ptr) {
+			if DcpArm64eIsAuth(ptr) {
+				return DyldChainedPtrArm64eAuthBind24{Pointer: ptr, Fixup: fixupLocation}, nil
+			}
+			return DyldChainedPtrArm64eBind24{Pointer: ptr, Fixup: fixupLocation}, nil
+		}
+		if DcpArm64eIsAuth(ptr) {
+			return DyldChainedPtrArm64eAuthRebase24{Pointer: ptr, Fixup: fixupLocation}, nil
+		}
+		return DyldChainedPtrArm64eRebase24{Pointer: ptr, Fixup: fixupLocation}, nil
+	default:
+		return nil, fmt.Errorf("unknown pointer format %#04X", pointerFormat)
+	}
+}
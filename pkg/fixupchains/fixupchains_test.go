@@ -0,0 +1,82 @@
+package fixupchains
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// chainedFixupsData returns a LC_DYLD_CHAINED_FIXUPS payload with one segment (at offset 0 of
+// the file) whose only page has a DYLD_CHAINED_PTR_64 chain starting at offset 0
+func chainedFixupsData(importsCount uint32) []byte {
+	var buf bytes.Buffer
+	hdr := DyldChainedFixupsHeader{
+		StartsOffset:  28,
+		ImportsOffset: 28 + 8 + 24,
+		ImportsCount:  importsCount,
+		ImportsFormat: DC_IMPORT,
+	}
+	hdr.SymbolsOffset = hdr.ImportsOffset + 4*importsCount
+	binary.Write(&buf, binary.LittleEndian, hdr)
+	binary.Write(&buf, binary.LittleEndian, []uint32{1, 8}) // seg_count, seg_info_offset[0]
+	binary.Write(&buf, binary.LittleEndian, DyldChainedStartsInSegment{
+		Size:          24,
+		PageSize:      0x1000,
+		PointerFormat: DYLD_CHAINED_PTR_64,
+		PageCount:     1,
+	})
+	binary.Write(&buf, binary.LittleEndian, uint16(0)) // page_start[0]
+	for i := uint32(0); i < importsCount; i++ {
+		binary.Write(&buf, binary.LittleEndian, uint32(1|1<<9)) // lib ordinal 1, name offset 1
+	}
+	buf.WriteString("\x00_sym\x00")
+	return buf.Bytes()
+}
+
+func parseChainedFixups(t *testing.T, importsCount uint32, ptrs ...uint64) (*DyldChainedFixups, error) {
+	t.Helper()
+	var seg bytes.Buffer
+	binary.Write(&seg, binary.LittleEndian, ptrs)
+	sr := io.NewSectionReader(bytes.NewReader(seg.Bytes()), 0, int64(seg.Len()))
+	return NewChainedFixups(bytes.NewReader(chainedFixupsData(importsCount)), sr, binary.LittleEndian).Parse()
+}
+
+func TestParse(t *testing.T) {
+	// a bind of import 0 followed (next=2, a 4-byte stride) by a rebase to 0x1000
+	dcf, err := parseChainedFixups(t, 1, 1<<63|2<<51, 0x1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dcf.Imports) != 1 || dcf.Imports[0].Name != "_sym" {
+		t.Fatalf("unexpected imports %v", dcf.Imports)
+	}
+	binds, rebases := dcf.Starts[0].Binds(), dcf.Starts[0].Rebases()
+	if len(binds) != 1 || binds[0].Name() != "_sym" || binds[0].Offset() != 0 {
+		t.Errorf("unexpected binds %v", binds)
+	}
+	if len(rebases) != 1 || rebases[0].Target() != 0x1000 || rebases[0].Offset() != 8 {
+		t.Errorf("unexpected rebases %v", rebases)
+	}
+}
+
+func TestParseBindOrdinalOutOfRange(t *testing.T) {
+	if _, err := parseChainedFixups(t, 1, 1<<63|3); err == nil {
+		t.Error("expected an error for a bind ordinal beyond the imports")
+	}
+	if _, err := parseChainedFixups(t, 0, 1<<63); err == nil {
+		t.Error("expected an error for a bind without imports")
+	}
+}
+
+func TestParseStartsMalformed(t *testing.T) {
+	data := chainedFixupsData(0)
+	binary.LittleEndian.PutUint32(data[28:], 0xffffffff) // seg_count
+	sr := io.NewSectionReader(bytes.NewReader(nil), 0, 0)
+	if _, err := NewChainedFixups(bytes.NewReader(data), sr, binary.LittleEndian).Parse(); err == nil {
+		t.Error("expected an error for a segment count larger than the data")
+	}
+	if _, err := NewChainedFixups(bytes.NewReader(data[:20]), sr, binary.LittleEndian).Parse(); err == nil {
+		t.Error("expected an error for a truncated header")
+	}
+}
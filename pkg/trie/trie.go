@@ -68,6 +68,38 @@ func ReadUleb128(r *bytes.Reader) (uint64, error) {
 	return result, nil
 }
 
+func ReadSleb128(r *bytes.Reader) (int64, error) {
+	var result int64
+	var shift uint64
+	var b byte
+	var err error
+
+	for {
+		b, err = r.ReadByte()
+		if err == io.EOF {
+			return 0, err
+		}
+		if err != nil {
+			return 0, fmt.Errorf("could not parse SLEB128 value: %v", err)
+		}
+
+		result |= int64(b&0x7f) << shift
+		shift += 7
+
+		// If high order bit is 1.
+		if (b & 0x80) == 0 {
+			break
+		}
+	}
+
+	// sign extend negative numbers
+	if shift < 64 && (b&0x40) != 0 {
+		result |= -1 << shift
+	}
+
+	return result, nil
+}
+
 func ReadUleb128FromBuffer(buf *bytes.Buffer) (uint64, int, error) {
 
 	var (
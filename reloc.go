@@ -0,0 +1,418 @@
+package macho
+
+import (
+	"fmt"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// A RelocTarget is what a relocation refers to; a symbol (extern), a section (non-extern) or an address (scattered).
+type RelocTarget struct {
+	Symbol       string // target symbol name (extern relocations)
+	SymIndex     uint32 // index into Symtab.Syms (extern relocations)
+	Extern       bool
+	SectionIndex uint32 // 1-based section ordinal of the target (0 if unknown or extern)
+	Segment      string
+	Section      string
+	Addr         uint64 // target address (non-extern and scattered relocations)
+}
+
+func (t RelocTarget) String() string {
+	if t.Extern {
+		return t.Symbol
+	}
+	if len(t.Section) > 0 {
+		return fmt.Sprintf("%s.%s(%#x)", t.Segment, t.Section, t.Addr)
+	}
+	return fmt.Sprintf("%#x", t.Addr)
+}
+
+// A Relocation is a decoded, CPU typed relocation with its
+// ARM64_RELOC_ADDEND, *_RELOC_SUBTRACTOR and *_RELOC_PAIR entries joined.
+//
+// The value the linker stores at the fixup location is:
+//
+//	Target + Addend [- Subtrahend] [- (Addr + Size) if PCRel]
+type Relocation struct {
+	Offset     uint32          // offset of the fixup location from the start of the section (r_address)
+	Addr       uint64          // address of the fixup location
+	Type       types.RelocType // types.RelocTypeX86_64, types.RelocTypeARM64, types.RelocTypeARM or types.RelocTypeGeneric
+	Size       uint8           // size of the fixup location in bytes
+	PCRel      bool
+	Scattered  bool
+	Target     RelocTarget
+	Subtrahend *RelocTarget // set for SUBTRACTOR and SECTDIFF pairs
+	Addend     int64
+}
+
+func (r Relocation) String() string {
+	var pcrel string
+	if r.PCRel {
+		pcrel = " pcrel"
+	}
+	var minus string
+	if r.Subtrahend != nil {
+		minus = " - " + r.Subtrahend.String()
+	}
+	var addend string
+	if r.Addend != 0 {
+		addend = fmt.Sprintf(" + %#x", r.Addend)
+	}
+	return fmt.Sprintf("%#016x %-30s %d%s %s%s%s", r.Addr, r.Type, r.Size, pcrel, r.Target, minus, addend)
+}
+
+// DecodedRelocations returns the section's relocations decoded for the file's CPU;
+// paired relocation entries are joined into a single Relocation and implicit addends are read from the section contents.
+func (s *Section) DecodedRelocations() ([]Relocation, error) {
+	if s.file == nil {
+		return nil, fmt.Errorf("section %s.%s is not part of a parsed file", s.Seg, s.Name)
+	}
+
+	var relocs []Relocation
+
+	for i := 0; i < len(s.Relocs); i++ {
+		rel := s.Relocs[i]
+
+		var next *Reloc
+		if i+1 < len(s.Relocs) {
+			next = &s.Relocs[i+1]
+		}
+
+		var r Relocation
+		var paired bool
+		var err error
+
+		switch s.file.CPU {
+		case types.CPUAmd64:
+			r, paired, err = s.decodeX86_64Reloc(rel, next)
+		case types.CPUArm64, types.CPUArm6432:
+			r, paired, err = s.decodeArm64Reloc(rel, next)
+		case types.CPUArm:
+			r, paired, err = s.decodeArmReloc(rel, next)
+		default:
+			r, paired, err = s.decodeGenericReloc(rel, next)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s.%s relocation %d: %v", s.Seg, s.Name, i, err)
+		}
+		if paired {
+			i++
+		}
+		relocs = append(relocs, r)
+	}
+
+	return relocs, nil
+}
+
+func (s *Section) newRelocation(rel Reloc, typ types.RelocType) (Relocation, error) {
+	r := Relocation{
+		Offset:    rel.Addr,
+		Addr:      s.Addr + uint64(rel.Addr),
+		Type:      typ,
+		Size:      1 << rel.Len,
+		PCRel:     rel.Pcrel,
+		Scattered: rel.Scattered,
+	}
+	t, err := s.relocTarget(rel)
+	if err != nil {
+		return r, err
+	}
+	r.Target = t
+	return r, nil
+}
+
+func (s *Section) relocTarget(rel Reloc) (RelocTarget, error) {
+	f := s.file
+	switch {
+	case rel.Scattered:
+		t := RelocTarget{Addr: uint64(rel.Value)}
+		for i, sec := range f.Sections {
+			if sec.Addr <= t.Addr && t.Addr < sec.Addr+sec.Size {
+				t.SectionIndex = uint32(i + 1)
+				t.Segment = sec.Seg
+				t.Section = sec.Name
+				break
+			}
+		}
+		return t, nil
+	case rel.Extern:
+		if f.Symtab == nil || int(rel.Value) >= len(f.Symtab.Syms) {
+			return RelocTarget{}, fmt.Errorf("symbol index %d out of range", rel.Value)
+		}
+		return RelocTarget{
+			Symbol:   f.Symtab.Syms[rel.Value].Name,
+			SymIndex: rel.Value,
+			Extern:   true,
+		}, nil
+	default:
+		if rel.Value == 0 { // R_ABS
+			return RelocTarget{}, nil
+		}
+		if int(rel.Value) > len(f.Sections) {
+			return RelocTarget{}, fmt.Errorf("section ordinal %d out of range", rel.Value)
+		}
+		sec := f.Sections[rel.Value-1]
+		return RelocTarget{
+			SectionIndex: rel.Value,
+			Segment:      sec.Seg,
+			Section:      sec.Name,
+		}, nil
+	}
+}
+
+// readRelocContent reads the (sign extended) value stored at the fixup location
+func (s *Section) readRelocContent(offset uint32, size uint8) (int64, error) {
+	if uint64(offset)+uint64(size) > s.Size {
+		return 0, fmt.Errorf("fixup at offset %#x extends past end of section", offset)
+	}
+	if s.Flags.IsZerofill() {
+		return 0, nil
+	}
+	dat := make([]byte, size)
+	if _, err := s.ReadAt(dat, int64(s.Offset)+int64(offset)); err != nil {
+		return 0, fmt.Errorf("failed to read fixup contents at offset %#x: %v", offset, err)
+	}
+	bo := s.file.ByteOrder
+	switch size {
+	case 1:
+		return int64(int8(dat[0])), nil
+	case 2:
+		return int64(int16(bo.Uint16(dat))), nil
+	case 4:
+		return int64(int32(bo.Uint32(dat))), nil
+	default:
+		return int64(bo.Uint64(dat)), nil
+	}
+}
+
+func (s *Section) decodeX86_64Reloc(rel Reloc, next *Reloc) (Relocation, bool, error) {
+	typ := types.RelocTypeX86_64(rel.Type)
+
+	if typ == types.X86_64_RELOC_SUBTRACTOR {
+		if next == nil || types.RelocTypeX86_64(next.Type) != types.X86_64_RELOC_UNSIGNED {
+			return Relocation{}, false, fmt.Errorf("X86_64_RELOC_SUBTRACTOR must be followed by X86_64_RELOC_UNSIGNED")
+		}
+		r, err := s.newRelocation(*next, typ)
+		if err != nil {
+			return r, false, err
+		}
+		minus, err := s.relocTarget(rel)
+		if err != nil {
+			return r, false, err
+		}
+		r.Subtrahend = &minus
+		if r.Addend, err = s.readRelocContent(next.Addr, r.Size); err != nil {
+			return r, false, err
+		}
+		return r, true, nil
+	}
+
+	r, err := s.newRelocation(rel, typ)
+	if err != nil {
+		return r, false, err
+	}
+	content, err := s.readRelocContent(rel.Addr, r.Size)
+	if err != nil {
+		return r, false, err
+	}
+	switch typ {
+	case types.X86_64_RELOC_SIGNED_1:
+		content++
+	case types.X86_64_RELOC_SIGNED_2:
+		content += 2
+	case types.X86_64_RELOC_SIGNED_4:
+		content += 4
+	}
+	if rel.Extern {
+		r.Addend = content
+	} else if rel.Pcrel { // displacement from the end of the 4 byte field
+		r.Target.Addr = uint64(int64(r.Addr) + 4 + content)
+	} else {
+		r.Target.Addr = uint64(content)
+	}
+	return r, false, nil
+}
+
+func (s *Section) decodeArm64Reloc(rel Reloc, next *Reloc) (Relocation, bool, error) {
+	typ := types.RelocTypeARM64(rel.Type)
+
+	switch typ {
+	case types.ARM64_RELOC_ADDEND:
+		if next == nil {
+			return Relocation{}, false, fmt.Errorf("ARM64_RELOC_ADDEND must be followed by another relocation")
+		}
+		r, err := s.newRelocation(*next, types.RelocTypeARM64(next.Type))
+		if err != nil {
+			return r, false, err
+		}
+		r.Addend = int64(int32(rel.Value<<8) >> 8) // sign extend the 24-bit addend
+		return r, true, nil
+	case types.ARM64_RELOC_SUBTRACTOR:
+		if next == nil || types.RelocTypeARM64(next.Type) != types.ARM64_RELOC_UNSIGNED {
+			return Relocation{}, false, fmt.Errorf("ARM64_RELOC_SUBTRACTOR must be followed by ARM64_RELOC_UNSIGNED")
+		}
+		r, err := s.newRelocation(*next, typ)
+		if err != nil {
+			return r, false, err
+		}
+		minus, err := s.relocTarget(rel)
+		if err != nil {
+			return r, false, err
+		}
+		r.Subtrahend = &minus
+		if r.Addend, err = s.readRelocContent(next.Addr, r.Size); err != nil {
+			return r, false, err
+		}
+		return r, true, nil
+	}
+
+	r, err := s.newRelocation(rel, typ)
+	if err != nil {
+		return r, false, err
+	}
+	if typ == types.ARM64_RELOC_UNSIGNED { // only pointers hold an implicit addend (instructions use ARM64_RELOC_ADDEND)
+		content, err := s.readRelocContent(rel.Addr, r.Size)
+		if err != nil {
+			return r, false, err
+		}
+		if rel.Extern {
+			r.Addend = content
+		} else {
+			r.Target.Addr = uint64(content)
+		}
+	}
+	return r, false, nil
+}
+
+func (s *Section) decodeGenericReloc(rel Reloc, next *Reloc) (Relocation, bool, error) {
+	typ := types.RelocTypeGeneric(rel.Type)
+
+	r, err := s.newRelocation(rel, typ)
+	if err != nil {
+		return r, false, err
+	}
+
+	switch typ {
+	case types.GENERIC_RELOC_SECTDIFF, types.GENERIC_RELOC_LOCAL_SECTDIFF:
+		if next == nil || types.RelocTypeGeneric(next.Type) != types.GENERIC_RELOC_PAIR {
+			return r, false, fmt.Errorf("%s must be followed by GENERIC_RELOC_PAIR", typ)
+		}
+		minus, err := s.relocTarget(*next)
+		if err != nil {
+			return r, false, err
+		}
+		r.Subtrahend = &minus
+		content, err := s.readRelocContent(rel.Addr, r.Size)
+		if err != nil {
+			return r, false, err
+		}
+		r.Addend = content - int64(r.Target.Addr) + int64(minus.Addr)
+		return r, true, nil
+	case types.GENERIC_RELOC_PAIR:
+		return r, false, fmt.Errorf("unexpected GENERIC_RELOC_PAIR")
+	}
+
+	if r.Size > 4 {
+		return r, false, nil
+	}
+	content, err := s.readRelocContent(rel.Addr, r.Size)
+	if err != nil {
+		return r, false, err
+	}
+	if rel.Pcrel { // the stored displacement is relative to the end of the fixup location
+		content += int64(r.Addr) + int64(r.Size)
+	}
+	switch {
+	case rel.Extern:
+		r.Addend = content
+	case rel.Scattered:
+		r.Addend = content - int64(r.Target.Addr)
+	default:
+		r.Target.Addr = uint64(content)
+	}
+	return r, false, nil
+}
+
+func (s *Section) decodeArmReloc(rel Reloc, next *Reloc) (Relocation, bool, error) {
+	typ := types.RelocTypeARM(rel.Type)
+
+	r, err := s.newRelocation(rel, typ)
+	if err != nil {
+		return r, false, err
+	}
+
+	switch typ {
+	case types.ARM_RELOC_SECTDIFF, types.ARM_RELOC_LOCAL_SECTDIFF:
+		if next == nil || types.RelocTypeARM(next.Type) != types.ARM_RELOC_PAIR {
+			return r, false, fmt.Errorf("%s must be followed by ARM_RELOC_PAIR", typ)
+		}
+		minus, err := s.relocTarget(*next)
+		if err != nil {
+			return r, false, err
+		}
+		r.Subtrahend = &minus
+		content, err := s.readRelocContent(rel.Addr, r.Size)
+		if err != nil {
+			return r, false, err
+		}
+		r.Addend = content - int64(r.Target.Addr) + int64(minus.Addr)
+		return r, true, nil
+	case types.ARM_RELOC_HALF, types.ARM_RELOC_HALF_SECTDIFF:
+		// r_length encodes the instruction; bit 0 is movt (high half) and bit 1 is thumb
+		if next == nil || types.RelocTypeARM(next.Type) != types.ARM_RELOC_PAIR {
+			return r, false, fmt.Errorf("%s must be followed by ARM_RELOC_PAIR", typ)
+		}
+		r.Size = 4
+		movt := rel.Len&1 != 0
+		thumb := rel.Len&2 != 0
+		if !s.Flags.IsZerofill() {
+			dat := make([]byte, 4)
+			if _, err := s.ReadAt(dat, int64(s.Offset)+int64(rel.Addr)); err != nil {
+				return r, false, fmt.Errorf("failed to read fixup contents at offset %#x: %v", rel.Addr, err)
+			}
+			var imm16 uint32
+			if thumb {
+				hw1 := uint32(s.file.ByteOrder.Uint16(dat[0:]))
+				hw2 := uint32(s.file.ByteOrder.Uint16(dat[2:]))
+				imm16 = (hw1&0xf)<<12 | (hw1>>10&1)<<11 | (hw2>>12&7)<<8 | hw2&0xff
+			} else {
+				ins := s.file.ByteOrder.Uint32(dat)
+				imm16 = (ins>>16&0xf)<<12 | ins&0xfff
+			}
+			other := next.Addr & 0xffff // the PAIR's r_address holds the other half of the value
+			var value uint32
+			if movt {
+				value = imm16<<16 | other
+			} else {
+				value = other<<16 | imm16
+			}
+			r.Addend = int64(int32(value))
+		}
+		if typ == types.ARM_RELOC_HALF_SECTDIFF {
+			minus := RelocTarget{Addr: uint64(next.Value)}
+			r.Subtrahend = &minus
+			r.Addend += int64(minus.Addr)
+		}
+		if rel.Scattered || !rel.Extern {
+			r.Addend -= int64(r.Target.Addr)
+		}
+		return r, true, nil
+	case types.ARM_RELOC_PAIR:
+		return r, false, fmt.Errorf("unexpected ARM_RELOC_PAIR")
+	case types.ARM_RELOC_VANILLA:
+		content, err := s.readRelocContent(rel.Addr, r.Size)
+		if err != nil {
+			return r, false, err
+		}
+		switch {
+		case rel.Extern:
+			r.Addend = content
+		case rel.Scattered:
+			r.Addend = content - int64(r.Target.Addr)
+		default:
+			r.Target.Addr = uint64(content)
+		}
+	}
+	// branch relocations (ARM_RELOC_BR24, ARM_THUMB_RELOC_BR22, ...) encode their addend in the instruction's displacement
+	return r, false, nil
+}
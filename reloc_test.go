@@ -0,0 +1,178 @@
+package macho
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+func TestDecodedRelocations(t *testing.T) {
+	type reloc struct {
+		addr   uint64
+		typ    string
+		target string
+		minus  string
+		addend int64
+		pcrel  bool
+	}
+	tests := []struct {
+		file    string
+		section string
+		want    []reloc
+	}{
+		{
+			file:    "internal/testdata/clang-amd64-darwin.obj.base64",
+			section: "__text",
+			want: []reloc{
+				{addr: 0x19, typ: "X86_64_RELOC_BRANCH", target: "_printf", pcrel: true},
+				{addr: 0xb, typ: "X86_64_RELOC_SIGNED", target: "__TEXT.__cstring(0x2a)", pcrel: true},
+			},
+		},
+		{
+			file:    "internal/testdata/clang-386-darwin.obj.base64",
+			section: "__text",
+			want: []reloc{
+				{addr: 0x1d, typ: "GENERIC_RELOC_VANILLA", target: "_printf", pcrel: true},
+				{addr: 0xe, typ: "GENERIC_RELOC_LOCAL_SECTDIFF", target: "__TEXT.__cstring(0x2d)", minus: "__TEXT.__text(0xb)"},
+			},
+		},
+		{
+			file:    "internal/testdata/reloc-amd64-darwin.obj.base64",
+			section: "__text",
+			want: []reloc{
+				{addr: 0xf, typ: "X86_64_RELOC_BRANCH", target: "_ext", pcrel: true},
+				{addr: 0x9, typ: "X86_64_RELOC_SIGNED_1", target: "_g", pcrel: true},
+				{addr: 0x3, typ: "X86_64_RELOC_SIGNED", target: "_g", addend: 8, pcrel: true},
+			},
+		},
+		{
+			file:    "internal/testdata/reloc-amd64-darwin.obj.base64",
+			section: "__data",
+			want: []reloc{
+				{addr: 0x3c, typ: "X86_64_RELOC_UNSIGNED", target: "_f", addend: 0x1b},
+				{addr: 0x34, typ: "X86_64_RELOC_SUBTRACTOR", target: "_g", minus: "_p", addend: 4},
+				{addr: 0x2c, typ: "X86_64_RELOC_UNSIGNED", target: "_ext", addend: 8},
+			},
+		},
+		{
+			file:    "internal/testdata/reloc-arm64-darwin.obj.base64",
+			section: "__text",
+			want: []reloc{
+				{addr: 0x10, typ: "ARM64_RELOC_BRANCH26", target: "_ext2", pcrel: true},
+				{addr: 0xc, typ: "ARM64_RELOC_GOT_LOAD_PAGEOFF12", target: "_ext"},
+				{addr: 0x8, typ: "ARM64_RELOC_GOT_LOAD_PAGE21", target: "_ext", pcrel: true},
+				{addr: 0x4, typ: "ARM64_RELOC_PAGEOFF12", target: "_g", addend: 0x10},
+				{addr: 0x0, typ: "ARM64_RELOC_PAGE21", target: "_g", addend: 0x10, pcrel: true},
+			},
+		},
+		{
+			file:    "internal/testdata/reloc-arm64-darwin.obj.base64",
+			section: "__data",
+			want: []reloc{
+				{addr: 0x44, typ: "ARM64_RELOC_SUBTRACTOR", target: "_ext", minus: "_e"},
+				{addr: 0x3c, typ: "ARM64_RELOC_SUBTRACTOR", target: "_g", minus: "_p"},
+				{addr: 0x34, typ: "ARM64_RELOC_UNSIGNED", target: "_ext", addend: 8},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file+":"+tt.section, func(t *testing.T) {
+			f, err := openObscured(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			sec := f.Section("__TEXT", tt.section)
+			if sec == nil {
+				sec = f.Section("__DATA", tt.section)
+			}
+			relocs, err := sec.DecodedRelocations()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(relocs) != len(tt.want) {
+				t.Fatalf("got %d relocations, want %d", len(relocs), len(tt.want))
+			}
+			for i, r := range relocs {
+				var minus string
+				if r.Subtrahend != nil {
+					minus = r.Subtrahend.String()
+				}
+				got := reloc{r.Addr, fmt.Sprint(r.Type), r.Target.String(), minus, r.Addend, r.PCRel}
+				if got != tt.want[i] {
+					t.Errorf("relocation %d: got %+v, want %+v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodedRelocationsMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		relocs []Reloc
+	}{
+		{
+			name:   "x86_64 subtractor without unsigned",
+			file:   "internal/testdata/reloc-amd64-darwin.obj.base64",
+			relocs: []Reloc{{Addr: 0, Type: uint8(types.X86_64_RELOC_SUBTRACTOR), Len: 3, Extern: true, Value: 0}},
+		},
+		{
+			name:   "arm64 trailing addend",
+			file:   "internal/testdata/reloc-arm64-darwin.obj.base64",
+			relocs: []Reloc{{Addr: 0, Type: uint8(types.ARM64_RELOC_ADDEND), Len: 2, Value: 0x10}},
+		},
+		{
+			name:   "arm64 subtractor without unsigned",
+			file:   "internal/testdata/reloc-arm64-darwin.obj.base64",
+			relocs: []Reloc{{Addr: 0, Type: uint8(types.ARM64_RELOC_SUBTRACTOR), Len: 3, Extern: true}},
+		},
+		{
+			name:   "symbol index out of range",
+			file:   "internal/testdata/reloc-amd64-darwin.obj.base64",
+			relocs: []Reloc{{Addr: 0, Type: uint8(types.X86_64_RELOC_UNSIGNED), Len: 3, Extern: true, Value: 0xffffff}},
+		},
+		{
+			name:   "section ordinal out of range",
+			file:   "internal/testdata/reloc-amd64-darwin.obj.base64",
+			relocs: []Reloc{{Addr: 0, Type: uint8(types.X86_64_RELOC_UNSIGNED), Len: 3, Value: 99}},
+		},
+		{
+			name:   "fixup past the end of the section",
+			file:   "internal/testdata/reloc-amd64-darwin.obj.base64",
+			relocs: []Reloc{{Addr: 0x1000, Type: uint8(types.X86_64_RELOC_UNSIGNED), Len: 3, Extern: true}},
+		},
+		{
+			name:   "sectdiff without pair",
+			file:   "internal/testdata/clang-386-darwin.obj.base64",
+			relocs: []Reloc{{Addr: 0xe, Type: uint8(types.GENERIC_RELOC_SECTDIFF), Len: 2, Scattered: true, Value: 0x2d}},
+		},
+		{
+			name:   "unexpected pair",
+			file:   "internal/testdata/clang-386-darwin.obj.base64",
+			relocs: []Reloc{{Addr: 0xe, Type: uint8(types.GENERIC_RELOC_PAIR), Len: 2, Scattered: true, Value: 0xb}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := openObscured(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			sec := f.Section("__TEXT", "__text")
+			sec.Relocs = tt.relocs
+			if _, err := sec.DecodedRelocations(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	if _, err := (&Section{}).DecodedRelocations(); err == nil {
+		t.Error("expected an error for a section that isn't part of a parsed file")
+	}
+}
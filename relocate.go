@@ -0,0 +1,427 @@
+package macho
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// maxZerofillSize is the largest zerofill section RelocateObject allocates the contents of
+const maxZerofillSize = 1 << 30
+
+// A RelocateConfig describes where to lay out a MH_OBJECT's sections and how to resolve its undefined symbols.
+type RelocateConfig struct {
+	// Base is the address sections without an entry in SectionAddrs are laid out from (in order, honoring alignment)
+	Base uint64
+	// SectionAddrs maps "segment.section" names to the address to place the section at
+	SectionAddrs map[string]uint64
+	// Resolve returns the address of an undefined (imported or common) symbol
+	Resolve func(name string) (uint64, error)
+}
+
+// A RelocatedSection is the linked view of a MH_OBJECT section.
+type RelocatedSection struct {
+	Segment string
+	Name    string
+	Addr    uint64
+	Data    []byte
+	Section *Section // the original section (nil for the synthesized __got and __thread_ptrs sections)
+}
+
+// RelocateObject lays out a MH_OBJECT's sections at the configured addresses and returns their contents with all
+// relocations applied. GOT loads are bound to a synthesized __DATA.__got section and TLV loads to a synthesized
+// __DATA.__thread_ptrs section of pointers to the TLV descriptors; both are placed after the other sections and
+// returned last. Like the linker, the initial value offset of each TLV descriptor is made relative to the start of
+// the thread-local template (the first S_THREAD_LOCAL_REGULAR or S_THREAD_LOCAL_ZEROFILL section).
+//
+// NOTE: only x86_64 and arm64 objects are supported.
+func (f *File) RelocateObject(conf RelocateConfig) ([]RelocatedSection, error) {
+	if f.Type != types.Obj {
+		return nil, fmt.Errorf("macho is not a relocatable object (type=%s)", f.Type)
+	}
+	if f.CPU != types.CPUAmd64 && f.CPU != types.CPUArm64 {
+		return nil, fmt.Errorf("relocating %s objects is not supported", f.CPU)
+	}
+
+	// layout
+	rsecs := make([]RelocatedSection, len(f.Sections))
+	next := conf.Base
+	for i, sec := range f.Sections {
+		if sec.Align >= 64 {
+			return nil, fmt.Errorf("%s.%s alignment 2^%d is out of range", sec.Seg, sec.Name, sec.Align)
+		}
+		addr, ok := conf.SectionAddrs[sec.Seg+"."+sec.Name]
+		if !ok {
+			align := uint64(1) << sec.Align
+			addr = (next + align - 1) &^ (align - 1)
+		}
+		rsecs[i] = RelocatedSection{
+			Segment: sec.Seg,
+			Name:    sec.Name,
+			Addr:    addr,
+			Section: sec,
+		}
+		if sec.Flags.IsZerofill() || sec.Flags.IsGbZerofill() || sec.Flags.IsThreadLocalZerofill() {
+			if sec.Size > maxZerofillSize {
+				return nil, fmt.Errorf("%s.%s zerofill size %#x exceeds the %#x byte limit", sec.Seg, sec.Name, sec.Size, maxZerofillSize)
+			}
+			rsecs[i].Data = make([]byte, sec.Size)
+		} else {
+			dat, err := sec.Data()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+			}
+			rsecs[i].Data = dat
+		}
+		if addr+sec.Size > next {
+			next = addr + sec.Size
+		}
+	}
+
+	// translates an address in the object to its relocated address
+	translate := func(sectIdx uint32, addr uint64) (uint64, error) {
+		if sectIdx == 0 || int(sectIdx) > len(f.Sections) {
+			return 0, fmt.Errorf("section ordinal %d out of range", sectIdx)
+		}
+		return rsecs[sectIdx-1].Addr + addr - f.Sections[sectIdx-1].Addr, nil
+	}
+
+	// the start of the thread-local template (the initial values of the TLVs)
+	var tlvStart uint64
+	var hasTLVs bool
+	for i, sec := range f.Sections {
+		if sec.Flags.IsThreadLocalRegular() || sec.Flags.IsThreadLocalZerofill() {
+			if !hasTLVs || rsecs[i].Addr < tlvStart {
+				tlvStart = rsecs[i].Addr
+			}
+			hasTLVs = true
+		}
+	}
+
+	symAddrs := make(map[uint32]uint64)
+	symbolAddr := func(t RelocTarget) (uint64, error) {
+		if !t.Extern {
+			if t.SectionIndex == 0 { // absolute
+				return t.Addr, nil
+			}
+			return translate(t.SectionIndex, t.Addr)
+		}
+		if addr, ok := symAddrs[t.SymIndex]; ok {
+			return addr, nil
+		}
+		sym := f.Symtab.Syms[t.SymIndex]
+		var addr uint64
+		var err error
+		switch {
+		case sym.Type.IsDefinedInSection():
+			addr, err = translate(uint32(sym.Sect), sym.Value)
+		case sym.Type.IsAbsoluteSym():
+			addr = sym.Value
+		case sym.Type.IsUndefinedSym():
+			if conf.Resolve == nil {
+				return 0, fmt.Errorf("undefined symbol %s", sym.Name)
+			}
+			addr, err = conf.Resolve(sym.Name)
+		default:
+			err = fmt.Errorf("unsupported symbol type %s", sym.Type.String(""))
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to resolve symbol %s: %v", sym.Name, err)
+		}
+		symAddrs[t.SymIndex] = addr
+		return addr, nil
+	}
+
+	relocs := make([][]Relocation, len(f.Sections))
+	gotSyms := make(map[uint32]bool)
+	for i, sec := range f.Sections {
+		rels, err := sec.DecodedRelocations()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rels {
+			if relocPointerKind(r.Type) == gotPointer {
+				gotSyms[r.Target.SymIndex] = true
+			}
+		}
+		relocs[i] = rels
+	}
+
+	// the synthesized pointer sections
+	got := RelocatedSection{
+		Segment: "__DATA",
+		Name:    "__got",
+		Addr:    (next + 7) &^ 7,
+	}
+	tlvPtrs := RelocatedSection{
+		Segment: "__DATA",
+		Name:    "__thread_ptrs",
+		Addr:    got.Addr + uint64(len(gotSyms))*8,
+	}
+	slots := make(map[relocPointer]map[uint32]uint64)
+	pointerAddr := func(kind relocPointer, t RelocTarget) (uint64, error) {
+		sec := &got
+		if kind == tlvPointer {
+			sec = &tlvPtrs
+		}
+		if !t.Extern {
+			return 0, fmt.Errorf("%s relocations must be extern", sec.Name)
+		}
+		if slot, ok := slots[kind][t.SymIndex]; ok {
+			return slot, nil
+		}
+		target, err := symbolAddr(t)
+		if err != nil {
+			return 0, err
+		}
+		slot := sec.Addr + uint64(len(sec.Data))
+		ptr := make([]byte, 8)
+		f.ByteOrder.PutUint64(ptr, target)
+		sec.Data = append(sec.Data, ptr...)
+		if slots[kind] == nil {
+			slots[kind] = make(map[uint32]uint64)
+		}
+		slots[kind][t.SymIndex] = slot
+		return slot, nil
+	}
+
+	for i, sec := range f.Sections {
+		rsec := &rsecs[i]
+		for _, r := range relocs[i] {
+			if uint64(r.Offset)+uint64(r.Size) > uint64(len(rsec.Data)) {
+				return nil, fmt.Errorf("%s.%s relocation at offset %#x extends past end of section", sec.Seg, sec.Name, r.Offset)
+			}
+			loc := rsec.Data[r.Offset:]
+			pc := rsec.Addr + uint64(r.Offset)
+
+			var err error
+			switch {
+			case sec.Flags.IsThreadLocalVariables() && r.Offset%24 == 16:
+				// the offset of the TLV's initial value in the thread-local template
+				var value uint64
+				if value, err = relocValue(r, symbolAddr); err == nil {
+					if !hasTLVs || value < tlvStart {
+						err = fmt.Errorf("TLV initial value %#x is not in a thread-local section", value)
+					} else {
+						err = putReloc(f.ByteOrder, loc, r.Size, value-tlvStart)
+					}
+				}
+			case f.CPU == types.CPUAmd64:
+				err = applyX86_64Reloc(f.ByteOrder, loc, pc, r, symbolAddr, pointerAddr)
+			case f.CPU == types.CPUArm64:
+				err = applyArm64Reloc(f.ByteOrder, loc, pc, r, symbolAddr, pointerAddr)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply %s.%s relocation %s: %v", sec.Seg, sec.Name, r, err)
+			}
+		}
+	}
+
+	if len(got.Data) > 0 {
+		rsecs = append(rsecs, got)
+	}
+	if len(tlvPtrs.Data) > 0 {
+		rsecs = append(rsecs, tlvPtrs)
+	}
+
+	return rsecs, nil
+}
+
+// relocPointer is the kind of pointer a relocation loads its target through
+type relocPointer uint8
+
+const (
+	noPointer  relocPointer = iota
+	gotPointer              // a GOT slot holding the target's address
+	tlvPointer              // a pointer to the target TLV's descriptor
+)
+
+func relocPointerKind(typ types.RelocType) relocPointer {
+	switch typ {
+	case types.X86_64_RELOC_GOT_LOAD, types.X86_64_RELOC_GOT,
+		types.ARM64_RELOC_GOT_LOAD_PAGE21, types.ARM64_RELOC_GOT_LOAD_PAGEOFF12, types.ARM64_RELOC_POINTER_TO_GOT:
+		return gotPointer
+	case types.X86_64_RELOC_TLV, types.ARM64_RELOC_TLVP_LOAD_PAGE21, types.ARM64_RELOC_TLVP_LOAD_PAGEOFF12:
+		return tlvPointer
+	default:
+		return noPointer
+	}
+}
+
+// relocValue returns the relocation's target (plus addend, minus subtrahend)
+func relocValue(r Relocation, symbolAddr func(RelocTarget) (uint64, error)) (uint64, error) {
+	target, err := symbolAddr(r.Target)
+	if err != nil {
+		return 0, err
+	}
+	value := target + uint64(r.Addend)
+	if r.Subtrahend != nil {
+		minus, err := symbolAddr(*r.Subtrahend)
+		if err != nil {
+			return 0, err
+		}
+		value -= minus
+	}
+	return value, nil
+}
+
+func putReloc(bo binary.ByteOrder, loc []byte, size uint8, value uint64) error {
+	switch size {
+	case 4:
+		bo.PutUint32(loc, uint32(value))
+	case 8:
+		bo.PutUint64(loc, value)
+	default:
+		return fmt.Errorf("unsupported fixup size %d", size)
+	}
+	return nil
+}
+
+func pcrel32(target, from uint64) (uint32, error) {
+	delta := int64(target - from)
+	if delta != int64(int32(delta)) {
+		return 0, fmt.Errorf("displacement %#x out of range", delta)
+	}
+	return uint32(int32(delta)), nil
+}
+
+func applyX86_64Reloc(bo binary.ByteOrder, loc []byte, pc uint64, r Relocation, symbolAddr func(RelocTarget) (uint64, error), pointerAddr func(relocPointer, RelocTarget) (uint64, error)) error {
+	typ := r.Type.(types.RelocTypeX86_64)
+
+	var adjust uint64 // bytes of the instruction that follow the 4 byte displacement
+	switch typ {
+	case types.X86_64_RELOC_SIGNED_1:
+		adjust = 1
+	case types.X86_64_RELOC_SIGNED_2:
+		adjust = 2
+	case types.X86_64_RELOC_SIGNED_4:
+		adjust = 4
+	}
+
+	switch typ {
+	case types.X86_64_RELOC_UNSIGNED, types.X86_64_RELOC_SUBTRACTOR:
+		value, err := relocValue(r, symbolAddr)
+		if err != nil {
+			return err
+		}
+		return putReloc(bo, loc, r.Size, value)
+	case types.X86_64_RELOC_SIGNED, types.X86_64_RELOC_BRANCH,
+		types.X86_64_RELOC_SIGNED_1, types.X86_64_RELOC_SIGNED_2, types.X86_64_RELOC_SIGNED_4:
+		var target uint64
+		var err error
+		if r.Target.Extern {
+			target, err = relocValue(r, symbolAddr)
+		} else { // the decoded non-extern target is the referenced address
+			target, err = symbolAddr(r.Target)
+		}
+		if err != nil {
+			return err
+		}
+		disp, err := pcrel32(target, pc+4+adjust)
+		if err != nil {
+			return err
+		}
+		bo.PutUint32(loc, disp)
+	case types.X86_64_RELOC_GOT_LOAD, types.X86_64_RELOC_GOT, types.X86_64_RELOC_TLV:
+		slot, err := pointerAddr(relocPointerKind(typ), r.Target)
+		if err != nil {
+			return err
+		}
+		disp, err := pcrel32(slot+uint64(r.Addend), pc+4)
+		if err != nil {
+			return err
+		}
+		bo.PutUint32(loc, disp)
+	default:
+		return fmt.Errorf("unsupported relocation type %s", typ)
+	}
+	return nil
+}
+
+func applyArm64Reloc(bo binary.ByteOrder, loc []byte, pc uint64, r Relocation, symbolAddr func(RelocTarget) (uint64, error), pointerAddr func(relocPointer, RelocTarget) (uint64, error)) error {
+	typ := r.Type.(types.RelocTypeARM64)
+
+	var target uint64
+	var err error
+	switch typ {
+	case types.ARM64_RELOC_UNSIGNED, types.ARM64_RELOC_SUBTRACTOR:
+		value, err := relocValue(r, symbolAddr)
+		if err != nil {
+			return err
+		}
+		return putReloc(bo, loc, r.Size, value)
+	case types.ARM64_RELOC_POINTER_TO_GOT:
+		slot, err := pointerAddr(gotPointer, r.Target)
+		if err != nil {
+			return err
+		}
+		if r.PCRel {
+			disp, err := pcrel32(slot, pc)
+			if err != nil {
+				return err
+			}
+			bo.PutUint32(loc, disp)
+			return nil
+		}
+		return putReloc(bo, loc, r.Size, slot)
+	case types.ARM64_RELOC_GOT_LOAD_PAGE21, types.ARM64_RELOC_GOT_LOAD_PAGEOFF12,
+		types.ARM64_RELOC_TLVP_LOAD_PAGE21, types.ARM64_RELOC_TLVP_LOAD_PAGEOFF12:
+		target, err = pointerAddr(relocPointerKind(typ), r.Target)
+		target += uint64(r.Addend)
+	default:
+		if r.Target.Extern {
+			target, err = relocValue(r, symbolAddr)
+		} else if typ == types.ARM64_RELOC_BRANCH26 {
+			// the displacement to the target (in the same object) is encoded in the instruction
+			t := r.Target
+			t.Addr = uint64(int64(r.Addr) + int64(int32(bo.Uint32(loc)<<6)>>6)<<2)
+			target, err = symbolAddr(t)
+		} else {
+			// the ARM64_RELOC_ADDEND of a non-extern relocation is the target's address in the object
+			t := r.Target
+			t.Addr = uint64(r.Addend)
+			target, err = symbolAddr(t)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	ins := bo.Uint32(loc)
+	switch typ {
+	case types.ARM64_RELOC_BRANCH26:
+		delta := int64(target - pc)
+		if delta&3 != 0 || delta < -(1<<27) || delta >= 1<<27 {
+			return fmt.Errorf("branch displacement %#x out of range", delta)
+		}
+		ins = ins&0xfc000000 | uint32(delta>>2)&0x03ffffff
+	case types.ARM64_RELOC_PAGE21, types.ARM64_RELOC_GOT_LOAD_PAGE21, types.ARM64_RELOC_TLVP_LOAD_PAGE21:
+		delta := int64(target&^0xfff) - int64(pc&^0xfff)
+		pages := delta >> 12
+		if pages < -(1<<20) || pages >= 1<<20 {
+			return fmt.Errorf("adrp displacement %#x out of range", delta)
+		}
+		immlo := uint32(pages) & 3
+		immhi := uint32(pages>>2) & 0x7ffff
+		ins = ins&0x9f00001f | immlo<<29 | immhi<<5
+	case types.ARM64_RELOC_PAGEOFF12, types.ARM64_RELOC_GOT_LOAD_PAGEOFF12, types.ARM64_RELOC_TLVP_LOAD_PAGEOFF12:
+		off := uint32(target & 0xfff)
+		var scale uint32
+		if ins&0x3b000000 == 0x39000000 { // load/store (unsigned immediate)
+			scale = ins >> 30
+			if ins&0x04800000 == 0x04800000 { // 128-bit SIMD&FP
+				scale = 4
+			}
+		}
+		if off&(1<<scale-1) != 0 {
+			return fmt.Errorf("page offset %#x is not aligned to the %d byte access size", off, 1<<scale)
+		}
+		ins = ins&0xffc003ff | (off>>scale)<<10
+	default:
+		return fmt.Errorf("unsupported relocation type %s", typ)
+	}
+	bo.PutUint32(loc, ins)
+
+	return nil
+}
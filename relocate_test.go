@@ -0,0 +1,308 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// relocatedSection returns the named section of RelocateObject's result
+func relocatedSection(t *testing.T, rsecs []RelocatedSection, name string) RelocatedSection {
+	t.Helper()
+	for _, rsec := range rsecs {
+		if rsec.Name == name {
+			return rsec
+		}
+	}
+	t.Fatalf("no relocated %s section", name)
+	return RelocatedSection{}
+}
+
+func resolveFrom(addrs map[string]uint64) func(string) (uint64, error) {
+	return func(name string) (uint64, error) {
+		if addr, ok := addrs[name]; ok {
+			return addr, nil
+		}
+		return 0, fmt.Errorf("unknown symbol %s", name)
+	}
+}
+
+// adrpTarget returns the page an adrp instruction at pc refers to
+func adrpTarget(ins uint32, pc uint64) uint64 {
+	imm := int64(int32((ins>>5&0x7ffff)<<2|ins>>29&3) << 11 >> 11)
+	return uint64(int64(pc&^0xfff) + imm<<12)
+}
+
+// pageOffset returns the (scaled) 12-bit immediate of an add or ldr (unsigned offset) instruction
+func pageOffset(ins uint32) uint64 {
+	imm := uint64(ins >> 10 & 0xfff)
+	if ins&0x3b000000 == 0x39000000 { // load/store
+		imm <<= ins >> 30
+	}
+	return imm
+}
+
+func TestRelocateObjectArm64(t *testing.T) {
+	f, err := openObscured("internal/testdata/reloc-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	conf := RelocateConfig{
+		Base:         0x100000000,
+		SectionAddrs: map[string]uint64{"__DATA.__data": 0x100004000},
+		Resolve:      resolveFrom(map[string]uint64{"_ext": 0x100008000, "_ext2": 0x100000800}),
+	}
+	rsecs, err := f.RelocateObject(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := relocatedSection(t, rsecs, "__text")
+	data := relocatedSection(t, rsecs, "__data")
+	got := relocatedSection(t, rsecs, "__got")
+	if text.Addr != 0x100000000 || data.Addr != 0x100004000 {
+		t.Fatalf("got __text at %#x and __data at %#x", text.Addr, data.Addr)
+	}
+	if rsecs[len(rsecs)-1].Name != "__got" || got.Addr != 0x100004030 || len(got.Data) != 8 {
+		t.Fatalf("got __got at %#x with %d bytes, want the last section at 0x100004030 with one slot", got.Addr, len(got.Data))
+	}
+	if slot := binary.LittleEndian.Uint64(got.Data); slot != 0x100008000 {
+		t.Errorf("got _ext GOT slot %#x, want 0x100008000", slot)
+	}
+
+	ins := func(off int) uint32 { return binary.LittleEndian.Uint32(text.Data[off:]) }
+	if page := adrpTarget(ins(0), text.Addr); page != 0x100004000 {
+		t.Errorf("adrp _g@PAGE: got page %#x, want 0x100004000", page)
+	}
+	if off := pageOffset(ins(4)); off != 0x10 {
+		t.Errorf("add _g@PAGEOFF+16: got %#x, want 0x10", off)
+	}
+	if page := adrpTarget(ins(8), text.Addr+8); page != 0x100004000 {
+		t.Errorf("adrp _ext@GOTPAGE: got page %#x, want 0x100004000", page)
+	}
+	if off := pageOffset(ins(12)); off != 0x30 {
+		t.Errorf("ldr _ext@GOTPAGEOFF: got %#x, want 0x30", off)
+	}
+	if disp := int64(int32(ins(16)<<6)>>6) << 2; text.Addr+16+uint64(disp) != 0x100000800 {
+		t.Errorf("bl _ext2: got displacement %#x", disp)
+	}
+
+	if p := binary.LittleEndian.Uint64(data.Data[0x18:]); p != 0x100008008 {
+		t.Errorf("_p: got %#x, want 0x100008008", p)
+	}
+	if d := int64(binary.LittleEndian.Uint64(data.Data[0x20:])); d != -0x18 {
+		t.Errorf("_d: got %#x, want -0x18", d)
+	}
+	if e := int32(binary.LittleEndian.Uint32(data.Data[0x28:])); e != 0x100008000-0x100004028 {
+		t.Errorf("_e: got %#x, want %#x", e, 0x100008000-0x100004028)
+	}
+
+	// the same references as non-extern relocations resolve through their section
+	sec := f.Section("__TEXT", "__text")
+	for i, rel := range sec.Relocs {
+		switch types.RelocTypeARM64(rel.Type) {
+		case types.ARM64_RELOC_PAGE21, types.ARM64_RELOC_PAGEOFF12:
+			sec.Relocs[i].Extern = false
+			sec.Relocs[i].Value = 2 // __data
+		case types.ARM64_RELOC_ADDEND:
+			sec.Relocs[i].Value = 0x1c + 0x10 // _g+16 in the object
+		}
+	}
+	rsecs, err = f.RelocateObject(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonExtern := relocatedSection(t, rsecs, "__text"); !bytes.Equal(nonExtern.Data, text.Data) {
+		t.Errorf("non-extern relocations: got %x, want %x", nonExtern.Data, text.Data)
+	}
+}
+
+func TestRelocateObjectX86_64(t *testing.T) {
+	f, err := openObscured("internal/testdata/reloc-amd64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rsecs, err := f.RelocateObject(RelocateConfig{
+		Base:    0x100000000,
+		Resolve: resolveFrom(map[string]uint64{"_ext": 0x100008000}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := relocatedSection(t, rsecs, "__text")
+	data := relocatedSection(t, rsecs, "__data")
+
+	disp := func(off int) uint64 { return uint64(int64(int32(binary.LittleEndian.Uint32(text.Data[off:])))) }
+	if target := text.Addr + 7 + disp(3); target != data.Addr+8 {
+		t.Errorf("movq _g+8(%%rip): got %#x, want %#x", target, data.Addr+8)
+	}
+	if target := text.Addr + 14 + disp(9); target != data.Addr {
+		t.Errorf("movb $1, _g(%%rip): got %#x, want %#x", target, data.Addr)
+	}
+	if target := text.Addr + 0x13 + disp(0xf); target != 0x100008000 {
+		t.Errorf("call _ext: got %#x, want 0x100008000", target)
+	}
+
+	if p := binary.LittleEndian.Uint64(data.Data[0x10:]); p != 0x100008008 {
+		t.Errorf("_p: got %#x, want 0x100008008", p)
+	}
+	if d := int64(binary.LittleEndian.Uint64(data.Data[0x18:])); d != -0x10+4 {
+		t.Errorf("_d: got %#x, want %#x", d, -0x10+4)
+	}
+	if l := binary.LittleEndian.Uint64(data.Data[0x20:]); l != text.Addr+0x1b {
+		t.Errorf("_l: got %#x, want %#x", l, text.Addr+0x1b)
+	}
+}
+
+func TestRelocateObjectTLV(t *testing.T) {
+	const tlvBootstrap = 0x7000
+
+	t.Run("arm64", func(t *testing.T) {
+		f, err := openObscured("internal/testdata/tlv-arm64-darwin.obj.base64")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		rsecs, err := f.RelocateObject(RelocateConfig{
+			Base:    0x10000,
+			Resolve: resolveFrom(map[string]uint64{"__tlv_bootstrap": tlvBootstrap}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bss := relocatedSection(t, rsecs, "__thread_bss"); len(bss.Data) != 0x10 || !bytes.Equal(bss.Data, make([]byte, 0x10)) {
+			t.Errorf("got __thread_bss %x, want 16 zero bytes", bss.Data)
+		}
+		text := relocatedSection(t, rsecs, "__text")
+		tdata := relocatedSection(t, rsecs, "__thread_data")
+		tbss := relocatedSection(t, rsecs, "__thread_bss")
+		vars := relocatedSection(t, rsecs, "__thread_vars")
+		ptrs := relocatedSection(t, rsecs, "__thread_ptrs")
+		for _, rsec := range rsecs {
+			if rsec.Name == "__got" {
+				t.Error("TLV loads must not be bound through the GOT")
+			}
+		}
+
+		// the descriptors
+		for i, init := range []uint64{tdata.Addr, tbss.Addr} {
+			desc := vars.Data[i*24:]
+			if thunk := binary.LittleEndian.Uint64(desc); thunk != tlvBootstrap {
+				t.Errorf("descriptor %d: got thunk %#x, want %#x", i, thunk, tlvBootstrap)
+			}
+			if off := binary.LittleEndian.Uint64(desc[16:]); off != init-tdata.Addr {
+				t.Errorf("descriptor %d: got template offset %#x, want %#x", i, off, init-tdata.Addr)
+			}
+		}
+
+		// the loads of the pointers to the descriptors
+		if len(ptrs.Data) != 16 {
+			t.Fatalf("got %d bytes of TLV pointers, want 16", len(ptrs.Data))
+		}
+		for i, off := range []int{0, 0x10} {
+			adrp := binary.LittleEndian.Uint32(text.Data[off:])
+			ldr := binary.LittleEndian.Uint32(text.Data[off+4:])
+			slot := adrpTarget(adrp, text.Addr+uint64(off)) + pageOffset(ldr)
+			if slot < ptrs.Addr || slot >= ptrs.Addr+uint64(len(ptrs.Data)) {
+				t.Fatalf("TLV load %d: got slot %#x outside of __thread_ptrs", i, slot)
+			}
+			if desc := binary.LittleEndian.Uint64(ptrs.Data[slot-ptrs.Addr:]); desc != vars.Addr+uint64(i)*24 {
+				t.Errorf("TLV pointer %d: got %#x, want descriptor %#x", i, desc, vars.Addr+uint64(i)*24)
+			}
+		}
+	})
+
+	t.Run("x86_64", func(t *testing.T) {
+		f, err := openObscured("internal/testdata/tlv-amd64-darwin.obj.base64")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		rsecs, err := f.RelocateObject(RelocateConfig{
+			Base:    0x10000,
+			Resolve: resolveFrom(map[string]uint64{"__tlv_bootstrap": tlvBootstrap, "_ext": 0x9000}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := relocatedSection(t, rsecs, "__text")
+		vars := relocatedSection(t, rsecs, "__thread_vars")
+		ptrs := relocatedSection(t, rsecs, "__thread_ptrs")
+
+		if off := binary.LittleEndian.Uint64(vars.Data[16:]); off != 0 {
+			t.Errorf("got template offset %#x, want 0", off)
+		}
+		for i, want := range []uint64{vars.Addr, 0x9000} {
+			off := []int{3, 0xc}[i]
+			disp := int64(int32(binary.LittleEndian.Uint32(text.Data[off:])))
+			slot := uint64(int64(text.Addr) + int64(off) + 4 + disp)
+			if slot < ptrs.Addr || slot >= ptrs.Addr+uint64(len(ptrs.Data)) {
+				t.Fatalf("TLV load %d: got slot %#x outside of __thread_ptrs", i, slot)
+			}
+			if desc := binary.LittleEndian.Uint64(ptrs.Data[slot-ptrs.Addr:]); desc != want {
+				t.Errorf("TLV pointer %d: got %#x, want %#x", i, desc, want)
+			}
+		}
+	})
+}
+
+func TestRelocateObjectErrors(t *testing.T) {
+	tlvConf := RelocateConfig{Resolve: resolveFrom(map[string]uint64{"__tlv_bootstrap": 0x7000})}
+
+	tests := []struct {
+		name  string
+		file  string
+		conf  RelocateConfig
+		patch func(f *File)
+	}{
+		{name: "not an object", file: dyldInfoTestFile},
+		{name: "unsupported CPU", file: "internal/testdata/clang-386-darwin.obj.base64"},
+		{name: "undefined symbol", file: "internal/testdata/reloc-arm64-darwin.obj.base64"},
+		{
+			name: "unresolved symbol",
+			file: "internal/testdata/reloc-amd64-darwin.obj.base64",
+			conf: RelocateConfig{Resolve: resolveFrom(nil)},
+		},
+		{
+			name: "branch out of range",
+			file: "internal/testdata/reloc-amd64-darwin.obj.base64",
+			conf: RelocateConfig{Base: 0x100000000, Resolve: resolveFrom(map[string]uint64{"_ext": 0x900000000000})},
+		},
+		{
+			name:  "alignment out of range",
+			file:  "internal/testdata/tlv-arm64-darwin.obj.base64",
+			conf:  tlvConf,
+			patch: func(f *File) { f.Section("__DATA", "__thread_data").Align = 64 },
+		},
+		{
+			name:  "huge zerofill section",
+			file:  "internal/testdata/tlv-arm64-darwin.obj.base64",
+			conf:  tlvConf,
+			patch: func(f *File) { f.Section("__DATA", "__thread_bss").Size = 1 << 62 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := openObscured(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			if tt.patch != nil {
+				tt.patch(f)
+			}
+			if _, err := f.RelocateObject(tt.conf); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package macho
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/blacktop/go-macho/pkg/trie"
+	"github.com/blacktop/go-macho/types"
+)
+
+// A SplitInfoRef is a single LC_SEGMENT_SPLIT_INFO reference; a location in the image
+// that must be adjusted when its segments are slid independently (e.g. by the dyld_shared_cache builder).
+//
+// v1 split info only records where the reference is, so the To* fields are empty for v1 refs.
+type SplitInfoRef struct {
+	Version     uint8
+	Kind        types.SplitInfoKind   // v2 kind
+	V1Kind      types.SplitInfoV1Kind // v1 kind
+	FromSegment string
+	FromSection string
+	FromAddr    uint64 // VM address of the instruction or data that holds the reference
+	ToSegment   string
+	ToSection   string
+	ToAddr      uint64 // VM address the reference points to
+}
+
+func (r SplitInfoRef) String() string {
+	if r.Version == 1 {
+		return fmt.Sprintf("%#016x  %s.%s\t%s", r.FromAddr, r.FromSegment, r.FromSection, r.V1Kind)
+	}
+	return fmt.Sprintf("%#016x  %s.%s -> %#016x  %s.%s\t%s",
+		r.FromAddr, r.FromSegment, r.FromSection,
+		r.ToAddr, r.ToSegment, r.ToSection,
+		r.Kind)
+}
+
+// SplitInfo returns the LC_SEGMENT_SPLIT_INFO load command, or nil if there is none.
+func (f *File) SplitInfo() *SplitInfo {
+	for _, l := range f.Loads {
+		if s, ok := l.(*SplitInfo); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// SplitInfoRefs returns the decoded LC_SEGMENT_SPLIT_INFO references.
+func (f *File) SplitInfoRefs() ([]SplitInfoRef, error) {
+	sinfo := f.SplitInfo()
+	if sinfo == nil {
+		return nil, fmt.Errorf("macho does not contain a %s load command", types.LC_SEGMENT_SPLIT_INFO)
+	}
+	if sinfo.Size == 0 {
+		return []SplitInfoRef{}, nil
+	}
+
+	data := make([]byte, sinfo.Size)
+	if _, err := f.lr.ReadAt(data, int64(sinfo.Offset)); err != nil {
+		return nil, fmt.Errorf("failed to read split info at offset=%#x; %v", sinfo.Offset, err)
+	}
+
+	if data[0] == types.DYLD_CACHE_ADJ_V2_FORMAT {
+		return f.parseSplitInfoV2(bytes.NewReader(data[1:]))
+	}
+	return f.parseSplitInfoV1(bytes.NewReader(data))
+}
+
+// parseSplitInfoV1 parses the v1 format; a list of kind bytes each followed by a zero terminated
+// list of ULEB128 deltas, where the first delta of each kind is from the start of the __TEXT segment.
+func (f *File) parseSplitInfoV1(r *bytes.Reader) ([]SplitInfoRef, error) {
+	var refs []SplitInfoRef
+
+	base := f.preferredLoadAddress()
+
+	for {
+		kind, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read split info v1 kind: %v", err)
+		}
+		if kind == 0 {
+			break
+		}
+		addr := base
+		for {
+			delta, err := trie.ReadUleb128(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read split info v1 %s offset: %v", types.SplitInfoV1Kind(kind), err)
+			}
+			if delta == 0 {
+				break
+			}
+			addr += delta
+			ref := SplitInfoRef{
+				Version:  1,
+				V1Kind:   types.SplitInfoV1Kind(kind),
+				FromAddr: addr,
+			}
+			if sec := f.FindSectionForVMAddr(addr); sec != nil {
+				ref.FromSegment = sec.Seg
+				ref.FromSection = sec.Name
+			}
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs, nil
+}
+
+// parseSplitInfoV2 parses the v2 (DYLD_CACHE_ADJ_V2_FORMAT) format; a table of from-section/to-section
+// pairs, each holding ULEB128 delta encoded to-offsets and the kinds and from-offsets that reference them.
+// Section index 0 is the mach header, and sections are then numbered from 1 in load command order.
+func (f *File) parseSplitInfoV2(r *bytes.Reader) ([]SplitInfoRef, error) {
+	var refs []SplitInfoRef
+
+	sectionAt := func(idx uint64) (string, string, uint64, error) {
+		if idx == 0 {
+			return "__TEXT", "", f.preferredLoadAddress(), nil
+		}
+		if idx > uint64(len(f.Sections)) {
+			return "", "", 0, fmt.Errorf("split info section index %d out of range (%d sections)", idx, len(f.Sections))
+		}
+		sec := f.Sections[idx-1]
+		return sec.Seg, sec.Name, sec.Addr, nil
+	}
+
+	read := func(what string) (uint64, error) {
+		v, err := trie.ReadUleb128(r)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read split info v2 %s: %v", what, err)
+		}
+		return v, nil
+	}
+
+	sectionCount, err := read("section count")
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < sectionCount; i++ {
+		fromSectIdx, err := read("from section index")
+		if err != nil {
+			return nil, err
+		}
+		toSectIdx, err := read("to section index")
+		if err != nil {
+			return nil, err
+		}
+		fromSeg, fromSect, fromBase, err := sectionAt(fromSectIdx)
+		if err != nil {
+			return nil, err
+		}
+		toSeg, toSect, toBase, err := sectionAt(toSectIdx)
+		if err != nil {
+			return nil, err
+		}
+		toOffsetCount, err := read("to offset count")
+		if err != nil {
+			return nil, err
+		}
+		var toOffset uint64
+		for j := uint64(0); j < toOffsetCount; j++ {
+			toDelta, err := read("to offset delta")
+			if err != nil {
+				return nil, err
+			}
+			toOffset += toDelta
+			fromOffsetCount, err := read("from offset count")
+			if err != nil {
+				return nil, err
+			}
+			for k := uint64(0); k < fromOffsetCount; k++ {
+				kind, err := read("kind")
+				if err != nil {
+					return nil, err
+				}
+				if kind > uint64(types.DYLD_CACHE_ADJ_V2_THREADED_POINTER_64) {
+					return nil, fmt.Errorf("unknown split info v2 kind %d", kind)
+				}
+				fromDeltaCount, err := read("from offset delta count")
+				if err != nil {
+					return nil, err
+				}
+				var fromOffset uint64
+				for l := uint64(0); l < fromDeltaCount; l++ {
+					fromDelta, err := read("from offset delta")
+					if err != nil {
+						return nil, err
+					}
+					fromOffset += fromDelta
+					refs = append(refs, SplitInfoRef{
+						Version:     2,
+						Kind:        types.SplitInfoKind(kind),
+						FromSegment: fromSeg,
+						FromSection: fromSect,
+						FromAddr:    fromBase + fromOffset,
+						ToSegment:   toSeg,
+						ToSection:   toSect,
+						ToAddr:      toBase + toOffset,
+					})
+				}
+			}
+		}
+	}
+
+	return refs, nil
+}
@@ -0,0 +1,119 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// splitInfoV1 has two 64-bit pointers at __TEXT+0xf80 and __TEXT+0xf88
+var splitInfoV1 = []byte{
+	byte(types.SPLIT_INFO_V1_POINTER_64), 0x80, 0x1f, 0x08, 0x00,
+	0x00,
+}
+
+// splitInfoV2 has two __text -> __nl_symbol_ptr+8 32-bit deltas at __text+4 and __text+8
+var splitInfoV2 = []byte{
+	types.DYLD_CACHE_ADJ_V2_FORMAT,
+	0x01,       // section count
+	0x01, 0x06, // from section (__text), to section (__nl_symbol_ptr)
+	0x01, 0x08, // to offset count, to offset delta
+	0x01,                                                     // from offset count
+	byte(types.DYLD_CACHE_ADJ_V2_DELTA_32), 0x02, 0x04, 0x04, // kind, from offset deltas
+}
+
+func TestSplitInfoRefs(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	refs, err := f.parseSplitInfoV1(bytes.NewReader(splitInfoV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 || refs[0].FromAddr != 0x100000f80 || refs[1].FromAddr != 0x100000f88 ||
+		refs[0].V1Kind != types.SPLIT_INFO_V1_POINTER_64 || refs[0].FromSection != "__text" {
+		t.Errorf("unexpected v1 refs %v", refs)
+	}
+
+	refs, err = f.parseSplitInfoV2(bytes.NewReader(splitInfoV2[1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SplitInfoRef{
+		{Version: 2, Kind: types.DYLD_CACHE_ADJ_V2_DELTA_32, FromSegment: "__TEXT", FromSection: "__text", FromAddr: 0x100000f64,
+			ToSegment: "__DATA", ToSection: "__nl_symbol_ptr", ToAddr: 0x100001008},
+		{Version: 2, Kind: types.DYLD_CACHE_ADJ_V2_DELTA_32, FromSegment: "__TEXT", FromSection: "__text", FromAddr: 0x100000f68,
+			ToSegment: "__DATA", ToSection: "__nl_symbol_ptr", ToAddr: 0x100001008},
+	}
+	if len(refs) != len(want) || refs[0] != want[0] || refs[1] != want[1] {
+		t.Errorf("got v2 refs %v, want %v", refs, want)
+	}
+}
+
+func TestSplitInfoRefsMalformed(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.parseSplitInfoV1(bytes.NewReader(splitInfoV1[:2])); err == nil {
+		t.Error("expected an error for a truncated v1 offset")
+	}
+	for name, data := range map[string][]byte{
+		"truncated":             splitInfoV2[1:7],
+		"section out of range":  {0x01, 0x01, 0x20, 0x00},
+		"unknown kind":          {0x01, 0x01, 0x06, 0x01, 0x00, 0x01, 0x7f, 0x00},
+		"huge count, no data":   {0xff, 0xff, 0xff, 0xff, 0x0f},
+		"huge from delta count": {0x01, 0x01, 0x06, 0x01, 0x00, 0x01, 0x03, 0xff, 0xff, 0xff, 0x0f},
+	} {
+		if _, err := f.parseSplitInfoV2(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSplitInfoLoad(t *testing.T) {
+	for _, tt := range []struct {
+		data    []byte
+		version uint8 // of the decoded references
+	}{
+		{data: splitInfoV1, version: 1},
+		{data: splitInfoV2, version: 2},
+	} {
+		sinfoData := tt.data
+		f, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+			// turn LC_DATA_IN_CODE (also a linkedit_data_command) into LC_SEGMENT_SPLIT_INFO
+			off := loadCmdOffset(f, f.DataInCode())
+			binary.LittleEndian.PutUint32(data[off:], uint32(types.LC_SEGMENT_SPLIT_INFO))
+			binary.LittleEndian.PutUint32(data[off+8:], uint32(len(data)))
+			binary.LittleEndian.PutUint32(data[off+12:], uint32(len(sinfoData)))
+			return append(data, sinfoData...)
+		})
+		if err != nil {
+			t.Fatalf("v%d: %v", tt.version, err)
+		}
+
+		sinfo := f.SplitInfo()
+		if sinfo == nil {
+			t.Fatalf("v%d: no LC_SEGMENT_SPLIT_INFO", tt.version)
+		}
+		// the version is the raw first byte of the split info
+		if sinfo.Version != sinfoData[0] || sinfo.Offsets != nil {
+			t.Errorf("v%d: got version %#x and offsets %#x, want version %#x", tt.version, sinfo.Version, sinfo.Offsets, sinfoData[0])
+		}
+		refs, err := f.SplitInfoRefs()
+		if err != nil {
+			t.Fatalf("v%d: %v", tt.version, err)
+		}
+		if len(refs) != 2 || refs[0].Version != tt.version {
+			t.Errorf("v%d: got refs %v", tt.version, refs)
+		}
+		f.Close()
+	}
+}
@@ -0,0 +1,110 @@
+package macho
+
+import (
+	"fmt"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// An IndirectSymbol is an indirect symbol table entry and the stub or pointer slot it describes.
+type IndirectSymbol struct {
+	Index    uint32 // index into the indirect symbol table
+	SymIndex uint32 // index into Symtab.Syms (or INDIRECT_SYMBOL_LOCAL/INDIRECT_SYMBOL_ABS)
+	Name     string
+	Segment  string
+	Section  string
+	Address  uint64 // VM address of the stub or pointer slot
+}
+
+// Local reports whether the slot points to a local (non-imported) symbol.
+func (i IndirectSymbol) Local() bool {
+	return i.SymIndex&types.INDIRECT_SYMBOL_LOCAL != 0
+}
+
+// Absolute reports whether the slot points to an absolute symbol.
+func (i IndirectSymbol) Absolute() bool {
+	return i.SymIndex&types.INDIRECT_SYMBOL_ABS != 0
+}
+
+func (i IndirectSymbol) String() string {
+	if i.Local() && i.Absolute() {
+		return fmt.Sprintf("%#016x: %s.%s\tLOCAL ABSOLUTE", i.Address, i.Segment, i.Section)
+	} else if i.Local() {
+		return fmt.Sprintf("%#016x: %s.%s\tLOCAL", i.Address, i.Segment, i.Section)
+	} else if i.Absolute() {
+		return fmt.Sprintf("%#016x: %s.%s\tABSOLUTE", i.Address, i.Segment, i.Section)
+	}
+	return fmt.Sprintf("%#016x: %s.%s\t%5d %s", i.Address, i.Segment, i.Section, i.SymIndex, i.Name)
+}
+
+// IndirectSymbols returns the indirect symbol table entries mapped to the stub and
+// symbol pointer sections (__stubs, __auth_stubs, __got, __la_symbol_ptr, etc) they describe.
+func (f *File) IndirectSymbols() ([]IndirectSymbol, error) {
+	var isyms []IndirectSymbol
+
+	if f.Dysymtab == nil {
+		return nil, fmt.Errorf("macho does not contain LC_DYSYMTAB")
+	}
+
+	for _, sec := range f.Sections {
+		var entrySize uint64
+		switch {
+		case sec.Flags.IsSymbolStubs():
+			entrySize = uint64(sec.Reserved2) // stub size
+		case sec.Flags.IsNonLazySymbolPointers(),
+			sec.Flags.IsLazySymbolPointers(),
+			sec.Flags.IsLazyDylibSymbolPointers(),
+			sec.Flags.IsThreadLocalVariablePointers():
+			entrySize = f.pointerSize()
+		default:
+			continue
+		}
+		if entrySize == 0 {
+			return nil, fmt.Errorf("%s.%s has a stub size of 0", sec.Seg, sec.Name)
+		}
+
+		for i := uint64(0); i < sec.Size/entrySize; i++ {
+			idx := sec.Reserved1 + uint32(i)
+			if int(idx) >= len(f.Dysymtab.IndirectSyms) {
+				return nil, fmt.Errorf("%s.%s indirect symbol index %d out of range (%d entries)", sec.Seg, sec.Name, idx, len(f.Dysymtab.IndirectSyms))
+			}
+			isym := IndirectSymbol{
+				Index:    idx,
+				SymIndex: f.Dysymtab.IndirectSyms[idx],
+				Segment:  sec.Seg,
+				Section:  sec.Name,
+				Address:  sec.Addr + i*entrySize,
+			}
+			if !isym.Local() && !isym.Absolute() && f.Symtab != nil && int(isym.SymIndex) < len(f.Symtab.Syms) {
+				isym.Name = f.Symtab.Syms[isym.SymIndex].Name
+			}
+			isyms = append(isyms, isym)
+		}
+	}
+
+	return isyms, nil
+}
+
+// StubTargets returns a map of stub and symbol pointer slot addresses to the imported symbol names they resolve to.
+func (f *File) StubTargets() (map[uint64]string, error) {
+	isyms, err := f.IndirectSymbols()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[uint64]string)
+	for _, isym := range isyms {
+		if len(isym.Name) > 0 {
+			targets[isym.Address] = isym.Name
+			continue
+		}
+		// stripped or local entries may still be bound to an import by their fixup
+		if sec := f.Section(isym.Segment, isym.Section); sec != nil && !sec.Flags.IsSymbolStubs() {
+			if ptr, err := f.ReadPointer(isym.Address); err == nil && ptr.IsBind() {
+				targets[isym.Address] = ptr.Name
+			}
+		}
+	}
+
+	return targets, nil
+}
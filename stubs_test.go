@@ -0,0 +1,63 @@
+package macho
+
+import "testing"
+
+func TestIndirectSymbols(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	isyms, err := f.IndirectSymbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		addr    uint64
+		section string
+		name    string
+	}{
+		{0x100000f8a, "__stubs", "_printf"},
+		{0x100001000, "__nl_symbol_ptr", "dyld_stub_binder"},
+		{0x100001008, "__nl_symbol_ptr", ""}, // INDIRECT_SYMBOL_ABS
+		{0x100001010, "__la_symbol_ptr", "_printf"},
+	}
+	if len(isyms) != len(want) {
+		t.Fatalf("got %d indirect symbols, want %d", len(isyms), len(want))
+	}
+	for i, w := range want {
+		if isyms[i].Address != w.addr || isyms[i].Section != w.section || isyms[i].Name != w.name {
+			t.Errorf("indirect symbol %d: got %s, want %#x %s %s", i, isyms[i], w.addr, w.section, w.name)
+		}
+	}
+	if !isyms[2].Absolute() {
+		t.Errorf("%s is not absolute", isyms[2])
+	}
+
+	targets, err := f.StubTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 3 || targets[0x100000f8a] != "_printf" || targets[0x100001000] != "dyld_stub_binder" {
+		t.Errorf("unexpected stub targets %v", targets)
+	}
+}
+
+func TestIndirectSymbolsMalformed(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stubs := f.Section("__TEXT", "__stubs")
+	stubs.Reserved1 = uint32(len(f.Dysymtab.IndirectSyms))
+	if _, err := f.IndirectSymbols(); err == nil {
+		t.Error("expected an error for an indirect symbol index beyond the table")
+	}
+	stubs.Reserved1, stubs.Reserved2 = 0, 0
+	if _, err := f.IndirectSymbols(); err == nil {
+		t.Error("expected an error for a stub size of 0")
+	}
+}
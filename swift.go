@@ -8,7 +8,6 @@ import (
 	"io"
 	"strings"
 
-	"github.com/blacktop/go-macho/pkg/fixupchains"
 	"github.com/blacktop/go-macho/types/swift"
 	fieldmd "github.com/blacktop/go-macho/types/swift/fields"
 	"github.com/blacktop/go-macho/types/swift/protocols"
@@ -457,38 +456,30 @@ func (f *File) GetMangledTypeAtOffset(offset int64) (string, *stypes.TypeDescrip
 			fmt.Println("name:", parent, name, tDesc.Flags)
 			return parent + "." + name, &tDesc, nil
 		case 2:
-			f.sr.Seek(offset+int64(t32)+1, io.SeekStart)
-			var context uint64
-			if err := binary.Read(f.sr, f.ByteOrder, &context); err != nil {
-				return "", nil, fmt.Errorf("failed to read 32bit symbolic ref: %v", err)
-			}
-			// Check if context pointer is a dyld chain fixup REBASE
-			if fixupchains.DcpArm64eIsRebase(context) {
-				off, err := f.GetOffset(f.vma.Convert(context))
-				if err != nil {
-					return "", nil, fmt.Errorf("failed to GetOffset: %v", err)
-				}
-				f.sr.Seek(int64(off), io.SeekStart)
-
-				var tDesc stypes.TypeDescriptor
-				if err := binary.Read(f.sr, f.ByteOrder, &tDesc); err != nil {
-					return "", nil, fmt.Errorf("failed to read stypes.TypeDescriptor: %v", err)
-				}
+			context, err := f.ReadPointerAtOffset(uint64(offset + int64(t32) + 1))
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to read symbolic ref context pointer: %v", err)
+			}
+			// context pointer is bound to an imported symbol
+			if context.IsBind() {
+				return context.Name, nil, nil
+			}
+			off, err := f.GetOffset(context.Target)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to GetOffset: %v", err)
+			}
+			f.sr.Seek(int64(off), io.SeekStart)
 
-				name, err := f.GetCStringAtOffset(int64(off) + 8 + int64(tDesc.Name))
-				if err != nil {
-					return "", nil, fmt.Errorf("failed to read cstring: %v", err)
-				}
-				fmt.Println("name:", name, tDesc.Flags)
-				return name, &tDesc, nil
+			var tDesc stypes.TypeDescriptor
+			if err := binary.Read(f.sr, f.ByteOrder, &tDesc); err != nil {
+				return "", nil, fmt.Errorf("failed to read stypes.TypeDescriptor: %v", err)
 			}
-			// context pointer is a dyld chain fixup BIND
-			dcf, err := f.DyldChainedFixups()
+
+			name, err := f.GetCStringAtOffset(int64(off) + 8 + int64(tDesc.Name))
 			if err != nil {
-				return "", nil, fmt.Errorf("failed to get DyldChainedFixups: %v", err)
+				return "", nil, fmt.Errorf("failed to read cstring: %v", err)
 			}
-			name := dcf.Imports[fixupchains.DyldChainedPtrArm64eBind{Pointer: context}.Ordinal()].Name
-			return name, nil, nil
+			return name, &tDesc, nil
 		default:
 			return "", nil, fmt.Errorf("unsupported symbolic REF: %X, 0x%x", refType, offset+int64(t32))
 		}
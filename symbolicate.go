@@ -0,0 +1,352 @@
+package macho
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A SourceFrame is a source location of a symbolicated address; the function and the file, line and column in it.
+type SourceFrame struct {
+	Function string
+	File     string
+	Line     int
+	Column   int
+	Inlined  bool // the function was inlined into the next (caller) frame
+}
+
+func (s SourceFrame) String() string {
+	fn := s.Function
+	if len(fn) == 0 {
+		fn = "?"
+	}
+	loc := s.File
+	if len(loc) == 0 {
+		loc = "?"
+	}
+	if s.Line > 0 {
+		loc += fmt.Sprintf(":%d", s.Line)
+		if s.Column > 0 {
+			loc += fmt.Sprintf(":%d", s.Column)
+		}
+	}
+	if s.Inlined {
+		return fmt.Sprintf("%s at %s (inlined)", fn, loc)
+	}
+	return fmt.Sprintf("%s at %s", fn, loc)
+}
+
+// A Symbolication is the source location of an address and its inlined-call chain.
+type Symbolication struct {
+	Addr uint64
+	// Frames are ordered from the innermost inlined function (whose location is from the line table)
+	// out to the concrete function; each caller's location is the call site of the function it inlined.
+	Frames []SourceFrame
+}
+
+// Function returns the innermost function of the address
+func (s Symbolication) Function() string {
+	if len(s.Frames) == 0 {
+		return ""
+	}
+	return s.Frames[0].Function
+}
+
+// Location returns the innermost source location of the address
+func (s Symbolication) Location() SourceFrame {
+	if len(s.Frames) == 0 {
+		return SourceFrame{}
+	}
+	return s.Frames[0]
+}
+
+func (s Symbolication) String() string {
+	var sb strings.Builder
+	for i, frame := range s.Frames {
+		if i == 0 {
+			sb.WriteString(fmt.Sprintf("%#016x  %s\n", s.Addr, frame))
+		} else {
+			sb.WriteString(fmt.Sprintf("%18s  %s\n", "", frame))
+		}
+	}
+	return sb.String()
+}
+
+// dwarfLineRow is a row of a line table
+type dwarfLineRow struct {
+	addr        uint64
+	file        string
+	line        int
+	column      int
+	endSequence bool
+}
+
+// dwarfScope is a DW_TAG_subprogram or DW_TAG_inlined_subroutine and the inlined subroutines it contains
+type dwarfScope struct {
+	ranges     [][2]uint64
+	name       string
+	callFile   string
+	callLine   int
+	callColumn int
+	inlined    []*dwarfScope
+}
+
+func (s *dwarfScope) contains(addr uint64) bool {
+	for _, r := range s.ranges {
+		if r[0] <= addr && addr < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// dwarfFuncRange is a range of a concrete function
+type dwarfFuncRange struct {
+	low, high uint64
+	fn        *dwarfScope
+}
+
+// dwarfIndex is the address lookup index of the DWARF line tables and subprograms (see Symbolicate)
+type dwarfIndex struct {
+	rows  []dwarfLineRow   // sorted by address
+	funcs []dwarfFuncRange // sorted by low address
+}
+
+// Symbolicate returns the function, file, line and column of an address and its inlined-call chain
+// from the DWARF line programs and DW_TAG_inlined_subroutine ranges.
+//
+// NOTE: the lookup index is built once on the first call (it is safe to call concurrently) and reused by the following calls.
+func (f *File) Symbolicate(addr uint64) (*Symbolication, error) {
+	// the index is replaced rather than reset when a dSYM is paired, so a build in progress is never clobbered
+	f.dsymMu.Lock()
+	if f.dwarfIndex == nil {
+		f.dwarfIndex = new(dwarfIndexOnce)
+	}
+	di := f.dwarfIndex
+	f.dsymMu.Unlock()
+
+	di.once.Do(func() {
+		d, err := f.DWARF()
+		if err != nil {
+			di.err = fmt.Errorf("failed to get DWARF: %v", err)
+			return
+		}
+		if di.idx, err = buildDwarfIndex(d); err != nil {
+			di.err = fmt.Errorf("failed to build DWARF index: %v", err)
+		}
+	})
+	if di.err != nil {
+		return nil, di.err
+	}
+	return di.idx.lookup(addr)
+}
+
+// dwarfIndexOnce builds a dwarfIndex (or fails to) exactly once
+type dwarfIndexOnce struct {
+	once sync.Once
+	idx  *dwarfIndex
+	err  error
+}
+
+func buildDwarfIndex(d *dwarf.Data) (*dwarfIndex, error) {
+	idx := &dwarfIndex{}
+	names := make(map[dwarf.Offset]string)
+
+	var files []*dwarf.LineFile
+	var stack []*dwarfScope // enclosing scope of the entries of each open DIE with children
+
+	r := d.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Tag == 0 {
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		var parent *dwarfScope
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		scope := parent
+
+		switch entry.Tag {
+		case dwarf.TagCompileUnit, dwarf.TagPartialUnit:
+			stack = stack[:0]
+			parent, scope = nil, nil
+			files = nil
+			lr, err := d.LineReader(entry)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read line table at %#x: %v", entry.Offset, err)
+			}
+			if lr != nil {
+				var le dwarf.LineEntry
+				for {
+					if err := lr.Next(&le); err == io.EOF {
+						break
+					} else if err != nil {
+						return nil, fmt.Errorf("failed to read line table at %#x: %v", entry.Offset, err)
+					}
+					row := dwarfLineRow{
+						addr:        le.Address,
+						line:        le.Line,
+						column:      le.Column,
+						endSequence: le.EndSequence,
+					}
+					if le.File != nil {
+						row.file = le.File.Name
+					}
+					idx.rows = append(idx.rows, row)
+				}
+				files = lr.Files()
+			}
+		case dwarf.TagSubprogram, dwarf.TagInlinedSubroutine:
+			ranges, err := d.Ranges(entry)
+			if err != nil || len(ranges) == 0 {
+				break // a declaration or an abstract instance
+			}
+			s := &dwarfScope{
+				ranges: ranges,
+				name:   dwarfEntryName(d, entry, names, 0),
+			}
+			if entry.Tag == dwarf.TagInlinedSubroutine {
+				if i, ok := entry.Val(dwarf.AttrCallFile).(int64); ok && 0 <= i && int(i) < len(files) && files[i] != nil {
+					s.callFile = files[i].Name
+				}
+				if line, ok := entry.Val(dwarf.AttrCallLine).(int64); ok {
+					s.callLine = int(line)
+				}
+				if col, ok := entry.Val(dwarf.AttrCallColumn).(int64); ok {
+					s.callColumn = int(col)
+				}
+			}
+			if entry.Tag == dwarf.TagInlinedSubroutine && parent != nil {
+				parent.inlined = append(parent.inlined, s)
+			} else {
+				for _, rng := range ranges {
+					idx.funcs = append(idx.funcs, dwarfFuncRange{low: rng[0], high: rng[1], fn: s})
+				}
+			}
+			scope = s
+		}
+
+		if entry.Children {
+			stack = append(stack, scope)
+		}
+	}
+
+	// end of sequence rows sort before the rows that start another sequence at the same address
+	sort.SliceStable(idx.rows, func(i, j int) bool {
+		if idx.rows[i].addr != idx.rows[j].addr {
+			return idx.rows[i].addr < idx.rows[j].addr
+		}
+		return idx.rows[i].endSequence && !idx.rows[j].endSequence
+	})
+	sort.SliceStable(idx.funcs, func(i, j int) bool { return idx.funcs[i].low < idx.funcs[j].low })
+
+	return idx, nil
+}
+
+// dwarfEntryName returns the (linkage) name of a subprogram following its abstract origin or specification
+func dwarfEntryName(d *dwarf.Data, entry *dwarf.Entry, names map[dwarf.Offset]string, depth int) string {
+	if name, ok := names[entry.Offset]; ok {
+		return name
+	}
+	var name string
+	for _, attr := range []dwarf.Attr{dwarf.AttrLinkageName, dwarf.Attr(0x2007) /* DW_AT_MIPS_linkage_name */, dwarf.AttrName} {
+		if n, ok := entry.Val(attr).(string); ok && len(n) > 0 {
+			name = n
+			break
+		}
+	}
+	if len(name) == 0 && depth < 8 {
+		for _, attr := range []dwarf.Attr{dwarf.AttrAbstractOrigin, dwarf.AttrSpecification} {
+			off, ok := entry.Val(attr).(dwarf.Offset)
+			if !ok {
+				continue
+			}
+			r := d.Reader()
+			r.Seek(off)
+			ref, err := r.Next()
+			if err != nil || ref == nil {
+				continue
+			}
+			if name = dwarfEntryName(d, ref, names, depth+1); len(name) > 0 {
+				break
+			}
+		}
+	}
+	names[entry.Offset] = name
+	return name
+}
+
+func (idx *dwarfIndex) lookup(addr uint64) (*Symbolication, error) {
+	sym := &Symbolication{Addr: addr}
+
+	// the innermost location is the last line table row at or before the address
+	var row *dwarfLineRow
+	if i := sort.Search(len(idx.rows), func(i int) bool { return idx.rows[i].addr > addr }) - 1; i >= 0 && !idx.rows[i].endSequence {
+		row = &idx.rows[i]
+	}
+
+	// the concrete function containing the address, then the chain of inlined subroutines inside it
+	var chain []*dwarfScope
+	if i := sort.Search(len(idx.funcs), func(i int) bool { return idx.funcs[i].low > addr }) - 1; i >= 0 {
+		if fr := idx.funcs[i]; addr < fr.high {
+			chain = append(chain, fr.fn)
+		}
+	}
+	if len(chain) > 0 {
+		for scope := chain[0]; ; {
+			var next *dwarfScope
+			for _, inl := range scope.inlined {
+				if inl.contains(addr) {
+					next = inl
+					break
+				}
+			}
+			if next == nil {
+				break
+			}
+			chain = append(chain, next)
+			scope = next
+		}
+	}
+
+	if row == nil && len(chain) == 0 {
+		return nil, fmt.Errorf("address %#x not found in DWARF", addr)
+	}
+
+	frame := SourceFrame{}
+	if row != nil {
+		frame.File = row.file
+		frame.Line = row.line
+		frame.Column = row.column
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		frame.Function = chain[i].name
+		frame.Inlined = i > 0
+		sym.Frames = append(sym.Frames, frame)
+		// the caller's location is the call site of the inlined subroutine
+		frame = SourceFrame{
+			File:   chain[i].callFile,
+			Line:   chain[i].callLine,
+			Column: chain[i].callColumn,
+		}
+	}
+	if len(chain) == 0 {
+		sym.Frames = append(sym.Frames, frame)
+	}
+
+	return sym, nil
+}
@@ -0,0 +1,91 @@
+package macho
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+)
+
+func TestSymbolicate(t *testing.T) {
+	f, err := openObscured("internal/testdata/symbolicate-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		addr   uint64
+		frames []SourceFrame
+	}{
+		{addr: 0x4, frames: []SourceFrame{{Function: "outer", File: "/src/sym.c", Line: 10, Column: 5}}},
+		{addr: 0x16, frames: []SourceFrame{
+			{Function: "inner", File: "/src/sym.c", Line: 3, Column: 7, Inlined: true},
+			{Function: "outer", File: "/src/sym.c", Line: 11, Column: 3}, // the call site
+		}},
+		{addr: 0x1c, frames: []SourceFrame{{Function: "outer", File: "/src/sym.c", Line: 13, Column: 1}}},
+	}
+
+	// the index is built once by whichever call comes first
+	var wg sync.WaitGroup
+	for _, tt := range tests {
+		wg.Add(1)
+		go func(addr uint64) {
+			defer wg.Done()
+			if _, err := f.Symbolicate(addr); err != nil {
+				t.Error(err)
+			}
+		}(tt.addr)
+	}
+	wg.Wait()
+
+	for _, tt := range tests {
+		sym, err := f.Symbolicate(tt.addr)
+		if err != nil {
+			t.Errorf("%#x: %v", tt.addr, err)
+			continue
+		}
+		if len(sym.Frames) != len(tt.frames) {
+			t.Errorf("%#x: got %d frames, want %d:\n%s", tt.addr, len(sym.Frames), len(tt.frames), sym)
+			continue
+		}
+		for i, frame := range sym.Frames {
+			if frame != tt.frames[i] {
+				t.Errorf("%#x: frame %d: got %s, want %s", tt.addr, i, frame, tt.frames[i])
+			}
+		}
+	}
+	if sym, err := f.Symbolicate(0x16); err == nil && (sym.Function() != "inner" || sym.Location().Line != 3) {
+		t.Errorf("got %s at line %d, want inner at line 3", sym.Function(), sym.Location().Line)
+	}
+	if _, err := f.Symbolicate(0x20); err == nil {
+		t.Error("expected an error for an address past the end of the function")
+	}
+}
+
+func TestSymbolicateMalformed(t *testing.T) {
+	exe, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exe.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := exe.Symbolicate(0x100000f60); err == nil {
+			t.Error("expected an error for an image without DWARF")
+		}
+	}
+
+	f, err := openPatchedTestFile(t, "internal/testdata/symbolicate-arm64-darwin.obj.base64", func(f *File, data []byte) []byte {
+		// replace the first line program opcode with an extended opcode running past the end of the program
+		line := data[f.Section("__DWARF", "__debug_line").Offset:]
+		program := 10 + binary.LittleEndian.Uint32(line[6:]) // unit length, version and header length
+		line[program], line[program+1] = 0x00, 0x7f
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Symbolicate(0x4); err == nil {
+		t.Error("expected an error for a malformed line program")
+	}
+}
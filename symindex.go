@@ -0,0 +1,317 @@
+package macho
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blacktop/go-macho/pkg/trie"
+	"github.com/blacktop/go-macho/types"
+)
+
+// SymbolSource is where an indexed symbol comes from
+type SymbolSource uint8
+
+const (
+	SymbolSourceSymtab         SymbolSource = iota // LC_SYMTAB
+	SymbolSourceExports                            // the dyld exports trie
+	SymbolSourceFunctionStarts                     // LC_FUNCTION_STARTS (an unnamed function)
+)
+
+func (s SymbolSource) String() string {
+	switch s {
+	case SymbolSourceSymtab:
+		return "symtab"
+	case SymbolSourceExports:
+		return "exports"
+	case SymbolSourceFunctionStarts:
+		return "function-starts"
+	default:
+		return fmt.Sprintf("source(%d)", s)
+	}
+}
+
+// An IndexedSymbol is a symbol of the symbol index (see LookupSymbol and SymbolForAddress).
+type IndexedSymbol struct {
+	Name     string // empty for unnamed functions of stripped binaries
+	Addr     uint64
+	Size     uint64 // up to the next symbol, the end of the function or the end of the section (0 if unknown)
+	Source   SymbolSource
+	External bool
+}
+
+func (s IndexedSymbol) String() string {
+	name := s.Name
+	if len(name) == 0 {
+		name = fmt.Sprintf("func_%x", s.Addr)
+	}
+	return fmt.Sprintf("%#016x  %s (%s)", s.Addr, name, s.Source)
+}
+
+// A SymbolOffset is the symbol containing an address and the offset of the address in it.
+type SymbolOffset struct {
+	IndexedSymbol
+	Offset uint64
+}
+
+func (s SymbolOffset) String() string {
+	name := s.Name
+	if len(name) == 0 {
+		name = fmt.Sprintf("func_%x", s.Addr)
+	}
+	if s.Offset == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s+%#x", name, s.Offset)
+}
+
+// symbolIndex is the name and address index of the symtab, exports trie and function starts (see File.symbols)
+type symbolIndex struct {
+	byName map[string][]IndexedSymbol
+	names  []string        // sorted unique names (for prefix search)
+	byAddr []IndexedSymbol // sorted by address; the preferred name of each address
+}
+
+// symbols returns the symbol index (building it once on first use; it is safe to call concurrently)
+func (f *File) symbols() *symbolIndex {
+	f.symOnce.Do(func() {
+		f.symIndex = f.buildSymbolIndex()
+	})
+	return f.symIndex
+}
+
+// symtabIndex is the index of the raw symtab entries (see FindSymbolAddress and FindAddressSymbols);
+// unlike symbolIndex it only needs the symtab, so a single lookup doesn't parse the exports or function starts
+type symtabIndex struct {
+	byFold map[string]uint64   // case folded name (see foldName) to the value of its first symbol
+	byAddr map[uint64][]Symbol // value to its symbols in symtab order
+}
+
+// symtabEntries returns the symtab index (building it once on first use; it is safe to call concurrently)
+func (f *File) symtabEntries() *symtabIndex {
+	f.symtabOnce.Do(func() {
+		idx := &symtabIndex{
+			byFold: make(map[string]uint64),
+			byAddr: make(map[uint64][]Symbol),
+		}
+		if f.Symtab != nil {
+			for _, sym := range f.Symtab.Syms {
+				name := foldName(sym.Name)
+				if _, ok := idx.byFold[name]; !ok {
+					idx.byFold[name] = sym.Value
+				}
+				idx.byAddr[sym.Value] = append(idx.byAddr[sym.Value], sym)
+			}
+		}
+		f.symtabIndex = idx
+	})
+	return f.symtabIndex
+}
+
+// foldName maps every rune of name to the smallest rune it is equal to under Unicode simple case folding,
+// so two names have the same key exactly when strings.EqualFold reports them equal ('k', 'K' and the
+// Kelvin sign all map to 'K')
+func foldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf {
+			if 'a' <= r && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			return r
+		}
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		return min
+	}, name)
+}
+
+// exportEntries returns the exports trie of LC_DYLD_EXPORTS_TRIE or LC_DYLD_INFO(_ONLY)
+func (f *File) exportEntries() []trie.TrieEntry {
+	if exports, err := f.DyldExports(); err == nil {
+		return exports
+	}
+	var off, size uint32
+	for _, l := range f.Loads {
+		switch info := l.(type) {
+		case *DyldInfo:
+			off, size = info.ExportOff, info.ExportSize
+		case *DyldInfoOnly:
+			off, size = info.ExportOff, info.ExportSize
+		}
+	}
+	if size == 0 {
+		return nil
+	}
+	data := make([]byte, size)
+	if _, err := f.lr.ReadAt(data, int64(off)); err != nil {
+		return nil
+	}
+	exports, err := trie.ParseTrie(data, f.GetBaseAddress())
+	if err != nil {
+		return nil
+	}
+	return exports
+}
+
+func (f *File) buildSymbolIndex() *symbolIndex {
+	idx := &symbolIndex{
+		byName: make(map[string][]IndexedSymbol),
+	}
+
+	type key struct {
+		name string
+		addr uint64
+	}
+	seen := make(map[key]bool)
+	// rank orders the names of an address; external symtab symbols, then exports, then local symbols
+	rank := func(s IndexedSymbol) int {
+		switch {
+		case s.Source == SymbolSourceSymtab && s.External:
+			return 0
+		case s.Source == SymbolSourceExports:
+			return 1
+		case s.Source == SymbolSourceSymtab:
+			return 2
+		default:
+			return 3
+		}
+	}
+	byAddr := make(map[uint64]IndexedSymbol)
+	add := func(s IndexedSymbol, hasAddr bool) {
+		if len(s.Name) > 0 {
+			if seen[key{s.Name, s.Addr}] {
+				return
+			}
+			seen[key{s.Name, s.Addr}] = true
+			idx.byName[s.Name] = append(idx.byName[s.Name], s)
+		}
+		if hasAddr {
+			if prev, ok := byAddr[s.Addr]; !ok || rank(s) < rank(prev) {
+				byAddr[s.Addr] = s
+			}
+		}
+	}
+
+	if f.Symtab != nil {
+		for _, sym := range f.Symtab.Syms {
+			if sym.Type.IsDebugSym() || len(sym.Name) == 0 {
+				continue
+			}
+			if !sym.Type.IsDefinedInSection() && !sym.Type.IsAbsoluteSym() {
+				continue
+			}
+			add(IndexedSymbol{
+				Name:     sym.Name,
+				Addr:     sym.Value,
+				Source:   SymbolSourceSymtab,
+				External: sym.Type.IsExternalSym(),
+			}, sym.Type.IsDefinedInSection())
+		}
+	}
+
+	for _, exp := range f.exportEntries() {
+		if exp.Flags&types.EXPORT_SYMBOL_FLAGS_REEXPORT != 0 || len(exp.ReExport) > 0 {
+			continue // defined in another image
+		}
+		add(IndexedSymbol{
+			Name:     exp.Name,
+			Addr:     exp.Address,
+			Source:   SymbolSourceExports,
+			External: true,
+		}, exp.Flags.Regular())
+	}
+
+	funcs := f.GetFunctions()
+	for _, fn := range funcs {
+		if _, ok := byAddr[fn.StartAddr]; !ok {
+			byAddr[fn.StartAddr] = IndexedSymbol{Addr: fn.StartAddr, Source: SymbolSourceFunctionStarts}
+		}
+	}
+
+	idx.byAddr = make([]IndexedSymbol, 0, len(byAddr))
+	for _, s := range byAddr {
+		idx.byAddr = append(idx.byAddr, s)
+	}
+	sort.Slice(idx.byAddr, func(i, j int) bool { return idx.byAddr[i].Addr < idx.byAddr[j].Addr })
+
+	// a symbol runs up to the next symbol, the end of its function or the end of its section
+	for i := range idx.byAddr {
+		s := &idx.byAddr[i]
+		var end uint64
+		if sec := f.FindSectionForVMAddr(s.Addr); sec != nil {
+			end = sec.Addr + sec.Size
+		}
+		if j := sort.Search(len(funcs), func(j int) bool { return funcs[j].StartAddr > s.Addr }) - 1; j >= 0 {
+			if fn := funcs[j]; s.Addr < fn.EndAddr && (end == 0 || fn.EndAddr < end) {
+				end = fn.EndAddr
+			}
+		}
+		if i+1 < len(idx.byAddr) && (end == 0 || idx.byAddr[i+1].Addr < end) {
+			end = idx.byAddr[i+1].Addr
+		}
+		if end > s.Addr {
+			s.Size = end - s.Addr
+		}
+	}
+	// the by name entries share the sizes
+	sizes := make(map[uint64]uint64, len(idx.byAddr))
+	for _, s := range idx.byAddr {
+		sizes[s.Addr] = s.Size
+	}
+	for name, syms := range idx.byName {
+		for i := range syms {
+			syms[i].Size = sizes[syms[i].Addr]
+		}
+		idx.names = append(idx.names, name)
+	}
+	sort.Strings(idx.names)
+
+	return idx
+}
+
+// LookupSymbol returns the symbols with the exact (case-sensitive) name from the symtab and exports trie.
+//
+// NOTE: the symbol index is built on the first lookup and reused by the following lookups.
+func (f *File) LookupSymbol(name string) ([]IndexedSymbol, error) {
+	if syms, ok := f.symbols().byName[name]; ok {
+		return syms, nil
+	}
+	return nil, fmt.Errorf("symbol %s not found", name)
+}
+
+// FindSymbolsWithPrefix returns the symbols whose names start with prefix sorted by name.
+func (f *File) FindSymbolsWithPrefix(prefix string) []IndexedSymbol {
+	idx := f.symbols()
+	var syms []IndexedSymbol
+	for i := sort.SearchStrings(idx.names, prefix); i < len(idx.names) && strings.HasPrefix(idx.names[i], prefix); i++ {
+		syms = append(syms, idx.byName[idx.names[i]]...)
+	}
+	return syms
+}
+
+// SymbolForAddress returns the symbol containing an address (the nearest symbol at or before it
+// in the same function or section) and the address' offset in it; unnamed functions of stripped
+// binaries come from LC_FUNCTION_STARTS. A symbol of unknown size only contains the addresses of its section.
+func (f *File) SymbolForAddress(addr uint64) (*SymbolOffset, error) {
+	idx := f.symbols()
+	i := sort.Search(len(idx.byAddr), func(i int) bool { return idx.byAddr[i].Addr > addr }) - 1
+	if i < 0 {
+		return nil, fmt.Errorf("no symbol contains address %#x", addr)
+	}
+	s := idx.byAddr[i]
+	if s.Size > 0 && addr >= s.Addr+s.Size {
+		return nil, fmt.Errorf("no symbol contains address %#x", addr)
+	}
+	if s.Size == 0 {
+		if sec := f.FindSectionForVMAddr(s.Addr); sec == nil || addr >= sec.Addr+sec.Size {
+			return nil, fmt.Errorf("no symbol contains address %#x", addr)
+		}
+	}
+	return &SymbolOffset{IndexedSymbol: s, Offset: addr - s.Addr}, nil
+}
@@ -0,0 +1,147 @@
+package macho
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+func TestSymbolIndex(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// the index is built once by whichever lookup comes first
+	var wg sync.WaitGroup
+	for _, name := range []string{"_main", "start", "_environ"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if _, err := f.LookupSymbol(name); err != nil {
+				t.Error(err)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	syms, err := f.LookupSymbol("_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(syms) != 1 || syms[0].Addr != 0x100000f6a || syms[0].Size != 0x17 || !syms[0].External {
+		t.Errorf("got %v, want the external _main at 0x100000f6a", syms)
+	}
+	if _, err := f.LookupSymbol("_MAIN"); err == nil {
+		t.Error("expected LookupSymbol to be case-sensitive")
+	}
+	if syms := f.FindSymbolsWithPrefix("_NX"); len(syms) != 2 || syms[0].Name != "_NXArgc" || syms[1].Name != "_NXArgv" {
+		t.Errorf("got %v, want _NXArgc and _NXArgv", syms)
+	}
+
+	tests := []struct {
+		addr uint64
+		want string
+	}{
+		{addr: 0x100000f14, want: "start"},
+		{addr: 0x100000f60, want: "dyld_stub_binding_helper+0x10"},
+		{addr: 0x100000f70, want: "_main+0x6"},
+		{addr: 0x100001017, want: "_NXArgv+0x7"},
+	}
+	for _, tt := range tests {
+		if s, err := f.SymbolForAddress(tt.addr); err != nil {
+			t.Errorf("%#x: %v", tt.addr, err)
+		} else if s.String() != tt.want {
+			t.Errorf("%#x: got %s, want %s", tt.addr, s, tt.want)
+		}
+	}
+	for _, addr := range []uint64{0x100000000, 0x100000f81, 0x100001020} {
+		if s, err := f.SymbolForAddress(addr); err == nil {
+			t.Errorf("%#x: got %s, want an error", addr, s)
+		}
+	}
+}
+
+func TestSymbolForAddressUnknownSize(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// a symbol outside of any section doesn't have a size, so it only contains its own section's addresses (none)
+	f.Symtab.Syms = append(f.Symtab.Syms, Symbol{Name: "_outside", Type: types.N_SECT | types.N_EXT, Sect: 1, Value: 0x100008000})
+	syms, err := f.LookupSymbol("_outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if syms[0].Size != 0 {
+		t.Fatalf("got size %#x, want an unknown size", syms[0].Size)
+	}
+	for _, addr := range []uint64{0x100008000, 0x100008010, 0x200000000} {
+		if s, err := f.SymbolForAddress(addr); err == nil {
+			t.Errorf("%#x: got %s, want an error", addr, s)
+		}
+	}
+}
+
+func TestFindSymbolAddress(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, name := range []string{"_main", "_MAIN"} {
+		if addr, err := f.FindSymbolAddress(name); err != nil || addr != 0x100000f6a {
+			t.Errorf("%s: got %#x (%v), want 0x100000f6a", name, addr, err)
+		}
+	}
+	// names are matched like strings.EqualFold (the long s folds to 's')
+	if addr, err := f.FindSymbolAddress("_PUT\u017f"); err != nil || addr != 0 {
+		t.Errorf("got %#x (%v), want _puts", addr, err)
+	}
+	for _, name := range [][2]string{{"k", "\u212a"}, {"K", "\u212a"}, {"\u00e9", "\u00c9"}, {"\xff", "\xfe"}} {
+		if (foldName(name[0]) == foldName(name[1])) != strings.EqualFold(name[0], name[1]) {
+			t.Errorf("%q and %q: folded to %q and %q", name[0], name[1], foldName(name[0]), foldName(name[1]))
+		}
+	}
+	if _, err := f.FindSymbolAddress("_missing"); err == nil {
+		t.Error("expected an error for a missing symbol")
+	}
+
+	syms, err := f.FindAddressSymbols(0x100001000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(syms) != 1 || syms[0].Name != "___progname" {
+		t.Errorf("got %v, want ___progname", syms)
+	}
+	// undefined symbols have the value 0 (in symtab order)
+	if syms, err := f.FindAddressSymbols(0); err != nil || len(syms) != 2 || syms[0].Name != "_exit" || syms[1].Name != "_puts" {
+		t.Errorf("got %v (%v), want _exit and _puts", syms, err)
+	}
+	if _, err := f.FindAddressSymbols(0x100000f15); err == nil {
+		t.Error("expected an error for an address without symbols")
+	}
+	// the lookups only index the symtab
+	if f.symIndex != nil {
+		t.Error("expected the symbol index to be built on demand")
+	}
+
+	stripped, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stripped.Close()
+	stripped.Symtab = nil
+	if _, err := stripped.FindSymbolAddress("_main"); err == nil {
+		t.Error("expected an error for a file without a symbol table")
+	}
+	if _, err := stripped.FindAddressSymbols(0x100000f6a); err == nil {
+		t.Error("expected an error for a file without a symbol table")
+	}
+}
@@ -0,0 +1,225 @@
+package macho
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// A TLVDescriptor is a thread-local variable descriptor from a S_THREAD_LOCAL_VARIABLES (i.e. __thread_vars) section.
+//
+//	struct tlv_descriptor {
+//		void*         (*thunk)(struct tlv_descriptor*);
+//		unsigned long key;
+//		unsigned long offset;
+//	};
+type TLVDescriptor struct {
+	Addr      uint64 // address of the descriptor
+	Name      string // symbol name of the thread-local variable (if known)
+	Thunk     uint64 // address of the thunk (0 if it is bound to an import)
+	ThunkName string // name of the thunk (usually __tlv_bootstrap)
+	Key       uint64 // pthread key (set by dyld at runtime)
+	Offset    uint64 // offset of the variable in the thread-local template (for objects; from the first initial value section)
+
+	InitAddr    uint64 // address of the variable's initial value
+	InitSegment string
+	InitSection string
+	InitName    string // symbol name of the initial value (i.e. _var$tlv$init)
+	Zerofill    bool   // the initial value is in a S_THREAD_LOCAL_ZEROFILL (i.e. __thread_bss) section
+	Size        uint64 // size of the initial value (up to the next variable or the end of the template)
+}
+
+func (t TLVDescriptor) String() string {
+	name := t.Name
+	if len(name) == 0 {
+		name = "?"
+	}
+	thunk := t.ThunkName
+	if len(thunk) == 0 {
+		thunk = fmt.Sprintf("%#x", t.Thunk)
+	}
+	var bss string
+	if t.Zerofill {
+		bss = " zerofill"
+	}
+	return fmt.Sprintf("%#016x  %s thunk=%s key=%#x offset=%#x init=%#016x %s.%s size=%#x%s %s",
+		t.Addr, name, thunk, t.Key, t.Offset, t.InitAddr, t.InitSegment, t.InitSection, t.Size, bss, t.InitName)
+}
+
+// TLVTemplate returns the address and size of the thread-local template; the S_THREAD_LOCAL_REGULAR
+// and S_THREAD_LOCAL_ZEROFILL sections every thread's copy of the thread-local variables is initialized from.
+func (f *File) TLVTemplate() (uint64, uint64, error) {
+	var start, end uint64
+	for _, sec := range f.tlvInitSections() {
+		if start == 0 || sec.Addr < start {
+			start = sec.Addr
+		}
+		if sec.Addr+sec.Size > end {
+			end = sec.Addr + sec.Size
+		}
+	}
+	if end == 0 {
+		return 0, 0, fmt.Errorf("macho does not contain any thread-local initial value sections")
+	}
+	return start, end - start, nil
+}
+
+func (f *File) tlvInitSections() []*Section {
+	var secs []*Section
+	for _, sec := range f.Sections {
+		if sec.Flags.IsThreadLocalRegular() || sec.Flags.IsThreadLocalZerofill() {
+			secs = append(secs, sec)
+		}
+	}
+	return secs
+}
+
+// TLVDescriptors returns the thread-local variable descriptors mapped to their initial values.
+func (f *File) TLVDescriptors() ([]TLVDescriptor, error) {
+	var tlvs []TLVDescriptor
+
+	templateStart, templateSize, err := f.TLVTemplate()
+	if err != nil {
+		templateStart, templateSize = 0, 0
+	}
+
+	ptrSize := f.pointerSize()
+
+	for _, sec := range f.Sections {
+		if !sec.Flags.IsThreadLocalVariables() {
+			continue
+		}
+
+		var relocAt map[uint64]Relocation
+		var dat []byte
+		if f.Type == types.Obj {
+			relocs, err := sec.DecodedRelocations()
+			if err != nil {
+				return nil, err
+			}
+			relocAt = make(map[uint64]Relocation)
+			for _, rel := range relocs {
+				relocAt[uint64(rel.Offset)] = rel
+			}
+			if dat, err = sec.Data(); err != nil {
+				return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+			}
+		}
+
+		// readField returns the raw value of a descriptor field and the symbol an object relocates it to
+		readField := func(off uint64) (uint64, *Symbol, error) {
+			if f.Type == types.Obj {
+				var val uint64
+				if ptrSize == 8 {
+					val = f.ByteOrder.Uint64(dat[off:])
+				} else {
+					val = uint64(f.ByteOrder.Uint32(dat[off:]))
+				}
+				if rel, ok := relocAt[off]; ok && rel.Target.Extern {
+					sym := f.Symtab.Syms[rel.Target.SymIndex]
+					if sym.Type.IsDefinedInSection() {
+						val = uint64(int64(sym.Value) + rel.Addend)
+					} else {
+						val = 0
+					}
+					return val, &sym, nil
+				}
+				return val, nil, nil
+			}
+			foff, err := f.GetOffset(sec.Addr + off)
+			if err != nil {
+				return 0, nil, err
+			}
+			if ptrSize == 8 {
+				val, err := f.readUint64(int64(foff))
+				return val, nil, err
+			}
+			val, err := f.readUint32(int64(foff))
+			return uint64(val), nil, err
+		}
+
+		for off := uint64(0); off+3*ptrSize <= sec.Size; off += 3 * ptrSize {
+			tlv := TLVDescriptor{Addr: sec.Addr + off}
+			tlv.Name = f.symbolNameForAddr(tlv.Addr)
+
+			// thunk
+			if f.Type == types.Obj {
+				thunk, sym, err := readField(off)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read TLV descriptor at %#x thunk: %v", tlv.Addr, err)
+				}
+				tlv.Thunk = thunk
+				if sym != nil {
+					tlv.ThunkName = sym.Name
+				}
+			} else {
+				ptr, err := f.ReadPointer(tlv.Addr)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read TLV descriptor at %#x thunk: %v", tlv.Addr, err)
+				}
+				if ptr.IsBind() {
+					tlv.ThunkName = ptr.Name
+				} else {
+					tlv.Thunk = ptr.Target
+					tlv.ThunkName = f.symbolNameForAddr(tlv.Thunk)
+				}
+			}
+
+			if tlv.Key, _, err = readField(off + ptrSize); err != nil {
+				return nil, fmt.Errorf("failed to read TLV descriptor at %#x key: %v", tlv.Addr, err)
+			}
+
+			// objects point the offset at the initial value, which the static linker converts to a template offset
+			offset, sym, err := readField(off + 2*ptrSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read TLV descriptor at %#x offset: %v", tlv.Addr, err)
+			}
+			if f.Type == types.Obj {
+				tlv.InitAddr = offset
+				if offset >= templateStart {
+					tlv.Offset = offset - templateStart
+				}
+				if sym != nil {
+					tlv.InitName = sym.Name
+				}
+			} else {
+				tlv.Offset = offset
+				tlv.InitAddr = templateStart + offset
+			}
+
+			tlvs = append(tlvs, tlv)
+		}
+	}
+
+	// map each descriptor to its initial value
+	inits := make([]uint64, 0, len(tlvs))
+	for _, tlv := range tlvs {
+		inits = append(inits, tlv.InitAddr)
+	}
+	sort.Slice(inits, func(i, j int) bool { return inits[i] < inits[j] })
+	for i, tlv := range tlvs {
+		if templateSize == 0 {
+			break
+		}
+		for _, sec := range f.tlvInitSections() {
+			if sec.Addr <= tlv.InitAddr && tlv.InitAddr < sec.Addr+sec.Size {
+				tlvs[i].InitSegment = sec.Seg
+				tlvs[i].InitSection = sec.Name
+				tlvs[i].Zerofill = sec.Flags.IsThreadLocalZerofill()
+				// the size runs up to the next variable's initial value or the end of the section
+				end := sec.Addr + sec.Size
+				if idx := sort.Search(len(inits), func(j int) bool { return inits[j] > tlv.InitAddr }); idx < len(inits) && inits[idx] < end {
+					end = inits[idx]
+				}
+				tlvs[i].Size = end - tlv.InitAddr
+				break
+			}
+		}
+		if len(tlvs[i].InitName) == 0 {
+			tlvs[i].InitName = f.symbolNameForAddr(tlv.InitAddr)
+		}
+	}
+
+	return tlvs, nil
+}
@@ -0,0 +1,154 @@
+package macho
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+func TestTLVDescriptorsObject(t *testing.T) {
+	tests := []struct {
+		file string
+		want []TLVDescriptor
+	}{
+		{
+			file: "internal/testdata/tlv-arm64-darwin.obj.base64",
+			want: []TLVDescriptor{
+				{
+					Addr: 0x28, Name: "_a", ThunkName: "__tlv_bootstrap", Offset: 0,
+					InitAddr: 0x1c, InitSegment: "__DATA", InitSection: "__thread_data", InitName: "_a$tlv$init", Size: 0xc,
+				},
+				{
+					Addr: 0x40, Name: "_b", ThunkName: "__tlv_bootstrap", Offset: 0x3c,
+					InitAddr: 0x58, InitSegment: "__DATA", InitSection: "__thread_bss", InitName: "_b$tlv$init", Zerofill: true, Size: 0x10,
+				},
+			},
+		},
+		{
+			file: "internal/testdata/tlv-amd64-darwin.obj.base64",
+			want: []TLVDescriptor{
+				{
+					Addr: 0x20, Name: "_a", ThunkName: "__tlv_bootstrap", Offset: 0,
+					InitAddr: 0x13, InitSegment: "__DATA", InitSection: "__thread_data", InitName: "_a$tlv$init", Size: 0x8,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		f, err := openObscured(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tlvs, err := f.TLVDescriptors()
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+		} else if len(tlvs) != len(tt.want) {
+			t.Errorf("%s: got %d descriptors, want %d", tt.file, len(tlvs), len(tt.want))
+		} else {
+			for i, tlv := range tlvs {
+				if tlv != tt.want[i] {
+					t.Errorf("%s: descriptor %d: got %s, want %s", tt.file, i, tlv, tt.want[i])
+				}
+			}
+		}
+		f.Close()
+	}
+
+	f, err := openObscured("internal/testdata/tlv-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if start, size, err := f.TLVTemplate(); err != nil || start != 0x1c || size != 0x4c {
+		t.Errorf("got template %#x+%#x (%v), want 0x1c+0x4c", start, size, err)
+	}
+}
+
+func TestTLVDescriptorsImage(t *testing.T) {
+	// the pointer sections as a descriptor with the __cstring section as its template
+	f, err := openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		vars := f.Section("__DATA", "__nl_symbol_ptr")
+		setSectionType(f, data, vars, types.ThreadLocalVariables)
+		binary.LittleEndian.PutUint64(data[sectionHeaderOffset(f, vars)+40:], 0x18)
+		binary.LittleEndian.PutUint64(data[vars.Offset+0x10:], 4) // the offset of the variable in the template
+		setSectionType(f, data, f.Section("__TEXT", "__cstring"), types.ThreadLocalRegular)
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tlvs, err := f.TLVDescriptors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TLVDescriptor{
+		Addr:        0x100001000,
+		ThunkName:   "dyld_stub_binder",
+		Offset:      4,
+		InitAddr:    0x100000fae,
+		InitSegment: "__TEXT",
+		InitSection: "__cstring",
+		Size:        0xa,
+	}
+	if len(tlvs) != 1 {
+		t.Fatalf("got %d descriptors, want 1", len(tlvs))
+	}
+	if tlvs[0] != want {
+		t.Errorf("got %s, want %s", tlvs[0], want)
+	}
+
+	exe, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exe.Close()
+	if tlvs, err := exe.TLVDescriptors(); err != nil || len(tlvs) != 0 {
+		t.Errorf("got %d descriptors (%v), want none", len(tlvs), err)
+	}
+	if _, _, err := exe.TLVTemplate(); err == nil {
+		t.Error("expected an error for an image without thread-local variables")
+	}
+}
+
+func TestTLVDescriptorsMalformed(t *testing.T) {
+	f, err := openPatchedTestFile(t, "internal/testdata/tlv-arm64-darwin.obj.base64", func(f *File, data []byte) []byte {
+		// move the __thread_vars contents past the end of the file
+		binary.LittleEndian.PutUint32(data[sectionHeaderOffset(f, f.Section("__DATA", "__thread_vars"))+48:], 0x100000)
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.TLVDescriptors(); err == nil {
+		t.Error("expected an error for an unreadable __thread_vars section")
+	}
+
+	f, err = openObscured("internal/testdata/tlv-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Section("__DATA", "__thread_vars").Relocs[0].Value = 0xffffff // symbol index
+	if _, err := f.TLVDescriptors(); err == nil {
+		t.Error("expected an error for a relocation to an unknown symbol")
+	}
+
+	f, err = openPatchedTestFile(t, dyldInfoTestFile, func(f *File, data []byte) []byte {
+		vars := f.Section("__DATA", "__nl_symbol_ptr")
+		setSectionType(f, data, vars, types.ThreadLocalVariables)
+		binary.LittleEndian.PutUint64(data[sectionHeaderOffset(f, vars)+32:], 0x200000000) // unmapped
+		binary.LittleEndian.PutUint64(data[sectionHeaderOffset(f, vars)+40:], 0x18)
+		return data
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.TLVDescriptors(); err == nil {
+		t.Error("expected an error for descriptors outside of any segment")
+	}
+}
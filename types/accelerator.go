@@ -0,0 +1,83 @@
+package types
+
+import "fmt"
+
+// APPLE_HASH_MAGIC is the magic of the Apple DWARF accelerator tables ('HASH')
+const APPLE_HASH_MAGIC = 0x48415348
+
+// APPLE_HASH_VERSION is the only Apple DWARF accelerator table version
+const APPLE_HASH_VERSION = 1
+
+// APPLE_HASH_EMPTY_BUCKET marks an empty bucket of an Apple DWARF accelerator table
+const APPLE_HASH_EMPTY_BUCKET = 0xffffffff
+
+// AppleHashFunction is the hash function of an Apple DWARF accelerator table
+type AppleHashFunction uint16
+
+const (
+	APPLE_HASH_FUNCTION_DJB AppleHashFunction = 0 // Daniel J Bernstein hash
+)
+
+func (h AppleHashFunction) String() string {
+	switch h {
+	case APPLE_HASH_FUNCTION_DJB:
+		return "djb"
+	default:
+		return fmt.Sprintf("hash_function(%d)", h)
+	}
+}
+
+// AppleAccelHeader is the header of the __DWARF,__apple_names, __apple_types, __apple_namespac and __apple_objc sections
+type AppleAccelHeader struct {
+	Magic            uint32 // APPLE_HASH_MAGIC
+	Version          uint16 // APPLE_HASH_VERSION
+	HashFunction     AppleHashFunction
+	BucketCount      uint32
+	HashesCount      uint32
+	HeaderDataLength uint32
+	// HeaderData
+}
+
+// AppleAtomType is the type of an Apple DWARF accelerator table atom (DW_ATOM_*)
+type AppleAtomType uint16
+
+const (
+	DW_ATOM_null           AppleAtomType = 0
+	DW_ATOM_die_offset     AppleAtomType = 1 // DIE offset in the .debug_info section
+	DW_ATOM_cu_offset      AppleAtomType = 2 // offset of the compile unit header containing the DIE
+	DW_ATOM_die_tag        AppleAtomType = 3 // DW_TAG of the DIE
+	DW_ATOM_type_flags     AppleAtomType = 4 // DW_FLAG_type_* flags
+	DW_ATOM_qual_name_hash AppleAtomType = 5 // hash of the fully qualified name (__apple_types)
+)
+
+func (a AppleAtomType) String() string {
+	switch a {
+	case DW_ATOM_null:
+		return "DW_ATOM_null"
+	case DW_ATOM_die_offset:
+		return "DW_ATOM_die_offset"
+	case DW_ATOM_cu_offset:
+		return "DW_ATOM_cu_offset"
+	case DW_ATOM_die_tag:
+		return "DW_ATOM_die_tag"
+	case DW_ATOM_type_flags:
+		return "DW_ATOM_type_flags"
+	case DW_ATOM_qual_name_hash:
+		return "DW_ATOM_qual_name_hash"
+	default:
+		return fmt.Sprintf("DW_ATOM(%#x)", uint16(a))
+	}
+}
+
+// DW_FLAG_type_implementation is set in DW_ATOM_type_flags for ObjC @implementation types
+const DW_FLAG_type_implementation = 2
+
+// AppleAtom is a HeaderData atom; the type and DW_FORM of a value of the table's hash data
+type AppleAtom struct {
+	Type AppleAtomType
+	Form uint16 // DW_FORM_*
+}
+
+func (a AppleAtom) String() string {
+	return fmt.Sprintf("%s (form %#x)", a.Type, a.Form)
+}
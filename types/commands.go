@@ -240,6 +240,59 @@ type DysymtabCmd struct {
 	Nlocrel        uint32
 }
 
+// A DylibTableOfContents is a LC_DYSYMTAB table of contents entry (dylib_table_of_contents).
+type DylibTableOfContents struct {
+	SymbolIndex uint32 // the defined external symbol (index into the symbol table)
+	ModuleIndex uint32 // index into the module table this symbol is defined in
+}
+
+// A DylibModule is a LC_DYSYMTAB module table entry (dylib_module).
+type DylibModule struct {
+	ModuleName         uint32 // the module name (index into the string table)
+	Iextdefsym         uint32 // index into externally defined symbols
+	Nextdefsym         uint32 // number of externally defined symbols
+	Irefsym            uint32 // index into reference symbol table
+	Nrefsym            uint32 // number of reference symbol table entries
+	Ilocalsym          uint32 // index into symbols for local symbols
+	Nlocalsym          uint32 // number of local symbols
+	Iextrel            uint32 // index into external relocation entries
+	Nextrel            uint32 // number of external relocation entries
+	IinitIterm         uint32 // low 16 bits are the index into the init section, high 16 bits are the index into the term section
+	NinitNterm         uint32 // low 16 bits are the number of init section entries, high 16 bits are the number of term section entries
+	ObjcModuleInfoAddr uint32 // the (__OBJC,__module_info) section address
+	ObjcModuleInfoSize uint32 // the (__OBJC,__module_info) section size
+}
+
+// A DylibModule64 is a LC_DYSYMTAB 64-bit module table entry (dylib_module_64).
+type DylibModule64 struct {
+	ModuleName         uint32
+	Iextdefsym         uint32
+	Nextdefsym         uint32
+	Irefsym            uint32
+	Nrefsym            uint32
+	Ilocalsym          uint32
+	Nlocalsym          uint32
+	Iextrel            uint32
+	Nextrel            uint32
+	IinitIterm         uint32
+	NinitNterm         uint32
+	ObjcModuleInfoSize uint32
+	ObjcModuleInfoAddr uint64
+}
+
+// A DylibReference is a LC_DYSYMTAB external reference table entry (dylib_reference).
+type DylibReference struct {
+	SymIndex uint32    // index into the symbol table
+	Flags    NDescType // REFERENCE_FLAG_* and REFERENCED_DYNAMICALLY
+}
+
+const (
+	// An indirect symbol table entry is the index of the symbol in the symbol
+	// table or one of these values for non-lazy pointers to local/absolute symbols.
+	INDIRECT_SYMBOL_LOCAL = 0x80000000
+	INDIRECT_SYMBOL_ABS   = 0x40000000
+)
+
 // A DylibCmd is a Mach-O load dynamic library command.
 // LC_ID_DYLIB, LC_LOAD_{,WEAK_}DYLIB,LC_REEXPORT_DYLIB
 type DylibCmd struct {
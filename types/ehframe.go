@@ -0,0 +1,162 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EhPointerEncoding is a DW_EH_PE_* pointer encoding used in __TEXT,__eh_frame
+type EhPointerEncoding uint8
+
+const (
+	DW_EH_PE_absptr  EhPointerEncoding = 0x00
+	DW_EH_PE_uleb128 EhPointerEncoding = 0x01
+	DW_EH_PE_udata2  EhPointerEncoding = 0x02
+	DW_EH_PE_udata4  EhPointerEncoding = 0x03
+	DW_EH_PE_udata8  EhPointerEncoding = 0x04
+	DW_EH_PE_signed  EhPointerEncoding = 0x08
+	DW_EH_PE_sleb128 EhPointerEncoding = 0x09
+	DW_EH_PE_sdata2  EhPointerEncoding = 0x0a
+	DW_EH_PE_sdata4  EhPointerEncoding = 0x0b
+	DW_EH_PE_sdata8  EhPointerEncoding = 0x0c
+
+	DW_EH_PE_pcrel   EhPointerEncoding = 0x10
+	DW_EH_PE_textrel EhPointerEncoding = 0x20
+	DW_EH_PE_datarel EhPointerEncoding = 0x30
+	DW_EH_PE_funcrel EhPointerEncoding = 0x40
+	DW_EH_PE_aligned EhPointerEncoding = 0x50
+
+	DW_EH_PE_indirect EhPointerEncoding = 0x80
+	DW_EH_PE_omit     EhPointerEncoding = 0xff
+)
+
+// Format returns the value format of the encoding (i.e. DW_EH_PE_sdata4)
+func (e EhPointerEncoding) Format() EhPointerEncoding {
+	return e & 0x0f
+}
+
+// Application returns how the value is applied (i.e. DW_EH_PE_pcrel)
+func (e EhPointerEncoding) Application() EhPointerEncoding {
+	return e & 0x70
+}
+
+// IsIndirect reports whether the value is the address of the pointer rather than the pointer itself
+func (e EhPointerEncoding) IsIndirect() bool {
+	return e != DW_EH_PE_omit && (e&DW_EH_PE_indirect) != 0
+}
+
+func (e EhPointerEncoding) String() string {
+	if e == DW_EH_PE_omit {
+		return "omit"
+	}
+	var parts []string
+	if e.IsIndirect() {
+		parts = append(parts, "indirect")
+	}
+	switch e.Application() {
+	case DW_EH_PE_pcrel:
+		parts = append(parts, "pcrel")
+	case DW_EH_PE_textrel:
+		parts = append(parts, "textrel")
+	case DW_EH_PE_datarel:
+		parts = append(parts, "datarel")
+	case DW_EH_PE_funcrel:
+		parts = append(parts, "funcrel")
+	case DW_EH_PE_aligned:
+		parts = append(parts, "aligned")
+	}
+	switch e.Format() {
+	case DW_EH_PE_absptr:
+		parts = append(parts, "absptr")
+	case DW_EH_PE_uleb128:
+		parts = append(parts, "uleb128")
+	case DW_EH_PE_udata2:
+		parts = append(parts, "udata2")
+	case DW_EH_PE_udata4:
+		parts = append(parts, "udata4")
+	case DW_EH_PE_udata8:
+		parts = append(parts, "udata8")
+	case DW_EH_PE_sleb128:
+		parts = append(parts, "sleb128")
+	case DW_EH_PE_sdata2:
+		parts = append(parts, "sdata2")
+	case DW_EH_PE_sdata4:
+		parts = append(parts, "sdata4")
+	case DW_EH_PE_sdata8:
+		parts = append(parts, "sdata8")
+	default:
+		parts = append(parts, fmt.Sprintf("format(%#x)", uint8(e.Format())))
+	}
+	return strings.Join(parts, "|")
+}
+
+// CFAOp is a DWARF call frame instruction opcode
+type CFAOp uint8
+
+const (
+	DW_CFA_nop                          CFAOp = 0x00
+	DW_CFA_set_loc                      CFAOp = 0x01
+	DW_CFA_advance_loc1                 CFAOp = 0x02
+	DW_CFA_advance_loc2                 CFAOp = 0x03
+	DW_CFA_advance_loc4                 CFAOp = 0x04
+	DW_CFA_offset_extended              CFAOp = 0x05
+	DW_CFA_restore_extended             CFAOp = 0x06
+	DW_CFA_undefined                    CFAOp = 0x07
+	DW_CFA_same_value                   CFAOp = 0x08
+	DW_CFA_register                     CFAOp = 0x09
+	DW_CFA_remember_state               CFAOp = 0x0a
+	DW_CFA_restore_state                CFAOp = 0x0b
+	DW_CFA_def_cfa                      CFAOp = 0x0c
+	DW_CFA_def_cfa_register             CFAOp = 0x0d
+	DW_CFA_def_cfa_offset               CFAOp = 0x0e
+	DW_CFA_def_cfa_expression           CFAOp = 0x0f
+	DW_CFA_expression                   CFAOp = 0x10
+	DW_CFA_offset_extended_sf           CFAOp = 0x11
+	DW_CFA_def_cfa_sf                   CFAOp = 0x12
+	DW_CFA_def_cfa_offset_sf            CFAOp = 0x13
+	DW_CFA_val_offset                   CFAOp = 0x14
+	DW_CFA_val_offset_sf                CFAOp = 0x15
+	DW_CFA_val_expression               CFAOp = 0x16
+	DW_CFA_GNU_window_save              CFAOp = 0x2d // DW_CFA_AARCH64_negate_ra_state on arm64
+	DW_CFA_GNU_args_size                CFAOp = 0x2e
+	DW_CFA_GNU_negative_offset_extended CFAOp = 0x2f
+	DW_CFA_advance_loc                  CFAOp = 0x40 // high 2 bits; low 6 bits are the delta
+	DW_CFA_offset                       CFAOp = 0x80 // high 2 bits; low 6 bits are the register
+	DW_CFA_restore                      CFAOp = 0xc0 // high 2 bits; low 6 bits are the register
+	DW_CFA_high_2_bits_mask             CFAOp = 0xc0
+	DW_CFA_low_6_bits_mask              CFAOp = 0x3f
+)
+
+// RegisterRuleKind is the kind of a call frame register rule
+type RegisterRuleKind uint8
+
+const (
+	RuleUndefined     RegisterRuleKind = iota // the register can not be recovered
+	RuleSameValue                             // the register has not been modified
+	RuleOffset                                // the register is saved at CFA+Offset
+	RuleValOffset                             // the register's value is CFA+Offset
+	RuleRegister                              // the register is saved in another Register
+	RuleExpression                            // the register is saved at the address computed by Expression
+	RuleValExpression                         // the register's value is computed by Expression
+)
+
+func (k RegisterRuleKind) String() string {
+	switch k {
+	case RuleUndefined:
+		return "undefined"
+	case RuleSameValue:
+		return "same-value"
+	case RuleOffset:
+		return "offset"
+	case RuleValOffset:
+		return "val-offset"
+	case RuleRegister:
+		return "register"
+	case RuleExpression:
+		return "expression"
+	case RuleValExpression:
+		return "val-expression"
+	default:
+		return fmt.Sprintf("rule(%d)", k)
+	}
+}
@@ -1,6 +1,9 @@
 package types
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 const (
 	/* The following are used to encode rebasing information */
@@ -68,6 +71,129 @@ const (
 	DYLD_CACHE_ADJ_V2_THREADED_POINTER_64 = 0x0D
 )
 
+// SplitInfoKind is the kind of a LC_SEGMENT_SPLIT_INFO v2 reference (one of the DYLD_CACHE_ADJ_V2_* kinds)
+type SplitInfoKind uint8
+
+func (k SplitInfoKind) String() string {
+	switch k {
+	case DYLD_CACHE_ADJ_V2_POINTER_32:
+		return "pointer32"
+	case DYLD_CACHE_ADJ_V2_POINTER_64:
+		return "pointer64"
+	case DYLD_CACHE_ADJ_V2_DELTA_32:
+		return "delta32"
+	case DYLD_CACHE_ADJ_V2_DELTA_64:
+		return "delta64"
+	case DYLD_CACHE_ADJ_V2_ARM64_ADRP:
+		return "adrp"
+	case DYLD_CACHE_ADJ_V2_ARM64_OFF12:
+		return "off12"
+	case DYLD_CACHE_ADJ_V2_ARM64_BR26:
+		return "br26"
+	case DYLD_CACHE_ADJ_V2_ARM_MOVW_MOVT:
+		return "movw/movt"
+	case DYLD_CACHE_ADJ_V2_ARM_BR24:
+		return "br24"
+	case DYLD_CACHE_ADJ_V2_THUMB_MOVW_MOVT:
+		return "thumb movw/movt"
+	case DYLD_CACHE_ADJ_V2_THUMB_BR22:
+		return "thumb br22"
+	case DYLD_CACHE_ADJ_V2_IMAGE_OFF_32:
+		return "image offset32"
+	case DYLD_CACHE_ADJ_V2_THREADED_POINTER_64:
+		return "threaded pointer64"
+	default:
+		return fmt.Sprintf("kind(%d)", k)
+	}
+}
+
+// SplitInfoV1Kind is the kind of a LC_SEGMENT_SPLIT_INFO v1 reference
+type SplitInfoV1Kind uint8
+
+const (
+	SPLIT_INFO_V1_POINTER_32    SplitInfoV1Kind = 0x01
+	SPLIT_INFO_V1_POINTER_64    SplitInfoV1Kind = 0x02
+	SPLIT_INFO_V1_PPC_HI16      SplitInfoV1Kind = 0x03
+	SPLIT_INFO_V1_IMPORT_OFF_32 SplitInfoV1Kind = 0x04
+	SPLIT_INFO_V1_THUMB2_MOVW   SplitInfoV1Kind = 0x05
+	SPLIT_INFO_V1_ARM_MOVW      SplitInfoV1Kind = 0x06
+	SPLIT_INFO_V1_THUMB2_MOVT   SplitInfoV1Kind = 0x10 // 0x10-0x1F, low 4 bits are bits 16-19 of the target
+	SPLIT_INFO_V1_ARM_MOVT      SplitInfoV1Kind = 0x20 // 0x20-0x2F, low 4 bits are bits 16-19 of the target
+)
+
+func (k SplitInfoV1Kind) String() string {
+	switch {
+	case k == SPLIT_INFO_V1_POINTER_32:
+		return "pointer32"
+	case k == SPLIT_INFO_V1_POINTER_64:
+		return "pointer64"
+	case k == SPLIT_INFO_V1_PPC_HI16:
+		return "ppc hi16"
+	case k == SPLIT_INFO_V1_IMPORT_OFF_32:
+		return "import offset32"
+	case k == SPLIT_INFO_V1_THUMB2_MOVW:
+		return "thumb2 movw"
+	case k == SPLIT_INFO_V1_ARM_MOVW:
+		return "arm movw"
+	case k&0xF0 == SPLIT_INFO_V1_THUMB2_MOVT:
+		return "thumb2 movt"
+	case k&0xF0 == SPLIT_INFO_V1_ARM_MOVT:
+		return "arm movt"
+	default:
+		return fmt.Sprintf("kind(%d)", k)
+	}
+}
+
+type RebaseType uint8
+
+func (t RebaseType) String() string {
+	switch t {
+	case REBASE_TYPE_POINTER:
+		return "pointer"
+	case REBASE_TYPE_TEXT_ABSOLUTE32:
+		return "text abs32"
+	case REBASE_TYPE_TEXT_PCREL32:
+		return "text pcrel32"
+	default:
+		return fmt.Sprintf("type(%d)", t)
+	}
+}
+
+type BindType uint8
+
+func (t BindType) String() string {
+	switch t {
+	case BIND_TYPE_POINTER:
+		return "pointer"
+	case BIND_TYPE_TEXT_ABSOLUTE32:
+		return "text abs32"
+	case BIND_TYPE_TEXT_PCREL32:
+		return "text pcrel32"
+	default:
+		return fmt.Sprintf("type(%d)", t)
+	}
+}
+
+type BindSymbolFlag uint8
+
+func (f BindSymbolFlag) WeakImport() bool {
+	return (f & BIND_SYMBOL_FLAGS_WEAK_IMPORT) != 0
+}
+func (f BindSymbolFlag) NonWeakDefinition() bool {
+	return (f & BIND_SYMBOL_FLAGS_NON_WEAK_DEFINITION) != 0
+}
+
+func (f BindSymbolFlag) String() string {
+	var fStr []string
+	if f.WeakImport() {
+		fStr = append(fStr, "weak_import")
+	}
+	if f.NonWeakDefinition() {
+		fStr = append(fStr, "non_weak_definition")
+	}
+	return strings.Join(fStr, "|")
+}
+
 type ExportFlag int
 
 const (
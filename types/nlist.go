@@ -147,6 +147,9 @@ const (
 	REFERENCE_FLAG_PRIVATE_UNDEFINED_LAZY     NDescType = 5
 )
 
+// REFERENCED_DYNAMICALLY marks a symbol that must never be stripped, as it is looked up at runtime (e.g. by dyld)
+const REFERENCED_DYNAMICALLY NDescType = 0x0010
+
 func (d NDescType) IsUndefinedNonLazy() bool {
 	return (d & REFERENCE_TYPE) == REFERENCE_FLAG_UNDEFINED_NON_LAZY
 }
@@ -6,6 +6,13 @@ package types
 
 //go:generate stringer -type=RelocTypeGeneric,RelocTypeX86_64,RelocTypeARM,RelocTypeARM64 -output reloc_string.go
 
+// A RelocType is one of the CPU specific relocation types
+// (RelocTypeGeneric, RelocTypeX86_64, RelocTypeARM or RelocTypeARM64).
+type RelocType interface {
+	String() string
+	GoString() string
+}
+
 type RelocTypeGeneric int
 
 const (
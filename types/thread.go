@@ -0,0 +1,199 @@
+package types
+
+import "fmt"
+
+// A ThreadFlavor is the flavor of a thread state in a LC_THREAD or LC_UNIXTHREAD command.
+// Flavor values are only unique per CPU type.
+type ThreadFlavor uint32
+
+// x86 thread flavors
+const (
+	X86_THREAD_STATE32      ThreadFlavor = 1
+	X86_FLOAT_STATE32       ThreadFlavor = 2
+	X86_EXCEPTION_STATE32   ThreadFlavor = 3
+	X86_THREAD_STATE64      ThreadFlavor = 4
+	X86_FLOAT_STATE64       ThreadFlavor = 5
+	X86_EXCEPTION_STATE64   ThreadFlavor = 6
+	X86_THREAD_STATE        ThreadFlavor = 7
+	X86_FLOAT_STATE         ThreadFlavor = 8
+	X86_EXCEPTION_STATE     ThreadFlavor = 9
+	X86_DEBUG_STATE32       ThreadFlavor = 10
+	X86_DEBUG_STATE64       ThreadFlavor = 11
+	X86_DEBUG_STATE         ThreadFlavor = 12
+	X86_THREAD_STATE_NONE   ThreadFlavor = 13
+	X86_AVX_STATE32         ThreadFlavor = 16
+	X86_AVX_STATE64         ThreadFlavor = 17
+	X86_AVX_STATE           ThreadFlavor = 18
+	X86_AVX512_STATE32      ThreadFlavor = 19
+	X86_AVX512_STATE64      ThreadFlavor = 20
+	X86_AVX512_STATE        ThreadFlavor = 21
+	X86_PAGEIN_STATE        ThreadFlavor = 22
+	X86_THREAD_FULL_STATE64 ThreadFlavor = 23
+)
+
+// ARM thread flavors
+const (
+	ARM_THREAD_STATE      ThreadFlavor = 1
+	ARM_VFP_STATE         ThreadFlavor = 2
+	ARM_EXCEPTION_STATE   ThreadFlavor = 3
+	ARM_DEBUG_STATE       ThreadFlavor = 4
+	ARM_THREAD_STATE_NONE ThreadFlavor = 5
+	ARM_THREAD_STATE64    ThreadFlavor = 6
+	ARM_EXCEPTION_STATE64 ThreadFlavor = 7
+	ARM_THREAD_STATE32    ThreadFlavor = 9
+	ARM_DEBUG_STATE32     ThreadFlavor = 14
+	ARM_DEBUG_STATE64     ThreadFlavor = 15
+	ARM_NEON_STATE        ThreadFlavor = 16
+	ARM_NEON_STATE64      ThreadFlavor = 17
+	ARM_CPMU_STATE64      ThreadFlavor = 18
+	ARM_PAGEIN_STATE      ThreadFlavor = 27
+)
+
+// PowerPC thread flavors
+const (
+	PPC_THREAD_STATE      ThreadFlavor = 1
+	PPC_FLOAT_STATE       ThreadFlavor = 2
+	PPC_EXCEPTION_STATE   ThreadFlavor = 3
+	PPC_VECTOR_STATE      ThreadFlavor = 4
+	PPC_THREAD_STATE64    ThreadFlavor = 5
+	PPC_EXCEPTION_STATE64 ThreadFlavor = 6
+	PPC_THREAD_STATE_NONE ThreadFlavor = 7
+)
+
+var x86ThreadFlavorStrings = []IntName{
+	{uint32(X86_THREAD_STATE32), "x86_THREAD_STATE32"},
+	{uint32(X86_FLOAT_STATE32), "x86_FLOAT_STATE32"},
+	{uint32(X86_EXCEPTION_STATE32), "x86_EXCEPTION_STATE32"},
+	{uint32(X86_THREAD_STATE64), "x86_THREAD_STATE64"},
+	{uint32(X86_FLOAT_STATE64), "x86_FLOAT_STATE64"},
+	{uint32(X86_EXCEPTION_STATE64), "x86_EXCEPTION_STATE64"},
+	{uint32(X86_THREAD_STATE), "x86_THREAD_STATE"},
+	{uint32(X86_FLOAT_STATE), "x86_FLOAT_STATE"},
+	{uint32(X86_EXCEPTION_STATE), "x86_EXCEPTION_STATE"},
+	{uint32(X86_DEBUG_STATE32), "x86_DEBUG_STATE32"},
+	{uint32(X86_DEBUG_STATE64), "x86_DEBUG_STATE64"},
+	{uint32(X86_DEBUG_STATE), "x86_DEBUG_STATE"},
+	{uint32(X86_THREAD_STATE_NONE), "THREAD_STATE_NONE"},
+	{uint32(X86_AVX_STATE32), "x86_AVX_STATE32"},
+	{uint32(X86_AVX_STATE64), "x86_AVX_STATE64"},
+	{uint32(X86_AVX_STATE), "x86_AVX_STATE"},
+	{uint32(X86_AVX512_STATE32), "x86_AVX512_STATE32"},
+	{uint32(X86_AVX512_STATE64), "x86_AVX512_STATE64"},
+	{uint32(X86_AVX512_STATE), "x86_AVX512_STATE"},
+	{uint32(X86_PAGEIN_STATE), "x86_PAGEIN_STATE"},
+	{uint32(X86_THREAD_FULL_STATE64), "x86_THREAD_FULL_STATE64"},
+}
+
+var armThreadFlavorStrings = []IntName{
+	{uint32(ARM_THREAD_STATE), "ARM_THREAD_STATE"},
+	{uint32(ARM_VFP_STATE), "ARM_VFP_STATE"},
+	{uint32(ARM_EXCEPTION_STATE), "ARM_EXCEPTION_STATE"},
+	{uint32(ARM_DEBUG_STATE), "ARM_DEBUG_STATE"},
+	{uint32(ARM_THREAD_STATE_NONE), "THREAD_STATE_NONE"},
+	{uint32(ARM_THREAD_STATE64), "ARM_THREAD_STATE64"},
+	{uint32(ARM_EXCEPTION_STATE64), "ARM_EXCEPTION_STATE64"},
+	{uint32(ARM_THREAD_STATE32), "ARM_THREAD_STATE32"},
+	{uint32(ARM_DEBUG_STATE32), "ARM_DEBUG_STATE32"},
+	{uint32(ARM_DEBUG_STATE64), "ARM_DEBUG_STATE64"},
+	{uint32(ARM_NEON_STATE), "ARM_NEON_STATE"},
+	{uint32(ARM_NEON_STATE64), "ARM_NEON_STATE64"},
+	{uint32(ARM_CPMU_STATE64), "ARM_CPMU_STATE64"},
+	{uint32(ARM_PAGEIN_STATE), "ARM_PAGEIN_STATE"},
+}
+
+var ppcThreadFlavorStrings = []IntName{
+	{uint32(PPC_THREAD_STATE), "PPC_THREAD_STATE"},
+	{uint32(PPC_FLOAT_STATE), "PPC_FLOAT_STATE"},
+	{uint32(PPC_EXCEPTION_STATE), "PPC_EXCEPTION_STATE"},
+	{uint32(PPC_VECTOR_STATE), "PPC_VECTOR_STATE"},
+	{uint32(PPC_THREAD_STATE64), "PPC_THREAD_STATE64"},
+	{uint32(PPC_EXCEPTION_STATE64), "PPC_EXCEPTION_STATE64"},
+	{uint32(PPC_THREAD_STATE_NONE), "THREAD_STATE_NONE"},
+}
+
+// Name returns the flavor's name for the given CPU type.
+func (f ThreadFlavor) Name(cpu CPU) string {
+	switch cpu {
+	case CPU386, CPUAmd64:
+		return StringName(uint32(f), x86ThreadFlavorStrings, false)
+	case CPUArm, CPUArm64, CPUArm6432:
+		return StringName(uint32(f), armThreadFlavorStrings, false)
+	case CPUPpc, CPUPpc64:
+		return StringName(uint32(f), ppcThreadFlavorStrings, false)
+	default:
+		return fmt.Sprintf("flavor(%d)", f)
+	}
+}
+
+// A ThreadStateKind is the kind of register state a thread flavor holds.
+type ThreadStateKind uint8
+
+const (
+	ThreadStateUnknown ThreadStateKind = iota
+	ThreadStateGeneral
+	ThreadStateFloat
+	ThreadStateException
+	ThreadStateDebug
+	ThreadStateVector
+)
+
+func (k ThreadStateKind) String() string {
+	switch k {
+	case ThreadStateGeneral:
+		return "general"
+	case ThreadStateFloat:
+		return "float"
+	case ThreadStateException:
+		return "exception"
+	case ThreadStateDebug:
+		return "debug"
+	case ThreadStateVector:
+		return "vector"
+	default:
+		return "unknown"
+	}
+}
+
+// Kind returns the kind of register state the flavor holds for the given CPU type.
+func (f ThreadFlavor) Kind(cpu CPU) ThreadStateKind {
+	switch cpu {
+	case CPU386, CPUAmd64:
+		switch f {
+		case X86_THREAD_STATE32, X86_THREAD_STATE64, X86_THREAD_STATE, X86_THREAD_FULL_STATE64:
+			return ThreadStateGeneral
+		case X86_FLOAT_STATE32, X86_FLOAT_STATE64, X86_FLOAT_STATE:
+			return ThreadStateFloat
+		case X86_EXCEPTION_STATE32, X86_EXCEPTION_STATE64, X86_EXCEPTION_STATE:
+			return ThreadStateException
+		case X86_DEBUG_STATE32, X86_DEBUG_STATE64, X86_DEBUG_STATE:
+			return ThreadStateDebug
+		case X86_AVX_STATE32, X86_AVX_STATE64, X86_AVX_STATE, X86_AVX512_STATE32, X86_AVX512_STATE64, X86_AVX512_STATE:
+			return ThreadStateVector
+		}
+	case CPUArm, CPUArm64, CPUArm6432:
+		switch f {
+		case ARM_THREAD_STATE, ARM_THREAD_STATE64, ARM_THREAD_STATE32:
+			return ThreadStateGeneral
+		case ARM_VFP_STATE:
+			return ThreadStateFloat
+		case ARM_EXCEPTION_STATE, ARM_EXCEPTION_STATE64:
+			return ThreadStateException
+		case ARM_DEBUG_STATE, ARM_DEBUG_STATE32, ARM_DEBUG_STATE64:
+			return ThreadStateDebug
+		case ARM_NEON_STATE, ARM_NEON_STATE64:
+			return ThreadStateVector
+		}
+	case CPUPpc, CPUPpc64:
+		switch f {
+		case PPC_THREAD_STATE, PPC_THREAD_STATE64:
+			return ThreadStateGeneral
+		case PPC_FLOAT_STATE:
+			return ThreadStateFloat
+		case PPC_EXCEPTION_STATE, PPC_EXCEPTION_STATE64:
+			return ThreadStateException
+		case PPC_VECTOR_STATE:
+			return ThreadStateVector
+		}
+	}
+	return ThreadStateUnknown
+}
@@ -72,8 +72,39 @@ const (
 	driverKit        Platform = 10 // PLATFORM_DRIVERKIT
 )
 
+// Exported platform values (PLATFORM_*)
+const (
+	PlatformUnknown          = unknown
+	PlatformMacOS            = macOS
+	PlatformIOS              = iOS
+	PlatformTvOS             = tvOS
+	PlatformWatchOS          = watchOS
+	PlatformBridgeOS         = bridgeOS
+	PlatformMacCatalyst      = macCatalyst
+	PlatformIOSSimulator     = iOSSimulator
+	PlatformTvOSSimulator    = tvOSSimulator
+	PlatformWatchOSSimulator = watchOSSimulator
+	PlatformDriverKit        = driverKit
+)
+
+// Version is a X.Y.Z version encoded in nibbles xxxx.yy.zz; encoded versions compare in version order
 type Version uint32
 
+// Major returns the X of X.Y.Z
+func (v Version) Major() uint16 {
+	return uint16(v >> 16)
+}
+
+// Minor returns the Y of X.Y.Z
+func (v Version) Minor() uint8 {
+	return uint8(v >> 8)
+}
+
+// Patch returns the Z of X.Y.Z
+func (v Version) Patch() uint8 {
+	return uint8(v)
+}
+
 func (v Version) String() string {
 	s := make([]byte, 4)
 	binary.BigEndian.PutUint32(s, uint32(v))
@@ -100,6 +131,14 @@ const (
 	ld    Tool = 3 // TOOL_LD
 )
 
+// Exported tool values (TOOL_*)
+const (
+	ToolNone  = none
+	ToolClang = clang
+	ToolSwift = swift
+	ToolLD    = ld
+)
+
 type BuildToolVersion struct {
 	Tool    Tool    /* enum for the tool */
 	Version Version /* version number of the tool */
@@ -121,6 +160,62 @@ const (
 	KindAbsJumpTable32 DiceKind = 0x0005
 )
 
+// LOHKind is the kind of a LC_LINKER_OPTIMIZATION_HINT record
+type LOHKind uint64
+
+const (
+	LOH_ARM64_ADRP_ADRP        LOHKind = 1
+	LOH_ARM64_ADRP_LDR         LOHKind = 2
+	LOH_ARM64_ADRP_ADD_LDR     LOHKind = 3
+	LOH_ARM64_ADRP_LDR_GOT_LDR LOHKind = 4
+	LOH_ARM64_ADRP_ADD_STR     LOHKind = 5
+	LOH_ARM64_ADRP_LDR_GOT_STR LOHKind = 6
+	LOH_ARM64_ADRP_ADD         LOHKind = 7
+	LOH_ARM64_ADRP_LDR_GOT     LOHKind = 8
+)
+
+func (k LOHKind) String() string {
+	switch k {
+	case LOH_ARM64_ADRP_ADRP:
+		return "AdrpAdrp"
+	case LOH_ARM64_ADRP_LDR:
+		return "AdrpLdr"
+	case LOH_ARM64_ADRP_ADD_LDR:
+		return "AdrpAddLdr"
+	case LOH_ARM64_ADRP_LDR_GOT_LDR:
+		return "AdrpLdrGotLdr"
+	case LOH_ARM64_ADRP_ADD_STR:
+		return "AdrpAddStr"
+	case LOH_ARM64_ADRP_LDR_GOT_STR:
+		return "AdrpLdrGotStr"
+	case LOH_ARM64_ADRP_ADD:
+		return "AdrpAdd"
+	case LOH_ARM64_ADRP_LDR_GOT:
+		return "AdrpLdrGot"
+	default:
+		return fmt.Sprintf("LOHKind(%d)", uint64(k))
+	}
+}
+
+// NumArgs returns the number of instruction addresses a hint of this kind references
+func (k LOHKind) NumArgs() int {
+	switch k {
+	case LOH_ARM64_ADRP_ADRP, LOH_ARM64_ADRP_LDR, LOH_ARM64_ADRP_ADD, LOH_ARM64_ADRP_LDR_GOT:
+		return 2
+	case LOH_ARM64_ADRP_ADD_LDR, LOH_ARM64_ADRP_LDR_GOT_LDR, LOH_ARM64_ADRP_ADD_STR, LOH_ARM64_ADRP_LDR_GOT_STR:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// LinkerOptimizationHint is a LC_LINKER_OPTIMIZATION_HINT record;
+// the addresses of the instructions (in order) that make up the hinted sequence
+type LinkerOptimizationHint struct {
+	Kind  LOHKind
+	Addrs []uint64
+}
+
 type Function struct {
 	Name      string
 	StartAddr uint64
@@ -0,0 +1,380 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UNWIND_SECTION_VERSION is the only __TEXT,__unwind_info version
+const UNWIND_SECTION_VERSION = 1
+
+// UnwindInfoSectionHeader is the header of the __TEXT,__unwind_info section
+type UnwindInfoSectionHeader struct {
+	Version                           uint32 // UNWIND_SECTION_VERSION
+	CommonEncodingsArraySectionOffset uint32
+	CommonEncodingsArrayCount         uint32
+	PersonalityArraySectionOffset     uint32
+	PersonalityArrayCount             uint32
+	IndexSectionOffset                uint32
+	IndexCount                        uint32
+}
+
+// UnwindInfoSectionHeaderIndexEntry is a first-level index entry of the __TEXT,__unwind_info section
+type UnwindInfoSectionHeaderIndexEntry struct {
+	FunctionOffset                uint32
+	SecondLevelPagesSectionOffset uint32 // section offset to start of regular or compress page
+	LsdaIndexArraySectionOffset   uint32 // section offset to start of lsda_index array for this range
+}
+
+// UnwindInfoSectionHeaderLsdaIndexEntry maps a function to its language specific data area
+type UnwindInfoSectionHeaderLsdaIndexEntry struct {
+	FunctionOffset uint32
+	LsdaOffset     uint32
+}
+
+// UnwindSecondLevelKind is the kind of a __TEXT,__unwind_info second-level page
+type UnwindSecondLevelKind uint32
+
+const (
+	UNWIND_SECOND_LEVEL_REGULAR    UnwindSecondLevelKind = 2
+	UNWIND_SECOND_LEVEL_COMPRESSED UnwindSecondLevelKind = 3
+)
+
+func (k UnwindSecondLevelKind) String() string {
+	switch k {
+	case UNWIND_SECOND_LEVEL_REGULAR:
+		return "regular"
+	case UNWIND_SECOND_LEVEL_COMPRESSED:
+		return "compressed"
+	default:
+		return fmt.Sprintf("kind(%d)", k)
+	}
+}
+
+// UnwindInfoRegularSecondLevelPageHeader is the header of a regular second-level page
+type UnwindInfoRegularSecondLevelPageHeader struct {
+	Kind            UnwindSecondLevelKind // UNWIND_SECOND_LEVEL_REGULAR
+	EntryPageOffset uint16
+	EntryCount      uint16
+	// entry array of UnwindInfoRegularSecondLevelEntry
+}
+
+// UnwindInfoRegularSecondLevelEntry is an entry in a regular second-level page
+type UnwindInfoRegularSecondLevelEntry struct {
+	FunctionOffset uint32
+	Encoding       CompactUnwindEncoding
+}
+
+// UnwindInfoCompressedSecondLevelPageHeader is the header of a compressed second-level page
+type UnwindInfoCompressedSecondLevelPageHeader struct {
+	Kind                UnwindSecondLevelKind // UNWIND_SECOND_LEVEL_COMPRESSED
+	EntryPageOffset     uint16
+	EntryCount          uint16
+	EncodingsPageOffset uint16
+	EncodingsCount      uint16
+	// 32-bit entry array
+	// encodings array
+}
+
+// UnwindInfoCompressedEntry is an entry in a compressed second-level page;
+// the low 24 bits are the function offset from the first-level index entry
+// and the high 8 bits index the common encodings followed by the page's encodings.
+type UnwindInfoCompressedEntry uint32
+
+func (e UnwindInfoCompressedEntry) FuncOffset() uint32 {
+	return uint32(e) & 0x00FFFFFF
+}
+func (e UnwindInfoCompressedEntry) EncodingIndex() uint32 {
+	return (uint32(e) >> 24) & 0xFF
+}
+
+// CompactUnwindEntry32 is an entry of the __LD,__compact_unwind section of a 32-bit object
+type CompactUnwindEntry32 struct {
+	FunctionStart uint32
+	FunctionSize  uint32
+	Encoding      CompactUnwindEncoding
+	Personality   uint32
+	Lsda          uint32
+}
+
+// CompactUnwindEntry64 is an entry of the __LD,__compact_unwind section of a 64-bit object
+type CompactUnwindEntry64 struct {
+	FunctionStart uint64
+	FunctionSize  uint32
+	Encoding      CompactUnwindEncoding
+	Personality   uint64
+	Lsda          uint64
+}
+
+// CompactUnwindEncoding is a compact unwind encoding
+type CompactUnwindEncoding uint32
+
+const (
+	UNWIND_IS_NOT_FUNCTION_START CompactUnwindEncoding = 0x80000000
+	UNWIND_HAS_LSDA              CompactUnwindEncoding = 0x40000000
+	UNWIND_PERSONALITY_MASK      CompactUnwindEncoding = 0x30000000
+	UNWIND_MODE_MASK             CompactUnwindEncoding = 0x0F000000
+)
+
+// x86_64 compact unwind encodings
+const (
+	UNWIND_X86_64_MODE_MASK       CompactUnwindEncoding = 0x0F000000
+	UNWIND_X86_64_MODE_RBP_FRAME  CompactUnwindEncoding = 0x01000000
+	UNWIND_X86_64_MODE_STACK_IMMD CompactUnwindEncoding = 0x02000000
+	UNWIND_X86_64_MODE_STACK_IND  CompactUnwindEncoding = 0x03000000
+	UNWIND_X86_64_MODE_DWARF      CompactUnwindEncoding = 0x04000000
+
+	UNWIND_X86_64_RBP_FRAME_REGISTERS CompactUnwindEncoding = 0x00007FFF
+	UNWIND_X86_64_RBP_FRAME_OFFSET    CompactUnwindEncoding = 0x00FF0000
+
+	UNWIND_X86_64_FRAMELESS_STACK_SIZE            CompactUnwindEncoding = 0x00FF0000
+	UNWIND_X86_64_FRAMELESS_STACK_ADJUST          CompactUnwindEncoding = 0x0000E000
+	UNWIND_X86_64_FRAMELESS_STACK_REG_COUNT       CompactUnwindEncoding = 0x00001C00
+	UNWIND_X86_64_FRAMELESS_STACK_REG_PERMUTATION CompactUnwindEncoding = 0x000003FF
+
+	UNWIND_X86_64_DWARF_SECTION_OFFSET CompactUnwindEncoding = 0x00FFFFFF
+)
+
+// arm64 compact unwind encodings
+const (
+	UNWIND_ARM64_MODE_MASK      CompactUnwindEncoding = 0x0F000000
+	UNWIND_ARM64_MODE_FRAMELESS CompactUnwindEncoding = 0x02000000
+	UNWIND_ARM64_MODE_DWARF     CompactUnwindEncoding = 0x03000000
+	UNWIND_ARM64_MODE_FRAME     CompactUnwindEncoding = 0x04000000
+
+	UNWIND_ARM64_FRAME_X19_X20_PAIR CompactUnwindEncoding = 0x00000001
+	UNWIND_ARM64_FRAME_X21_X22_PAIR CompactUnwindEncoding = 0x00000002
+	UNWIND_ARM64_FRAME_X23_X24_PAIR CompactUnwindEncoding = 0x00000004
+	UNWIND_ARM64_FRAME_X25_X26_PAIR CompactUnwindEncoding = 0x00000008
+	UNWIND_ARM64_FRAME_X27_X28_PAIR CompactUnwindEncoding = 0x00000010
+	UNWIND_ARM64_FRAME_D8_D9_PAIR   CompactUnwindEncoding = 0x00000100
+	UNWIND_ARM64_FRAME_D10_D11_PAIR CompactUnwindEncoding = 0x00000200
+	UNWIND_ARM64_FRAME_D12_D13_PAIR CompactUnwindEncoding = 0x00000400
+	UNWIND_ARM64_FRAME_D14_D15_PAIR CompactUnwindEncoding = 0x00000800
+
+	UNWIND_ARM64_FRAMELESS_STACK_SIZE_MASK CompactUnwindEncoding = 0x00FFF000
+	UNWIND_ARM64_DWARF_SECTION_OFFSET      CompactUnwindEncoding = 0x00FFFFFF
+)
+
+// IsFunctionStart reports whether the encoding starts a function (as opposed to continuing the previous one)
+func (e CompactUnwindEncoding) IsFunctionStart() bool {
+	return (e & UNWIND_IS_NOT_FUNCTION_START) == 0
+}
+
+// HasLSDA reports whether the function has a language specific data area
+func (e CompactUnwindEncoding) HasLSDA() bool {
+	return (e & UNWIND_HAS_LSDA) != 0
+}
+
+// PersonalityIndex returns the 1-based index of the function's personality routine (0 means none)
+func (e CompactUnwindEncoding) PersonalityIndex() uint32 {
+	return uint32(e&UNWIND_PERSONALITY_MASK) >> 28
+}
+
+// Mode returns the CPU specific unwind mode bits
+func (e CompactUnwindEncoding) Mode() CompactUnwindEncoding {
+	return e & UNWIND_MODE_MASK
+}
+
+func (e CompactUnwindEncoding) String() string {
+	return fmt.Sprintf("%#08x", uint32(e))
+}
+
+// UnwindX86_64 is a decoded x86_64 compact unwind encoding
+type UnwindX86_64 struct {
+	Mode CompactUnwindEncoding
+	// UNWIND_X86_64_MODE_RBP_FRAME: the saved registers are stored at RBP-FrameOffset*8
+	FrameOffset uint32
+	// UNWIND_X86_64_MODE_STACK_IMMD: the stack size (including the return address)
+	StackSize uint32
+	// UNWIND_X86_64_MODE_STACK_IND: the offset into the function of the 32-bit stack size immediate
+	// (i.e. the subq $nnnnnn,%rsp) and the adjustment to add to it
+	StackSizeOffset uint32
+	StackAdjust     uint32
+	// the callee saved registers in the order they are pushed
+	SavedRegisters []string
+	// UNWIND_X86_64_MODE_DWARF: the offset of the function's FDE in __TEXT,__eh_frame
+	DwarfOffset uint32
+}
+
+var x86_64UnwindRegisters = []string{"", "rbx", "r12", "r13", "r14", "r15", "rbp"}
+
+// X86_64 decodes the encoding as an x86_64 compact unwind encoding
+func (e CompactUnwindEncoding) X86_64() UnwindX86_64 {
+	u := UnwindX86_64{Mode: e & UNWIND_X86_64_MODE_MASK}
+	switch u.Mode {
+	case UNWIND_X86_64_MODE_RBP_FRAME:
+		u.FrameOffset = uint32(e&UNWIND_X86_64_RBP_FRAME_OFFSET) >> 16
+		regs := uint32(e & UNWIND_X86_64_RBP_FRAME_REGISTERS)
+		for i := 0; i < 5; i++ {
+			if reg := (regs >> (3 * i)) & 0x7; reg > 0 && int(reg) < len(x86_64UnwindRegisters) {
+				u.SavedRegisters = append(u.SavedRegisters, x86_64UnwindRegisters[reg])
+			}
+		}
+	case UNWIND_X86_64_MODE_STACK_IMMD, UNWIND_X86_64_MODE_STACK_IND:
+		size := uint32(e&UNWIND_X86_64_FRAMELESS_STACK_SIZE) >> 16
+		if u.Mode == UNWIND_X86_64_MODE_STACK_IMMD {
+			u.StackSize = size * 8
+		} else {
+			u.StackSizeOffset = size
+			u.StackAdjust = (uint32(e&UNWIND_X86_64_FRAMELESS_STACK_ADJUST) >> 13) * 8
+		}
+		count := uint32(e&UNWIND_X86_64_FRAMELESS_STACK_REG_COUNT) >> 10
+		for _, reg := range unwindPermutation(uint32(e&UNWIND_X86_64_FRAMELESS_STACK_REG_PERMUTATION), count) {
+			u.SavedRegisters = append(u.SavedRegisters, x86_64UnwindRegisters[reg])
+		}
+	case UNWIND_X86_64_MODE_DWARF:
+		u.DwarfOffset = uint32(e & UNWIND_X86_64_DWARF_SECTION_OFFSET)
+	}
+	return u
+}
+
+// unwindPermutation decodes the frameless register permutation into register numbers (1-6) in push order
+func unwindPermutation(permutation, count uint32) []uint32 {
+	if count == 0 || count > 6 {
+		return nil
+	}
+	var permunreg [6]uint32
+	switch count {
+	case 6, 5:
+		permunreg[0] = permutation / 120
+		permutation -= permunreg[0] * 120
+		permunreg[1] = permutation / 24
+		permutation -= permunreg[1] * 24
+		permunreg[2] = permutation / 6
+		permutation -= permunreg[2] * 6
+		permunreg[3] = permutation / 2
+		permutation -= permunreg[3] * 2
+		permunreg[4] = permutation
+	case 4:
+		permunreg[0] = permutation / 60
+		permutation -= permunreg[0] * 60
+		permunreg[1] = permutation / 12
+		permutation -= permunreg[1] * 12
+		permunreg[2] = permutation / 3
+		permutation -= permunreg[2] * 3
+		permunreg[3] = permutation
+	case 3:
+		permunreg[0] = permutation / 20
+		permutation -= permunreg[0] * 20
+		permunreg[1] = permutation / 4
+		permutation -= permunreg[1] * 4
+		permunreg[2] = permutation
+	case 2:
+		permunreg[0] = permutation / 5
+		permutation -= permunreg[0] * 5
+		permunreg[1] = permutation
+	case 1:
+		permunreg[0] = permutation
+	}
+	// renumber the registers back to standard numbers
+	var regs []uint32
+	var used [7]bool
+	for i := uint32(0); i < count; i++ {
+		renum := uint32(0)
+		for u := uint32(1); u < 7; u++ {
+			if !used[u] {
+				if renum == permunreg[i] {
+					regs = append(regs, u)
+					used[u] = true
+					break
+				}
+				renum++
+			}
+		}
+	}
+	return regs
+}
+
+// UnwindARM64 is a decoded arm64 compact unwind encoding
+type UnwindARM64 struct {
+	Mode CompactUnwindEncoding
+	// UNWIND_ARM64_MODE_FRAMELESS: the stack size
+	StackSize uint32
+	// UNWIND_ARM64_MODE_FRAME: the callee saved register pairs in the order they are stored below the frame record
+	SavedPairs []string
+	// UNWIND_ARM64_MODE_DWARF: the offset of the function's FDE in __TEXT,__eh_frame
+	DwarfOffset uint32
+}
+
+var arm64UnwindPairs = []struct {
+	flag CompactUnwindEncoding
+	name string
+}{
+	{UNWIND_ARM64_FRAME_X19_X20_PAIR, "x19/x20"},
+	{UNWIND_ARM64_FRAME_X21_X22_PAIR, "x21/x22"},
+	{UNWIND_ARM64_FRAME_X23_X24_PAIR, "x23/x24"},
+	{UNWIND_ARM64_FRAME_X25_X26_PAIR, "x25/x26"},
+	{UNWIND_ARM64_FRAME_X27_X28_PAIR, "x27/x28"},
+	{UNWIND_ARM64_FRAME_D8_D9_PAIR, "d8/d9"},
+	{UNWIND_ARM64_FRAME_D10_D11_PAIR, "d10/d11"},
+	{UNWIND_ARM64_FRAME_D12_D13_PAIR, "d12/d13"},
+	{UNWIND_ARM64_FRAME_D14_D15_PAIR, "d14/d15"},
+}
+
+// ARM64 decodes the encoding as an arm64 compact unwind encoding
+func (e CompactUnwindEncoding) ARM64() UnwindARM64 {
+	u := UnwindARM64{Mode: e & UNWIND_ARM64_MODE_MASK}
+	switch u.Mode {
+	case UNWIND_ARM64_MODE_FRAMELESS:
+		u.StackSize = (uint32(e&UNWIND_ARM64_FRAMELESS_STACK_SIZE_MASK) >> 12) * 16
+	case UNWIND_ARM64_MODE_FRAME:
+		for _, pair := range arm64UnwindPairs {
+			if e&pair.flag != 0 {
+				u.SavedPairs = append(u.SavedPairs, pair.name)
+			}
+		}
+	case UNWIND_ARM64_MODE_DWARF:
+		u.DwarfOffset = uint32(e & UNWIND_ARM64_DWARF_SECTION_OFFSET)
+	}
+	return u
+}
+
+// Description returns a human readable description of the encoding for the given CPU type
+func (e CompactUnwindEncoding) Description(cpu CPU) string {
+	if e == 0 {
+		return "no unwind information"
+	}
+
+	var desc string
+	switch cpu {
+	case CPUAmd64:
+		u := e.X86_64()
+		regs := strings.Join(u.SavedRegisters, ", ")
+		switch u.Mode {
+		case UNWIND_X86_64_MODE_RBP_FRAME:
+			desc = fmt.Sprintf("rbp frame: regs=[%s] at rbp-%d", regs, u.FrameOffset*8)
+		case UNWIND_X86_64_MODE_STACK_IMMD:
+			desc = fmt.Sprintf("frameless: stack size=%d, regs=[%s]", u.StackSize, regs)
+		case UNWIND_X86_64_MODE_STACK_IND:
+			desc = fmt.Sprintf("frameless: stack size in subq at func+%#x + %d, regs=[%s]", u.StackSizeOffset, u.StackAdjust, regs)
+		case UNWIND_X86_64_MODE_DWARF:
+			desc = fmt.Sprintf("dwarf: FDE at __eh_frame+%#x", u.DwarfOffset)
+		default:
+			desc = fmt.Sprintf("mode(%#x)", uint32(u.Mode))
+		}
+	case CPUArm64, CPUArm6432:
+		u := e.ARM64()
+		switch u.Mode {
+		case UNWIND_ARM64_MODE_FRAMELESS:
+			desc = fmt.Sprintf("frameless: stack size=%d", u.StackSize)
+		case UNWIND_ARM64_MODE_FRAME:
+			desc = fmt.Sprintf("frame: regs=[%s]", strings.Join(u.SavedPairs, ", "))
+		case UNWIND_ARM64_MODE_DWARF:
+			desc = fmt.Sprintf("dwarf: FDE at __eh_frame+%#x", u.DwarfOffset)
+		default:
+			desc = fmt.Sprintf("mode(%#x)", uint32(u.Mode))
+		}
+	default:
+		desc = fmt.Sprintf("mode(%#x)", uint32(e.Mode()))
+	}
+
+	if idx := e.PersonalityIndex(); idx > 0 {
+		desc += fmt.Sprintf(", personality[%d]", idx)
+	}
+	if e.HasLSDA() {
+		desc += ", has LSDA"
+	}
+
+	return desc
+}
@@ -0,0 +1,371 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// An UnwindEntry is the compact unwind information for a function (or a range of a function).
+type UnwindEntry struct {
+	Start           uint64
+	End             uint64
+	Encoding        types.CompactUnwindEncoding
+	Personality     uint64 // address of the personality routine's pointer (images) or of the routine itself (objects)
+	PersonalityName string // symbol name of the personality routine (if known)
+	LSDA            uint64 // address of the language specific data area (0 if none)
+}
+
+func (e UnwindEntry) String() string {
+	var extra string
+	if len(e.PersonalityName) > 0 {
+		extra += fmt.Sprintf(" personality=%s", e.PersonalityName)
+	} else if e.Personality != 0 {
+		extra += fmt.Sprintf(" personality=%#x", e.Personality)
+	}
+	if e.LSDA != 0 {
+		extra += fmt.Sprintf(" lsda=%#x", e.LSDA)
+	}
+	return fmt.Sprintf("%#016x-%#016x encoding=%s%s", e.Start, e.End, e.Encoding, extra)
+}
+
+// UnwindPage is a second-level page of the __TEXT,__unwind_info section.
+type UnwindPage struct {
+	Kind           types.UnwindSecondLevelKind
+	Offset         uint32                        // section offset of the page
+	FunctionOffset uint32                        // function offset of the page's first-level index entry
+	Encodings      []types.CompactUnwindEncoding // page local encodings (compressed pages only)
+	Entries        []UnwindEntry
+}
+
+// UnwindInfo is the parsed __TEXT,__unwind_info section of a linked image.
+type UnwindInfo struct {
+	types.UnwindInfoSectionHeader
+	CommonEncodings []types.CompactUnwindEncoding
+	Personalities   []uint64 // addresses of the personality routine pointers
+	Indices         []types.UnwindInfoSectionHeaderIndexEntry
+	LSDAs           []types.UnwindInfoSectionHeaderLsdaIndexEntry
+	Pages           []UnwindPage
+	Entries         []UnwindEntry // all the second-level entries sorted by address
+}
+
+// Lookup returns the unwind entry for the function containing the given address.
+func (u *UnwindInfo) Lookup(addr uint64) (*UnwindEntry, error) {
+	return lookupUnwindEntry(u.Entries, addr)
+}
+
+func lookupUnwindEntry(entries []UnwindEntry, addr uint64) (*UnwindEntry, error) {
+	idx := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Start > addr
+	}) - 1
+	if idx < 0 || addr >= entries[idx].End {
+		return nil, fmt.Errorf("address %#016x not in any unwind entry", addr)
+	}
+	return &entries[idx], nil
+}
+
+// UnwindInfo parses the __TEXT,__unwind_info section of a linked image.
+func (f *File) UnwindInfo() (*UnwindInfo, error) {
+	sec := f.Section("__TEXT", "__unwind_info")
+	if sec == nil {
+		return nil, fmt.Errorf("macho does not contain a __TEXT,__unwind_info section")
+	}
+	dat, err := sec.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read __TEXT,__unwind_info data: %v", err)
+	}
+	return f.parseUnwindInfo(dat)
+}
+
+func (f *File) parseUnwindInfo(dat []byte) (*UnwindInfo, error) {
+	var u UnwindInfo
+
+	r := bytes.NewReader(dat)
+
+	// readAt decodes v from the given section offset
+	readAt := func(off uint32, v interface{}, what string) error {
+		if _, err := r.Seek(int64(off), 0); err != nil {
+			return fmt.Errorf("failed to seek to unwind info %s at offset %#x: %v", what, off, err)
+		}
+		if err := binary.Read(r, f.ByteOrder, v); err != nil {
+			return fmt.Errorf("failed to read unwind info %s at offset %#x: %v", what, off, err)
+		}
+		return nil
+	}
+
+	// fits checks that count entries of size bytes can be in the section before they are allocated
+	fits := func(count uint32, size int, what string) error {
+		if uint64(count)*uint64(size) > uint64(len(dat)) {
+			return fmt.Errorf("unwind info %s count %d exceeds the section size %#x", what, count, len(dat))
+		}
+		return nil
+	}
+
+	if err := readAt(0, &u.UnwindInfoSectionHeader, "header"); err != nil {
+		return nil, err
+	}
+	if u.Version != types.UNWIND_SECTION_VERSION {
+		return nil, fmt.Errorf("unsupported unwind info version %d", u.Version)
+	}
+
+	if err := fits(u.CommonEncodingsArrayCount, 4, "common encodings"); err != nil {
+		return nil, err
+	}
+	u.CommonEncodings = make([]types.CompactUnwindEncoding, u.CommonEncodingsArrayCount)
+	if err := readAt(u.CommonEncodingsArraySectionOffset, u.CommonEncodings, "common encodings"); err != nil {
+		return nil, err
+	}
+
+	base := f.GetBaseAddress()
+
+	if err := fits(u.PersonalityArrayCount, 4, "personalities"); err != nil {
+		return nil, err
+	}
+	personalities := make([]uint32, u.PersonalityArrayCount)
+	if err := readAt(u.PersonalityArraySectionOffset, personalities, "personalities"); err != nil {
+		return nil, err
+	}
+	personalityNames := make([]string, len(personalities))
+	for i, off := range personalities {
+		addr := base + uint64(off)
+		u.Personalities = append(u.Personalities, addr)
+		personalityNames[i] = f.unwindPersonalityName(addr)
+	}
+
+	if err := fits(u.IndexCount, binary.Size(types.UnwindInfoSectionHeaderIndexEntry{}), "index"); err != nil {
+		return nil, err
+	}
+	u.Indices = make([]types.UnwindInfoSectionHeaderIndexEntry, u.IndexCount)
+	if err := readAt(u.IndexSectionOffset, u.Indices, "index"); err != nil {
+		return nil, err
+	}
+	if len(u.Indices) == 0 {
+		return &u, nil
+	}
+
+	// the LSDA index arrays of all the first-level entries are contiguous, ending at the sentinel entry's
+	lsdaStart := u.Indices[0].LsdaIndexArraySectionOffset
+	lsdaEnd := u.Indices[len(u.Indices)-1].LsdaIndexArraySectionOffset
+	if lsdaEnd > lsdaStart {
+		if uint64(lsdaEnd) > uint64(len(dat)) {
+			return nil, fmt.Errorf("unwind info LSDA index end %#x exceeds the section size %#x", lsdaEnd, len(dat))
+		}
+		u.LSDAs = make([]types.UnwindInfoSectionHeaderLsdaIndexEntry, (lsdaEnd-lsdaStart)/uint32(binary.Size(types.UnwindInfoSectionHeaderLsdaIndexEntry{})))
+		if err := readAt(lsdaStart, u.LSDAs, "LSDA index"); err != nil {
+			return nil, err
+		}
+	}
+	lsdaFor := func(funcOffset uint32) uint64 {
+		idx := sort.Search(len(u.LSDAs), func(i int) bool {
+			return u.LSDAs[i].FunctionOffset >= funcOffset
+		})
+		if idx < len(u.LSDAs) && u.LSDAs[idx].FunctionOffset == funcOffset {
+			return base + uint64(u.LSDAs[idx].LsdaOffset)
+		}
+		return 0
+	}
+
+	newEntry := func(funcOffset uint32, enc types.CompactUnwindEncoding) UnwindEntry {
+		e := UnwindEntry{
+			Start:    base + uint64(funcOffset),
+			Encoding: enc,
+		}
+		if idx := enc.PersonalityIndex(); idx > 0 && int(idx) <= len(u.Personalities) {
+			e.Personality = u.Personalities[idx-1]
+			e.PersonalityName = personalityNames[idx-1]
+		}
+		if enc.HasLSDA() {
+			e.LSDA = lsdaFor(funcOffset)
+		}
+		return e
+	}
+
+	// the last first-level entry is a sentinel holding the end of the last function
+	for i, idx := range u.Indices[:len(u.Indices)-1] {
+		if idx.SecondLevelPagesSectionOffset == 0 {
+			continue
+		}
+		page := UnwindPage{
+			Offset:         idx.SecondLevelPagesSectionOffset,
+			FunctionOffset: idx.FunctionOffset,
+		}
+		if err := readAt(page.Offset, &page.Kind, "second-level page kind"); err != nil {
+			return nil, err
+		}
+		switch page.Kind {
+		case types.UNWIND_SECOND_LEVEL_REGULAR:
+			var hdr types.UnwindInfoRegularSecondLevelPageHeader
+			if err := readAt(page.Offset, &hdr, "regular page header"); err != nil {
+				return nil, err
+			}
+			entries := make([]types.UnwindInfoRegularSecondLevelEntry, hdr.EntryCount)
+			if err := readAt(page.Offset+uint32(hdr.EntryPageOffset), entries, "regular page entries"); err != nil {
+				return nil, err
+			}
+			for _, ent := range entries {
+				page.Entries = append(page.Entries, newEntry(ent.FunctionOffset, ent.Encoding))
+			}
+		case types.UNWIND_SECOND_LEVEL_COMPRESSED:
+			var hdr types.UnwindInfoCompressedSecondLevelPageHeader
+			if err := readAt(page.Offset, &hdr, "compressed page header"); err != nil {
+				return nil, err
+			}
+			page.Encodings = make([]types.CompactUnwindEncoding, hdr.EncodingsCount)
+			if err := readAt(page.Offset+uint32(hdr.EncodingsPageOffset), page.Encodings, "compressed page encodings"); err != nil {
+				return nil, err
+			}
+			entries := make([]types.UnwindInfoCompressedEntry, hdr.EntryCount)
+			if err := readAt(page.Offset+uint32(hdr.EntryPageOffset), entries, "compressed page entries"); err != nil {
+				return nil, err
+			}
+			for _, ent := range entries {
+				var enc types.CompactUnwindEncoding
+				encIdx := ent.EncodingIndex()
+				switch {
+				case encIdx < uint32(len(u.CommonEncodings)):
+					enc = u.CommonEncodings[encIdx]
+				case encIdx-uint32(len(u.CommonEncodings)) < uint32(len(page.Encodings)):
+					enc = page.Encodings[encIdx-uint32(len(u.CommonEncodings))]
+				default:
+					return nil, fmt.Errorf("compressed page at offset %#x encoding index %d out of range", page.Offset, encIdx)
+				}
+				page.Entries = append(page.Entries, newEntry(idx.FunctionOffset+ent.FuncOffset(), enc))
+			}
+		default:
+			return nil, fmt.Errorf("unknown second-level page kind %d at offset %#x", page.Kind, page.Offset)
+		}
+		// each entry ends where the next one starts, and the page's last entry where the next page starts
+		for j := range page.Entries {
+			if j+1 < len(page.Entries) {
+				page.Entries[j].End = page.Entries[j+1].Start
+			} else {
+				page.Entries[j].End = base + uint64(u.Indices[i+1].FunctionOffset)
+			}
+		}
+		u.Pages = append(u.Pages, page)
+		u.Entries = append(u.Entries, page.Entries...)
+	}
+
+	return &u, nil
+}
+
+// unwindPersonalityName returns the name of the personality routine a personality pointer slot points to
+func (f *File) unwindPersonalityName(slot uint64) string {
+	ptr, err := f.ReadPointer(slot)
+	if err != nil {
+		return ""
+	}
+	if ptr.IsBind() {
+		return ptr.Name
+	}
+	if f.Symtab != nil {
+		if syms, err := f.FindAddressSymbols(ptr.Target); err == nil {
+			return syms[0].Name
+		}
+	}
+	return ""
+}
+
+// CompactUnwind parses the __LD,__compact_unwind section of a MH_OBJECT; the input the static linker
+// uses to build the __TEXT,__unwind_info section. The returned entries are sorted by address.
+func (f *File) CompactUnwind() ([]UnwindEntry, error) {
+	sec := f.Section("__LD", "__compact_unwind")
+	if sec == nil {
+		return nil, fmt.Errorf("macho does not contain a __LD,__compact_unwind section")
+	}
+	dat, err := sec.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read __LD,__compact_unwind data: %v", err)
+	}
+
+	relocs, err := sec.DecodedRelocations()
+	if err != nil {
+		return nil, err
+	}
+	relocAt := make(map[uint32]Relocation)
+	for _, rel := range relocs {
+		relocAt[rel.Offset] = rel
+	}
+
+	// resolve returns the address and symbol name a pointer field of an entry refers to
+	resolve := func(off uint32, value uint64) (uint64, string) {
+		rel, ok := relocAt[off]
+		if !ok || !rel.Target.Extern {
+			// section relative pointers hold their target address
+			return value, ""
+		}
+		var addr uint64
+		if sym := f.Symtab.Syms[rel.Target.SymIndex]; sym.Type.IsDefinedInSection() {
+			addr = sym.Value
+		}
+		return addr + uint64(rel.Addend), rel.Target.Symbol
+	}
+
+	var entries []UnwindEntry
+
+	ptrSize := uint32(f.pointerSize())
+
+	r := bytes.NewReader(dat)
+	for off := uint32(0); r.Len() > 0; {
+		var ent types.CompactUnwindEntry64
+		var size uint32
+		if f.is64bit() {
+			if err := binary.Read(r, f.ByteOrder, &ent); err != nil {
+				return nil, fmt.Errorf("failed to read compact unwind entry at offset %#x: %v", off, err)
+			}
+			size = 32
+		} else {
+			var ent32 types.CompactUnwindEntry32
+			if err := binary.Read(r, f.ByteOrder, &ent32); err != nil {
+				return nil, fmt.Errorf("failed to read compact unwind entry at offset %#x: %v", off, err)
+			}
+			ent = types.CompactUnwindEntry64{
+				FunctionStart: uint64(ent32.FunctionStart),
+				FunctionSize:  ent32.FunctionSize,
+				Encoding:      ent32.Encoding,
+				Personality:   uint64(ent32.Personality),
+				Lsda:          uint64(ent32.Lsda),
+			}
+			size = 20
+		}
+
+		e := UnwindEntry{Encoding: ent.Encoding}
+		e.Start, _ = resolve(off, ent.FunctionStart)
+		e.End = e.Start + uint64(ent.FunctionSize)
+		e.Personality, e.PersonalityName = resolve(off+ptrSize+8, ent.Personality)
+		e.LSDA, _ = resolve(off+2*ptrSize+8, ent.Lsda)
+		entries = append(entries, e)
+
+		off += size
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Start < entries[j].Start
+	})
+
+	return entries, nil
+}
+
+// GetUnwindEntryForAddr returns the compact unwind entry of the function containing the given address,
+// from the __TEXT,__unwind_info section of an image or the __LD,__compact_unwind section of an object.
+func (f *File) GetUnwindEntryForAddr(addr uint64) (*UnwindEntry, error) {
+	if f.unwindEntries == nil {
+		if f.Type == types.Obj {
+			entries, err := f.CompactUnwind()
+			if err != nil {
+				return nil, err
+			}
+			f.unwindEntries = entries
+		} else {
+			u, err := f.UnwindInfo()
+			if err != nil {
+				return nil, err
+			}
+			f.unwindEntries = u.Entries
+		}
+	}
+	return lookupUnwindEntry(f.unwindEntries, addr)
+}
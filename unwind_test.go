@@ -0,0 +1,210 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// unwindInfoData returns an __unwind_info section with a regular page with an LSDA and personality entry
+// followed by a compressed page using a common and a page local encoding
+func unwindInfoData(t *testing.T, patch func(hdr *types.UnwindInfoSectionHeader)) []byte {
+	t.Helper()
+	hdr := types.UnwindInfoSectionHeader{
+		Version:                           types.UNWIND_SECTION_VERSION,
+		CommonEncodingsArraySectionOffset: 28,
+		CommonEncodingsArrayCount:         1,
+		PersonalityArraySectionOffset:     32,
+		PersonalityArrayCount:             1,
+		IndexSectionOffset:                36,
+		IndexCount:                        3,
+	}
+	if patch != nil {
+		patch(&hdr)
+	}
+	var buf bytes.Buffer
+	for _, v := range []interface{}{
+		hdr,
+		[]types.CompactUnwindEncoding{0x01000000},
+		[]uint32{0x1000}, // the dyld_stub_binder pointer
+		[]types.UnwindInfoSectionHeaderIndexEntry{
+			{FunctionOffset: 0xf00, SecondLevelPagesSectionOffset: 80, LsdaIndexArraySectionOffset: 72},
+			{FunctionOffset: 0xf40, SecondLevelPagesSectionOffset: 104, LsdaIndexArraySectionOffset: 80},
+			{FunctionOffset: 0xf90, LsdaIndexArraySectionOffset: 80}, // sentinel
+		},
+		[]types.UnwindInfoSectionHeaderLsdaIndexEntry{{FunctionOffset: 0xf10, LsdaOffset: 0x2000}},
+		// regular page
+		types.UnwindInfoRegularSecondLevelPageHeader{Kind: types.UNWIND_SECOND_LEVEL_REGULAR, EntryPageOffset: 8, EntryCount: 2},
+		[]types.UnwindInfoRegularSecondLevelEntry{
+			{FunctionOffset: 0xf00, Encoding: 0x01000000},
+			{FunctionOffset: 0xf10, Encoding: 0x01000000 | types.UNWIND_HAS_LSDA | 1<<28},
+		},
+		// compressed page
+		types.UnwindInfoCompressedSecondLevelPageHeader{
+			Kind:                types.UNWIND_SECOND_LEVEL_COMPRESSED,
+			EntryPageOffset:     12,
+			EntryCount:          2,
+			EncodingsPageOffset: 20,
+			EncodingsCount:      1,
+		},
+		[]types.UnwindInfoCompressedEntry{0x0, 1<<24 | 0x20},
+		[]types.CompactUnwindEncoding{0x02000000},
+	} {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestParseUnwindInfo(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	u, err := f.parseUnwindInfo(unwindInfoData(t, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Pages) != 2 || u.Pages[0].Kind != types.UNWIND_SECOND_LEVEL_REGULAR || u.Pages[1].Kind != types.UNWIND_SECOND_LEVEL_COMPRESSED {
+		t.Fatalf("got %d pages, want a regular and a compressed page", len(u.Pages))
+	}
+	want := []UnwindEntry{
+		{Start: 0x100000f00, End: 0x100000f10, Encoding: 0x01000000},
+		{
+			Start:           0x100000f10,
+			End:             0x100000f40,
+			Encoding:        0x51000000,
+			Personality:     0x100001000,
+			PersonalityName: "dyld_stub_binder",
+			LSDA:            0x100002000,
+		},
+		{Start: 0x100000f40, End: 0x100000f60, Encoding: 0x01000000},
+		{Start: 0x100000f60, End: 0x100000f90, Encoding: 0x02000000},
+	}
+	if len(u.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(u.Entries), len(want))
+	}
+	for i, e := range u.Entries {
+		if e != want[i] {
+			t.Errorf("entry %d: got %s, want %s", i, e, want[i])
+		}
+	}
+
+	if e, err := u.Lookup(0x100000f20); err != nil || e.Start != 0x100000f10 {
+		t.Errorf("got %v (%v) for 0x100000f20, want the entry at 0x100000f10", e, err)
+	}
+	for _, addr := range []uint64{0x100000eff, 0x100000f90} {
+		if _, err := u.Lookup(addr); err == nil {
+			t.Errorf("expected an error for %#x", addr)
+		}
+	}
+}
+
+func TestParseUnwindInfoMalformed(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		name  string
+		patch func(hdr *types.UnwindInfoSectionHeader)
+		data  func(dat []byte)
+	}{
+		{name: "version", patch: func(hdr *types.UnwindInfoSectionHeader) { hdr.Version = 2 }},
+		{name: "common encodings count", patch: func(hdr *types.UnwindInfoSectionHeader) { hdr.CommonEncodingsArrayCount = 0xffffffff }},
+		{name: "personalities count", patch: func(hdr *types.UnwindInfoSectionHeader) { hdr.PersonalityArrayCount = 0xffffffff }},
+		{name: "index count", patch: func(hdr *types.UnwindInfoSectionHeader) { hdr.IndexCount = 0xffffffff }},
+		{name: "index offset", patch: func(hdr *types.UnwindInfoSectionHeader) { hdr.IndexSectionOffset = 0x1000 }},
+		{name: "LSDA index end", data: func(dat []byte) { binary.LittleEndian.PutUint32(dat[36+2*12+8:], 0xfffffff0) }},
+		{name: "page kind", data: func(dat []byte) { binary.LittleEndian.PutUint32(dat[80:], 7) }},
+		{name: "page offset", data: func(dat []byte) { binary.LittleEndian.PutUint32(dat[36+4:], 0x1000) }},
+		{name: "regular page entries", data: func(dat []byte) { binary.LittleEndian.PutUint16(dat[86:], 0xffff) }},
+		{name: "compressed encoding index", data: func(dat []byte) { dat[116+4+3] = 9 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dat := unwindInfoData(t, tt.patch)
+			if tt.data != nil {
+				tt.data(dat)
+			}
+			if _, err := f.parseUnwindInfo(dat); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+	if _, err := f.parseUnwindInfo(unwindInfoData(t, nil)[:20]); err == nil {
+		t.Error("expected an error for a truncated header")
+	}
+}
+
+func TestGetUnwindEntryForAddr(t *testing.T) {
+	tests := []struct {
+		file     string
+		addr     uint64
+		start    uint64
+		encoding types.CompactUnwindEncoding
+	}{
+		{file: dyldInfoTestFile, addr: 0x100000f70, start: 0x100000f60, encoding: 0x01000000},
+		{file: "internal/testdata/clang-amd64-darwin.obj.base64", addr: 0x10, start: 0, encoding: 0x01000000},
+	}
+	for _, tt := range tests {
+		f, err := openObscured(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := f.GetUnwindEntryForAddr(tt.addr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+		} else if e.Start != tt.start || e.Encoding != tt.encoding {
+			t.Errorf("%s: got %s for %#x", tt.file, e, tt.addr)
+		}
+		if _, err := f.GetUnwindEntryForAddr(0x200000000); err == nil {
+			t.Errorf("%s: expected an error for an address outside of any function", tt.file)
+		}
+		f.Close()
+	}
+}
+
+func TestCompactUnwind(t *testing.T) {
+	f, err := openObscured("internal/testdata/unwind-arm64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	entries, err := f.CompactUnwind()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []UnwindEntry{
+		{Start: 0, End: 0x14, Encoding: 0x44000000, PersonalityName: "___gxx_personality_v0", LSDA: 0x18}, // the frame and its LSDA
+		{Start: 0x14, End: 0x18, Encoding: 0x02000000},                                                    // frameless
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e != want[i] {
+			t.Errorf("entry %d: got %s, want %s", i, e, want[i])
+		}
+	}
+	if arm64 := entries[0].Encoding.ARM64(); arm64.Mode != types.UNWIND_ARM64_MODE_FRAME {
+		t.Errorf("got %s, want a frame based encoding", entries[0].Encoding.Description(f.CPU))
+	}
+
+	exe, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exe.Close()
+	if _, err := exe.CompactUnwind(); err == nil {
+		t.Error("expected an error for an image without a __compact_unwind section")
+	}
+}
@@ -0,0 +1,192 @@
+package macho
+
+import (
+	"fmt"
+	"sort"
+)
+
+// addrRange is a [start, end) range of a segment or section (idx is its index in vmIndex.segs or File.Sections)
+type addrRange struct {
+	start uint64
+	end   uint64
+	idx   int
+}
+
+// rangeIndex is a sorted list of non-overlapping ranges
+type rangeIndex []addrRange
+
+// newRangeIndex sorts the non-empty ranges; it returns nil if any of them overlap
+// (the lookups then fall back to a linear scan that returns the first match in load order)
+func newRangeIndex(ranges []addrRange) rangeIndex {
+	idx := make(rangeIndex, 0, len(ranges))
+	for _, r := range ranges {
+		if r.end > r.start {
+			idx = append(idx, r)
+		}
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return idx[i].start < idx[j].start })
+	for i := 1; i < len(idx); i++ {
+		if idx[i].start < idx[i-1].end {
+			return nil
+		}
+	}
+	return idx
+}
+
+// find returns the index of the range containing addr (or -1)
+func (ri rangeIndex) find(addr uint64) int {
+	i := sort.Search(len(ri), func(i int) bool { return ri[i].start > addr }) - 1
+	if i >= 0 && addr < ri[i].end {
+		return ri[i].idx
+	}
+	return -1
+}
+
+// vmIndex is the sorted index of the segments and sections behind the address lookups
+// (see FindSegmentForVMAddr, FindSectionForVMAddr, GetOffset and GetVMAddress).
+//
+// The Segment and Section fields are exported, so they can be changed after the index was built; the lookups check
+// the ranges they find against the current fields and fall back to a linear scan (and rebuild the index) on a miss.
+type vmIndex struct {
+	nloads int // number of load commands and sections when the index was built
+	nsects int
+
+	segs         []*Segment
+	segsByAddr   rangeIndex
+	segsByOffset rangeIndex
+	sectsByAddr  rangeIndex
+}
+
+// vmIndex returns the segment and section index (rebuilding it if load commands or sections were added)
+func (f *File) vmIndex() *vmIndex {
+	f.vmMu.Lock()
+	defer f.vmMu.Unlock()
+	if f.vmIdx == nil || f.vmIdx.nloads != len(f.Loads) || f.vmIdx.nsects != len(f.Sections) {
+		f.vmIdx = f.buildVMIndex()
+	}
+	return f.vmIdx
+}
+
+// invalidateVMIndex drops the index (if it is still idx) so the next lookup rebuilds it
+func (f *File) invalidateVMIndex(idx *vmIndex) {
+	f.vmMu.Lock()
+	defer f.vmMu.Unlock()
+	if idx == nil || f.vmIdx == idx {
+		f.vmIdx = nil
+	}
+}
+
+func (f *File) buildVMIndex() *vmIndex {
+	idx := &vmIndex{
+		nloads: len(f.Loads),
+		nsects: len(f.Sections),
+		segs:   f.Segments(),
+	}
+
+	var byAddr, byOffset, sects []addrRange
+	for i, seg := range idx.segs {
+		byAddr = append(byAddr, addrRange{start: seg.Addr, end: seg.Addr + seg.Memsz, idx: i})
+		byOffset = append(byOffset, addrRange{start: seg.Offset, end: seg.Offset + seg.Filesz, idx: i})
+	}
+	for i, sec := range f.Sections {
+		sects = append(sects, addrRange{start: sec.Addr, end: sec.Addr + sec.Size, idx: i})
+	}
+	idx.segsByAddr = newRangeIndex(byAddr)
+	idx.segsByOffset = newRangeIndex(byOffset)
+	idx.sectsByAddr = newRangeIndex(sects)
+
+	return idx
+}
+
+// segmentForVMAddr returns the segment containing a virtual address (or nil)
+func (f *File) segmentForVMAddr(addr uint64) *Segment {
+	idx := f.vmIndex()
+	contains := func(seg *Segment) bool { return seg.Addr <= addr && addr < seg.Addr+seg.Memsz }
+	if idx.segsByAddr != nil {
+		if i := idx.segsByAddr.find(addr); i >= 0 && contains(idx.segs[i]) {
+			return idx.segs[i]
+		}
+	}
+	for _, seg := range idx.segs {
+		if contains(seg) {
+			if idx.segsByAddr != nil {
+				f.invalidateVMIndex(idx) // a segment moved
+			}
+			return seg
+		}
+	}
+	return nil
+}
+
+// segmentForOffset returns the segment containing a file offset (or nil)
+func (f *File) segmentForOffset(offset uint64) *Segment {
+	idx := f.vmIndex()
+	contains := func(seg *Segment) bool { return seg.Offset <= offset && offset < seg.Offset+seg.Filesz }
+	if idx.segsByOffset != nil {
+		if i := idx.segsByOffset.find(offset); i >= 0 && contains(idx.segs[i]) {
+			return idx.segs[i]
+		}
+	}
+	for _, seg := range idx.segs {
+		if contains(seg) {
+			if idx.segsByOffset != nil {
+				f.invalidateVMIndex(idx) // a segment moved
+			}
+			return seg
+		}
+	}
+	return nil
+}
+
+// sectionForVMAddr returns the section containing a virtual address (or nil)
+func (f *File) sectionForVMAddr(addr uint64) *Section {
+	idx := f.vmIndex()
+	contains := func(sec *Section) bool { return sec.Addr <= addr && addr < sec.Addr+sec.Size }
+	if idx.sectsByAddr != nil {
+		if i := idx.sectsByAddr.find(addr); i >= 0 && contains(f.Sections[i]) {
+			return f.Sections[i]
+		}
+	}
+	for _, sec := range f.Sections {
+		if contains(sec) {
+			if idx.sectsByAddr != nil {
+				f.invalidateVMIndex(idx) // a section moved
+			}
+			return sec
+		}
+	}
+	return nil
+}
+
+// GetOffsets returns the file offsets of the virtual addresses (see GetOffset).
+func (f *File) GetOffsets(addrs []uint64) ([]uint64, error) {
+	overlapping := f.vmIndex().segsByAddr == nil
+	offsets := make([]uint64, len(addrs))
+	var seg *Segment
+	for i, addr := range addrs {
+		// consecutive addresses are usually in the same segment (unless segments overlap)
+		if seg == nil || overlapping || addr < seg.Addr || addr >= seg.Addr+seg.Memsz {
+			if seg = f.segmentForVMAddr(addr); seg == nil {
+				return nil, fmt.Errorf("address 0x%x not within any segments adress range", addr)
+			}
+		}
+		offsets[i] = (addr - seg.Addr) + seg.Offset
+	}
+	return offsets, nil
+}
+
+// GetVMAddresses returns the virtual addresses of the file offsets (see GetVMAddress).
+func (f *File) GetVMAddresses(offsets []uint64) ([]uint64, error) {
+	overlapping := f.vmIndex().segsByOffset == nil
+	addrs := make([]uint64, len(offsets))
+	var seg *Segment
+	for i, offset := range offsets {
+		if seg == nil || overlapping || offset < seg.Offset || offset >= seg.Offset+seg.Filesz {
+			if seg = f.segmentForOffset(offset); seg == nil {
+				return nil, fmt.Errorf("offset 0x%x not within any segments file offset range", offset)
+			}
+		}
+		addrs[i] = (offset - seg.Offset) + seg.Addr
+	}
+	return addrs, nil
+}
@@ -0,0 +1,152 @@
+package macho
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestVMIndex(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		addr    uint64
+		segment string
+		section string
+		offset  uint64
+	}{
+		{addr: 0x100000000, segment: "__TEXT", offset: 0},
+		{addr: 0x100000f70, segment: "__TEXT", section: "__text", offset: 0xf70},
+		{addr: 0x100001010, segment: "__DATA", section: "__la_symbol_ptr", offset: 0x1010},
+		{addr: 0x100002010, segment: "__LINKEDIT", offset: 0x2010},
+	}
+	addrs := make([]uint64, 0, len(tests))
+	for _, tt := range tests {
+		addrs = append(addrs, tt.addr)
+		if seg := f.FindSegmentForVMAddr(tt.addr); seg == nil || seg.Name != tt.segment {
+			t.Errorf("%#x: got segment %v, want %s", tt.addr, seg, tt.segment)
+		}
+		if sec := f.FindSectionForVMAddr(tt.addr); (sec == nil && len(tt.section) > 0) || (sec != nil && sec.Name != tt.section) {
+			t.Errorf("%#x: got section %v, want %q", tt.addr, sec, tt.section)
+		}
+		if off, err := f.GetOffset(tt.addr); err != nil || off != tt.offset {
+			t.Errorf("%#x: got offset %#x (%v), want %#x", tt.addr, off, err, tt.offset)
+		}
+	}
+	offsets, err := f.GetOffsets(addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := f.GetVMAddresses(offsets[1:]) // __PAGEZERO has no file offsets, so 0 maps to __TEXT
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, addr := range back {
+		if addr != addrs[i+1] {
+			t.Errorf("got %#x for offset %#x, want %#x", addr, offsets[i+1], addrs[i+1])
+		}
+	}
+
+	if _, err := f.GetOffset(0x200000000); err == nil {
+		t.Error("expected an error for an address outside of any segment")
+	}
+	if _, err := f.GetVMAddress(0x10000); err == nil {
+		t.Error("expected an error for an offset outside of any segment")
+	}
+	if _, err := f.GetOffsets([]uint64{0x100000f70, 0x200000000}); err == nil {
+		t.Error("expected an error for an address outside of any segment")
+	}
+
+	fn, err := f.GetFunctionForVMAddr(0x100000f70)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn.StartAddr != 0x100000f60 || fn.EndAddr != 0x100000f8a {
+		t.Errorf("got function %#x-%#x, want _main", fn.StartAddr, fn.EndAddr)
+	}
+	if _, err := f.GetFunctionForVMAddr(0x100000f8a); err == nil {
+		t.Error("expected an error for an address past the end of the last function")
+	}
+}
+
+func TestVMIndexConcurrent(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.GetOffset(0x100000f70); err != nil {
+				t.Error(err)
+			}
+			if sec := f.FindSectionForVMAddr(0x100000f70); sec == nil {
+				t.Error("expected __text")
+			}
+			if _, err := f.GetFunctionForVMAddr(0x100000f70); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestVMIndexMovedSegments(t *testing.T) {
+	f, err := openObscured(dyldInfoTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// build the index, then move __DATA and its sections past __LINKEDIT
+	if seg := f.FindSegmentForVMAddr(0x100001010); seg == nil || seg.Name != "__DATA" {
+		t.Fatalf("got %v, want __DATA", seg)
+	}
+	const slide = 0x10000
+	data := f.Segment("__DATA")
+	data.Addr += slide
+	data.Offset += slide
+	for _, sec := range f.Sections {
+		if sec.Seg == "__DATA" {
+			sec.Addr += slide
+		}
+	}
+
+	if seg := f.FindSegmentForVMAddr(0x100001010); seg != nil {
+		t.Errorf("got %s at the old __DATA address", seg.Name)
+	}
+	if sec := f.FindSectionForVMAddr(0x100001010); sec != nil {
+		t.Errorf("got %s at the old __la_symbol_ptr address", sec.Name)
+	}
+	if seg := f.FindSegmentForVMAddr(0x100011010); seg != data {
+		t.Errorf("got %v at the new __DATA address", seg)
+	}
+	if sec := f.FindSectionForVMAddr(0x100011010); sec == nil || sec.Name != "__la_symbol_ptr" {
+		t.Errorf("got %v at the new __la_symbol_ptr address", sec)
+	}
+	if off, err := f.GetOffset(0x100011010); err != nil || off != 0x11010 {
+		t.Errorf("got offset %#x (%v), want 0x11010", off, err)
+	}
+	if addr, err := f.GetVMAddress(0x11010); err != nil || addr != 0x100011010 {
+		t.Errorf("got address %#x (%v), want 0x100011010", addr, err)
+	}
+	if _, err := f.GetVMAddress(0x1010); err == nil {
+		t.Error("expected an error for the old __DATA offset")
+	}
+
+	// overlapping segments are looked up in load order
+	data.Addr = 0x100000000
+	if seg := f.FindSegmentForVMAddr(0x100000010); seg == nil || seg.Name != "__TEXT" {
+		t.Errorf("got %v, want __TEXT (the first segment in load order)", seg)
+	}
+	if offsets, err := f.GetOffsets([]uint64{0x100000010, 0x100000020}); err != nil || offsets[1] != 0x20 {
+		t.Errorf("got %v (%v), want the __TEXT offsets", offsets, err)
+	}
+}